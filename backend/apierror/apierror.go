@@ -0,0 +1,150 @@
+// Package apierror defines the error envelope returned by every endpoint:
+//
+//	{"code": "validation_failed", "message": "...", "details": [{"field": "age", "code": "gte", "message": "..."}]}
+//
+// Handlers return an *Error (or any other error) and the HTTP error handler
+// installed by Handler turns it into the envelope.
+package apierror
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/middleware"
+)
+
+// Machine-readable error codes. Statuses without a dedicated code use the
+// snake_case form of their status text, e.g. "method_not_allowed".
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidParameter     = "invalid_parameter"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeConflict             = "conflict"
+	CodeAssignmentConflict   = "assignment_conflict"
+	CodeTimesheetLocked      = "timesheet_locked"
+	CodeBulkFailed           = "bulk_failed"
+	CodePreconditionRequired = "precondition_required"
+	CodeTimeout              = "timeout"
+	CodeClientClosedRequest  = "client_closed_request"
+	CodeInternal             = "internal_error"
+)
+
+// FieldError describes a problem with a single field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an API error together with the status it is answered with
+type Error struct {
+	Status  int          `json:"-"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+	// Current is the current state of the resource a conflicting request
+	// was based on an older version of
+	Current interface{} `json:"current,omitempty"`
+
+	// cause is the underlying error; it is logged but never sent to clients
+	cause error
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.cause)
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Wrap records the underlying error of e
+func (e *Error) Wrap(cause error) *Error {
+	e.cause = cause
+	return e
+}
+
+// WithDetails adds field-level details to e
+func (e *Error) WithDetails(details ...FieldError) *Error {
+	e.Details = append(e.Details, details...)
+	return e
+}
+
+// WithCurrent attaches the current state of the conflicting resource to e
+func (e *Error) WithCurrent(current interface{}) *Error {
+	e.Current = current
+	return e
+}
+
+// New creates an error with an explicit status and code
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// BadRequest reports a malformed request
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// InvalidParameter reports an invalid query or path parameter
+func InvalidParameter(param, message string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("Invalid %s", param)).
+		WithDetails(FieldError{Field: param, Code: CodeInvalidParameter, Message: message})
+}
+
+// Unauthorized reports a missing or invalid authentication
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// Forbidden reports an authenticated user lacking a permission
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound reports a missing resource
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+// Conflict reports a request conflicting with the current state of a resource
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+// PreconditionRequired reports a conditional request sent without its condition
+func PreconditionRequired(message string) *Error {
+	return New(http.StatusPreconditionRequired, CodePreconditionRequired, message)
+}
+
+// Internal reports an unexpected failure; the cause is logged, not returned
+func Internal(cause error) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, "Internal server error").Wrap(cause)
+}
+
+// codeForStatus returns the default code of a status
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusInternalServerError:
+		return CodeInternal
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	case middleware.StatusClientClosedRequest:
+		return CodeClientClosedRequest
+	}
+
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	text = strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+	return strings.ToLower(text)
+}
@@ -0,0 +1,183 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/middleware"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/query"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Handler is the Echo HTTPErrorHandler writing every error as the envelope
+func Handler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	apiErr := From(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		c.Logger().Error(err)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(apiErr.Status)
+	} else {
+		err = c.JSON(apiErr.Status, apiErr)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+// From converts any error returned by a handler into an API error. Errors
+// caused by a cancelled or expired context take precedence, since a query
+// failing that way says nothing about the request itself. Only the error
+// chain is inspected: the request context is released by the time the error
+// handler runs, and RequestTimeout already covers drivers that don't wrap
+// the context error.
+func From(err error) *Error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(http.StatusGatewayTimeout, CodeTimeout, statusMessage(http.StatusGatewayTimeout)).Wrap(err)
+	case errors.Is(err, context.Canceled):
+		return New(middleware.StatusClientClosedRequest, CodeClientClosedRequest, statusMessage(middleware.StatusClientClosedRequest)).Wrap(err)
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var queryErr *query.Error
+	if errors.As(err, &queryErr) {
+		detail := FieldError{Field: queryErr.Param, Code: CodeInvalidParameter, Message: queryErr.Message}
+		if queryErr.Field != "" {
+			detail.Field = queryErr.Param + "." + queryErr.Field
+		}
+		return New(http.StatusBadRequest, CodeInvalidParameter, queryErr.Error()).WithDetails(detail)
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return Validation(validationErrs)
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return fromHTTPError(httpErr)
+	}
+
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return Conflict("The record was modified by another request").Wrap(err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return NotFound("Record not found").Wrap(err)
+	}
+
+	return Internal(err)
+}
+
+// Validation converts the errors of a validator run into field-level details
+func Validation(errs validator.ValidationErrors) *Error {
+	apiErr := New(http.StatusBadRequest, CodeValidationFailed, "Validation failed")
+	for _, fieldErr := range errs {
+		apiErr.Details = append(apiErr.Details, FieldError{
+			Field:   fieldPath(fieldErr),
+			Code:    fieldErr.Tag(),
+			Message: validationMessage(fieldErr),
+		})
+	}
+	return apiErr.Wrap(errs)
+}
+
+// fieldPath returns the path of a failed field below the validated struct,
+// e.g. "boundaries[0].points"
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if strings.HasPrefix(namespace, "[") {
+		return namespace
+	}
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return fieldErr.Field()
+}
+
+// validationMessage describes a failed validator tag in plain words
+func validationMessage(fieldErr validator.FieldError) string {
+	// Length rules on text fields count characters
+	unit := ""
+	if fieldErr.Kind() == reflect.String {
+		unit = " characters long"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s%s", fieldErr.Param(), unit)
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s%s", fieldErr.Param(), unit)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	case "lt":
+		return fmt.Sprintf("must be less than %s", fieldErr.Param())
+	case "gtefield":
+		return fmt.Sprintf("must not be before %s", fieldErr.Param())
+	case "day_hours":
+		return fmt.Sprintf("added to hours, must not exceed %s", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fieldErr.Param())
+	case "email":
+		return "must be a valid email address"
+	case "polygon":
+		return "must be a closed ring of at least 4 points whose edges do not cross"
+	case "site_extent":
+		return fmt.Sprintf("must lie within %s km of the project location", fieldErr.Param())
+	default:
+		return fmt.Sprintf("failed the %q rule", fieldErr.Tag())
+	}
+}
+
+// fromHTTPError converts the errors raised by Echo itself (unknown routes,
+// bind failures, ...) and by handlers still using echo.NewHTTPError
+func fromHTTPError(httpErr *echo.HTTPError) *Error {
+	message := statusMessage(httpErr.Code)
+	if text, ok := httpErr.Message.(string); ok && text != "" {
+		message = text
+	}
+	apiErr := New(httpErr.Code, codeForStatus(httpErr.Code), message).Wrap(httpErr)
+
+	// Bind failures carry the decoding error; a type mismatch names the field
+	if httpErr.Code == http.StatusBadRequest && httpErr.Internal != nil {
+		apiErr.Message = "Invalid request body"
+
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(httpErr.Internal, &typeErr) {
+			apiErr.Details = []FieldError{{
+				Field:   typeErr.Field,
+				Code:    "type",
+				Message: fmt.Sprintf("must be of type %s", typeErr.Type),
+			}}
+		}
+	}
+	return apiErr
+}
+
+// statusMessage returns the default message of a status
+func statusMessage(status int) string {
+	if status == middleware.StatusClientClosedRequest {
+		return "Client closed request"
+	}
+	return http.StatusText(status)
+}
@@ -3,10 +3,9 @@ package auth
 import (
 	"errors"
 	"fmt"
-	"os"
-	"strconv"
 	"time"
 
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -19,25 +18,29 @@ type JWTClaims struct {
 	jwt.RegisteredClaims
 }
 
-// Generate JWT token for a user
-func GenerateToken(user *model.User) (string, error) {
-	// Get the JWT secret from environment
-	jwtSecret := getEnv("JWT_SECRET", "")
-	
-	// Ensure a secret is set
-	if jwtSecret == "" {
-		return "", errors.New("JWT_SECRET environment variable must be set")
+// TokenService issues and validates JWT tokens using the auth configuration
+type TokenService struct {
+	secret     []byte
+	expiration time.Duration
+}
+
+// NewTokenService creates a new TokenService from the auth configuration
+func NewTokenService(cfg config.AuthConfig) *TokenService {
+	return &TokenService{
+		secret:     []byte(cfg.JWTSecret),
+		expiration: cfg.TokenExpiration,
 	}
-	
-	// Get expiration duration from environment variable, default to 24 hours
-	expirationStr := getEnv("JWT_EXPIRATION_HOURS", "24")
-	expirationHours, err := strconv.Atoi(expirationStr)
-	if err != nil {
-		expirationHours = 24 // Default to 24 hours if parsing fails
+}
+
+// GenerateToken generates a JWT token for a user
+func (s *TokenService) GenerateToken(user *model.User) (string, error) {
+	// Ensure a secret is set
+	if len(s.secret) == 0 {
+		return "", errors.New("JWT secret is not configured")
 	}
 	
 	// Set expiration time
-	expirationTime := time.Now().Add(time.Duration(expirationHours) * time.Hour)
+	expirationTime := time.Now().Add(s.expiration)
 	
 	// Create claims with user information
 	claims := &JWTClaims{
@@ -57,7 +60,7 @@ func GenerateToken(user *model.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	
 	// Generate the signed token string
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokenString, err := token.SignedString(s.secret)
 	if err != nil {
 		return "", err
 	}
@@ -66,12 +69,10 @@ func GenerateToken(user *model.User) (string, error) {
 }
 
 // ValidateToken validates the JWT token and returns the claims
-func ValidateToken(tokenString string) (*JWTClaims, error) {
-	jwtSecret := getEnv("JWT_SECRET", "")
-	
+func (s *TokenService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	// Ensure a secret is set
-	if jwtSecret == "" {
-		return nil, errors.New("JWT_SECRET environment variable must be set")
+	if len(s.secret) == 0 {
+		return nil, errors.New("JWT secret is not configured")
 	}
 	
 	// Parse the JWT string and store the result in claims
@@ -80,7 +81,7 @@ func ValidateToken(tokenString string) (*JWTClaims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(jwtSecret), nil
+		return s.secret, nil
 	})
 	
 	if err != nil {
@@ -94,12 +95,3 @@ func ValidateToken(tokenString string) (*JWTClaims, error) {
 	
 	return nil, errors.New("invalid token")
 }
-
-// Helper function to get environment variables
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	return value
-} 
\ No newline at end of file
@@ -1,36 +1,42 @@
 package auth
 
 import (
-	"net/http"
 	"strings"
 
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
 	"github.com/labstack/echo/v4"
 )
 
 // JWTMiddleware checks for a valid JWT token in the Authorization header
-func JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+func (s *TokenService) JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// Get the Authorization header
 		authHeader := c.Request().Header.Get("Authorization")
 		
 		// Check if the header is empty or doesn't start with "Bearer "
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Missing or invalid authorization token")
+			return apierror.Unauthorized("Missing or invalid authorization token")
 		}
 		
 		// Extract the token from the header
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		
 		// Validate the token
-		claims, err := ValidateToken(tokenString)
+		claims, err := s.ValidateToken(tokenString)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid or expired token")
+			return apierror.Unauthorized("Invalid or expired token")
 		}
 		
 		// Set user information in the context
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+
+		// Attribute the writes of the request to the user, in their history
+		actor := model.Actor{ID: claims.UserID, Username: claims.Username}
+		c.SetRequest(c.Request().WithContext(repository.WithActor(c.Request().Context(), actor)))
 		
 		// Continue to the next handler
 		return next(c)
@@ -43,7 +49,7 @@ func AdminOnly(next echo.HandlerFunc) echo.HandlerFunc {
 		// Get the role from the context (set by JWTMiddleware)
 		role, ok := c.Get("role").(string)
 		if !ok || role != "admin" {
-			return echo.NewHTTPError(http.StatusForbidden, "Admin access required")
+			return apierror.Forbidden("Admin access required")
 		}
 		
 		// Continue to the next handler
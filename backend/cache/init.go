@@ -3,6 +3,8 @@ package cache
 import (
 	"fmt"
 	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
 )
 
 // Global cache instances
@@ -10,17 +12,22 @@ var (
 	// QueryCache is used for database query results
 	QueryCache *Cache
 	
-	// Default expiration times
-	DefaultExpiration  = 5 * time.Minute
-	ShortExpiration    = 1 * time.Minute
-	LongExpiration     = 30 * time.Minute
+	// Default expiration times, set from the configuration by InitCache
+	DefaultExpiration time.Duration
+	ShortExpiration   time.Duration
+	LongExpiration    time.Duration
 	
 	// Cleanup interval for expired items
-	CleanupInterval    = 10 * time.Minute
+	CleanupInterval time.Duration
 )
 
-// InitCache initializes the cache system
-func InitCache() {
+// InitCache initializes the cache system from the cache configuration
+func InitCache(cfg config.CacheConfig) {
+	DefaultExpiration = cfg.DefaultExpiration
+	ShortExpiration = cfg.ShortExpiration
+	LongExpiration = cfg.LongExpiration
+	CleanupInterval = cfg.CleanupInterval
+
 	// Initialize the query cache with default expiration and cleanup interval
 	QueryCache = NewCache(DefaultExpiration, CleanupInterval)
 }
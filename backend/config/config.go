@@ -0,0 +1,529 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every runtime setting of the backend. It is loaded once at
+// startup and handed explicitly to the subsystems that need it.
+type Config struct {
+	Env      string         `yaml:"env" toml:"env"`
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Auth     AuthConfig     `yaml:"auth" toml:"auth"`
+	Cache    CacheConfig    `yaml:"cache" toml:"cache"`
+	Trash    TrashConfig    `yaml:"trash" toml:"trash"`
+}
+
+// ServerConfig holds the HTTP server settings
+type ServerConfig struct {
+	Port           int      `yaml:"port" toml:"port"`
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	// RequestTimeout bounds the time a request (and its database queries) may take
+	RequestTimeout time.Duration `yaml:"request_timeout" toml:"request_timeout"`
+	// RouteTimeouts overrides RequestTimeout per route, keyed by "METHOD /path"
+	// using the route path as registered (e.g. "GET /api/projects/:id")
+	RouteTimeouts map[string]time.Duration `yaml:"route_timeouts" toml:"route_timeouts"`
+}
+
+// DatabaseConfig holds the connection and pool settings for the database
+type DatabaseConfig struct {
+	Driver             string        `yaml:"driver" toml:"driver"`
+	Path               string        `yaml:"path" toml:"path"`
+	Host               string        `yaml:"host" toml:"host"`
+	Port               int           `yaml:"port" toml:"port"`
+	User               string        `yaml:"user" toml:"user"`
+	Password           string        `yaml:"password" toml:"password"`
+	Name               string        `yaml:"name" toml:"name"`
+	SSLMode            string        `yaml:"ssl_mode" toml:"ssl_mode"`
+	MaxIdleConns       int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	MaxOpenConns       int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	ConnMaxLifetime    time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" toml:"slow_query_threshold"`
+}
+
+// AuthConfig holds the JWT signing settings
+type AuthConfig struct {
+	JWTSecret       string        `yaml:"jwt_secret" toml:"jwt_secret"`
+	TokenExpiration time.Duration `yaml:"token_expiration" toml:"token_expiration"`
+}
+
+// CacheConfig holds the expiration settings of the in-memory query cache
+type CacheConfig struct {
+	DefaultExpiration time.Duration `yaml:"default_expiration" toml:"default_expiration"`
+	ShortExpiration   time.Duration `yaml:"short_expiration" toml:"short_expiration"`
+	LongExpiration    time.Duration `yaml:"long_expiration" toml:"long_expiration"`
+	CleanupInterval   time.Duration `yaml:"cleanup_interval" toml:"cleanup_interval"`
+}
+
+// TrashConfig holds the retention settings of soft-deleted records
+type TrashConfig struct {
+	// Retention is how long deleted workers and projects stay restorable
+	// before they are purged; zero keeps them forever
+	Retention time.Duration `yaml:"retention" toml:"retention"`
+	// PurgeInterval is the time between two runs of the purge job
+	PurgeInterval time.Duration `yaml:"purge_interval" toml:"purge_interval"`
+}
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+
+	// SQLiteMemory is the SQLite path of a private in-memory database
+	SQLiteMemory = ":memory:"
+
+	// redactedValue replaces secrets when the configuration is printed
+	redactedValue = "********"
+
+	// ExportTimeout is the default deadline of the export routes, which read
+	// every matching row instead of a page
+	ExportTimeout = 5 * time.Minute
+)
+
+// Default returns the configuration used when nothing else is provided
+func Default() *Config {
+	return &Config{
+		Env: EnvDevelopment,
+		Server: ServerConfig{
+			Port:           8080,
+			AllowedOrigins: []string{"http://localhost:5173", "http://127.0.0.1:5173"},
+			RequestTimeout: 30 * time.Second,
+			RouteTimeouts: map[string]time.Duration{
+				"GET /api/workers/export.csv":               ExportTimeout,
+				"GET /api/workers/export.xlsx":              ExportTimeout,
+				"GET /api/projects/export.geojson":          ExportTimeout,
+				"GET /api/projects/export.kml":              ExportTimeout,
+				"GET /api/projects/export.csv":              ExportTimeout,
+				"GET /api/projects/export.xlsx":             ExportTimeout,
+				"GET /api/projects/assignments/export.csv":  ExportTimeout,
+				"GET /api/projects/assignments/export.xlsx": ExportTimeout,
+			},
+		},
+		Database: DatabaseConfig{
+			Driver:             DriverPostgres,
+			Path:               "worksite_management.db",
+			Host:               "localhost",
+			Port:               5432,
+			User:               "mihaicristianfarcas",
+			Name:               "worksite_management",
+			MaxIdleConns:       10,
+			MaxOpenConns:       100,
+			ConnMaxLifetime:    time.Hour,
+			SlowQueryThreshold: time.Second,
+		},
+		Auth: AuthConfig{
+			TokenExpiration: 24 * time.Hour,
+		},
+		Cache: CacheConfig{
+			DefaultExpiration: 5 * time.Minute,
+			ShortExpiration:   1 * time.Minute,
+			LongExpiration:    30 * time.Minute,
+			CleanupInterval:   10 * time.Minute,
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+	}
+}
+
+// setting describes a single option that can be supplied through an
+// environment variable and a command line flag
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	apply func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{"ENV", "env", "application environment (development or production)", setString(func(c *Config) *string { return &c.Env })},
+	{"PORT", "port", "HTTP server port", setInt(func(c *Config) *int { return &c.Server.Port })},
+	{"ALLOWED_ORIGINS", "allowed-origins", "comma separated list of CORS origins", setList(func(c *Config) *[]string { return &c.Server.AllowedOrigins })},
+	{"REQUEST_TIMEOUT", "request-timeout", "default deadline of a request", setDuration(func(c *Config) *time.Duration { return &c.Server.RequestTimeout })},
+	{"ROUTE_TIMEOUTS", "route-timeouts", "per-route deadlines, e.g. \"GET /api/workers=5s,GET /api/projects=10s\"", setDurationMap(func(c *Config) *map[string]time.Duration { return &c.Server.RouteTimeouts })},
+	{"DB_DRIVER", "db-driver", "database driver (postgres or sqlite)", setString(func(c *Config) *string { return &c.Database.Driver })},
+	{"DB_PATH", "db-path", "SQLite database file, or :memory: for an in-memory database", setString(func(c *Config) *string { return &c.Database.Path })},
+	{"DB_HOST", "db-host", "database host", setString(func(c *Config) *string { return &c.Database.Host })},
+	{"DB_PORT", "db-port", "database port", setInt(func(c *Config) *int { return &c.Database.Port })},
+	{"DB_USER", "db-user", "database user", setString(func(c *Config) *string { return &c.Database.User })},
+	{"DB_PASSWORD", "db-password", "database password", setString(func(c *Config) *string { return &c.Database.Password })},
+	{"DB_NAME", "db-name", "database name", setString(func(c *Config) *string { return &c.Database.Name })},
+	{"DB_SSL_MODE", "db-ssl-mode", "database SSL mode", setString(func(c *Config) *string { return &c.Database.SSLMode })},
+	{"DB_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum number of idle database connections", setInt(func(c *Config) *int { return &c.Database.MaxIdleConns })},
+	{"DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum number of open database connections", setInt(func(c *Config) *int { return &c.Database.MaxOpenConns })},
+	{"DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "maximum lifetime of a database connection", setDuration(func(c *Config) *time.Duration { return &c.Database.ConnMaxLifetime })},
+	{"DB_SLOW_QUERY_THRESHOLD", "db-slow-query-threshold", "duration after which a query is logged as slow", setDuration(func(c *Config) *time.Duration { return &c.Database.SlowQueryThreshold })},
+	{"JWT_SECRET", "jwt-secret", "secret used to sign JWT tokens", setString(func(c *Config) *string { return &c.Auth.JWTSecret })},
+	{"JWT_EXPIRATION_HOURS", "jwt-expiration-hours", "JWT token lifetime in hours", setHours(func(c *Config) *time.Duration { return &c.Auth.TokenExpiration })},
+	{"CACHE_DEFAULT_EXPIRATION", "cache-default-expiration", "default expiration of cached queries", setDuration(func(c *Config) *time.Duration { return &c.Cache.DefaultExpiration })},
+	{"CACHE_SHORT_EXPIRATION", "cache-short-expiration", "expiration of short-lived cached queries", setDuration(func(c *Config) *time.Duration { return &c.Cache.ShortExpiration })},
+	{"CACHE_LONG_EXPIRATION", "cache-long-expiration", "expiration of long-lived cached queries", setDuration(func(c *Config) *time.Duration { return &c.Cache.LongExpiration })},
+	{"CACHE_CLEANUP_INTERVAL", "cache-cleanup-interval", "interval between cache cleanups", setDuration(func(c *Config) *time.Duration { return &c.Cache.CleanupInterval })},
+	{"TRASH_RETENTION_DAYS", "trash-retention-days", "days deleted workers and projects stay restorable (0 keeps them forever)", setDays(func(c *Config) *time.Duration { return &c.Trash.Retention })},
+	{"TRASH_PURGE_INTERVAL", "trash-purge-interval", "interval between purges of expired trash", setDuration(func(c *Config) *time.Duration { return &c.Trash.PurgeInterval })},
+}
+
+// Load builds the configuration from, in increasing order of precedence, the
+// built-in defaults, an optional YAML or TOML file, environment variables and
+// command line flags. The flags are registered on fs and parsed from args, so
+// callers may register flags of their own beforehand. The returned config is
+// validated; on a validation failure both the config and a *ValidationError
+// are returned.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML configuration file")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.flag] = fs.String(s.flag, "", s.usage+" (env "+s.env+")")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+
+	// Configuration file
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Environment variables
+	for _, s := range settings {
+		if value := os.Getenv(s.env); value != "" {
+			if err := s.apply(cfg, value); err != nil {
+				return nil, fmt.Errorf("environment variable %s: %w", s.env, err)
+			}
+		}
+	}
+
+	// Command line flags, only those explicitly set
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && flagErr == nil {
+				if err := s.apply(cfg, *flagValues[s.flag]); err != nil {
+					flagErr = fmt.Errorf("flag -%s: %w", s.flag, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	cfg.applyEnvDefaults()
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnvDefaults fills in the defaults that depend on the environment
+func (c *Config) applyEnvDefaults() {
+	// Require SSL in production unless explicitly overridden
+	if c.Database.SSLMode == "" {
+		c.Database.SSLMode = "disable"
+		if c.Env == EnvProduction {
+			c.Database.SSLMode = "require"
+		}
+	}
+
+	// In production, don't use defaults for sensitive information
+	if c.Database.Password == "" && c.Env != EnvProduction {
+		c.Database.Password = "postgres"
+	}
+}
+
+// loadFile decodes a YAML or TOML file on top of the current values
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parsing config file %s: unknown key %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+
+	return nil
+}
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the configuration for missing or inconsistent values
+func (c *Config) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		addf("env must be %q or %q, got %q", EnvDevelopment, EnvProduction, c.Env)
+	}
+
+	// Server
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		addf("server.port must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	if len(c.Server.AllowedOrigins) == 0 {
+		addf("server.allowed_origins must contain at least one origin")
+	}
+	if c.Server.RequestTimeout <= 0 {
+		addf("server.request_timeout must be positive, got %s", c.Server.RequestTimeout)
+	}
+	for route, timeout := range c.Server.RouteTimeouts {
+		if method, path, ok := strings.Cut(route, " "); !ok || method == "" || !strings.HasPrefix(path, "/") {
+			addf("server.route_timeouts key %q must have the form \"METHOD /path\"", route)
+		}
+		if timeout <= 0 {
+			addf("server.route_timeouts[%q] must be positive, got %s", route, timeout)
+		}
+	}
+
+	// Database
+	switch c.Database.Driver {
+	case DriverPostgres:
+		if c.Database.Host == "" {
+			addf("database.host is required")
+		}
+		if c.Database.Port < 1 || c.Database.Port > 65535 {
+			addf("database.port must be between 1 and 65535, got %d", c.Database.Port)
+		}
+		if c.Database.User == "" {
+			addf("database.user is required")
+		}
+		if c.Database.Name == "" {
+			addf("database.name is required")
+		}
+		switch c.Database.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			addf("database.ssl_mode %q is not a valid Postgres sslmode", c.Database.SSLMode)
+		}
+	case DriverSQLite:
+		if c.Database.Path == "" {
+			addf("database.path is required when database.driver is %q", DriverSQLite)
+		}
+	default:
+		addf("database.driver must be %q or %q, got %q", DriverPostgres, DriverSQLite, c.Database.Driver)
+	}
+	if c.Database.MaxOpenConns < 1 {
+		addf("database.max_open_conns must be positive, got %d", c.Database.MaxOpenConns)
+	}
+	if c.Database.MaxIdleConns < 0 {
+		addf("database.max_idle_conns must not be negative, got %d", c.Database.MaxIdleConns)
+	}
+	if c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		addf("database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		addf("database.conn_max_lifetime must not be negative, got %s", c.Database.ConnMaxLifetime)
+	}
+
+	// Auth
+	if c.Auth.JWTSecret == "" {
+		addf("auth.jwt_secret is required (env JWT_SECRET)")
+	}
+	if c.Auth.TokenExpiration <= 0 {
+		addf("auth.token_expiration must be positive, got %s", c.Auth.TokenExpiration)
+	}
+
+	// Cache
+	if c.Cache.DefaultExpiration <= 0 {
+		addf("cache.default_expiration must be positive, got %s", c.Cache.DefaultExpiration)
+	}
+	if c.Cache.ShortExpiration <= 0 {
+		addf("cache.short_expiration must be positive, got %s", c.Cache.ShortExpiration)
+	}
+	if c.Cache.LongExpiration <= 0 {
+		addf("cache.long_expiration must be positive, got %s", c.Cache.LongExpiration)
+	}
+	if c.Cache.CleanupInterval < 0 {
+		addf("cache.cleanup_interval must not be negative, got %s", c.Cache.CleanupInterval)
+	}
+
+	// Trash
+	if c.Trash.Retention < 0 {
+		addf("trash.retention must not be negative, got %s", c.Trash.Retention)
+	}
+	if c.Trash.Retention > 0 && c.Trash.PurgeInterval <= 0 {
+		addf("trash.purge_interval must be positive, got %s", c.Trash.PurgeInterval)
+	}
+
+	// In production, don't accept defaults for sensitive information
+	if c.Env == EnvProduction {
+		if c.Database.Driver == DriverPostgres && c.Database.Password == "" {
+			addf("database.password is required in production (env DB_PASSWORD)")
+		}
+		if len(c.Auth.JWTSecret) > 0 && len(c.Auth.JWTSecret) < 32 {
+			addf("auth.jwt_secret must be at least 32 characters long in production")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration with secrets masked
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Server.AllowedOrigins = append([]string(nil), c.Server.AllowedOrigins...)
+	if c.Server.RouteTimeouts != nil {
+		redacted.Server.RouteTimeouts = make(map[string]time.Duration, len(c.Server.RouteTimeouts))
+		for route, timeout := range c.Server.RouteTimeouts {
+			redacted.Server.RouteTimeouts[route] = timeout
+		}
+	}
+	if redacted.Database.Password != "" {
+		redacted.Database.Password = redactedValue
+	}
+	if redacted.Auth.JWTSecret != "" {
+		redacted.Auth.JWTSecret = redactedValue
+	}
+	return &redacted
+}
+
+// Write prints the configuration as YAML
+func (c *Config) Write(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// Helpers that parse a raw string into a config field
+
+func setString(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func setInt(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func setDuration(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration (e.g. 30s, 5m, 1h)", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func setHours(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		hours, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number of hours", value)
+		}
+		*field(c) = time.Duration(hours) * time.Hour
+		return nil
+	}
+}
+
+func setDays(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number of days", value)
+		}
+		*field(c) = time.Duration(days) * 24 * time.Hour
+		return nil
+	}
+}
+
+func setList(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*field(c) = items
+		return nil
+	}
+}
+
+func setDurationMap(field func(*Config) *map[string]time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		items := make(map[string]time.Duration)
+		for key, current := range *field(c) {
+			items[key] = current
+		}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, raw, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("%q is not of the form key=duration", item)
+			}
+			parsed, err := time.ParseDuration(strings.TrimSpace(raw))
+			if err != nil {
+				return fmt.Errorf("%q is not a duration (e.g. 30s, 5m, 1h)", raw)
+			}
+			items[strings.TrimSpace(key)] = parsed
+		}
+		*field(c) = items
+		return nil
+	}
+}
@@ -4,37 +4,22 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"time"
 
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
-
-func InitDB() {
-	sslMode := getEnv("DB_SSL_MODE", "disable")
-	if os.Getenv("ENV") == "production" {
-		// Default to require SSL in production unless explicitly overridden
-		sslMode = getEnv("DB_SSL_MODE", "require")
-	}
-	
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		getEnv("DB_HOST", "localhost"),
-		getEnv("DB_USER", "mihaicristianfarcas"),
-		getEnv("DB_PASSWORD", "postgres"),
-		getEnv("DB_NAME", "worksite_management"),
-		getEnv("DB_PORT", "5432"),
-		sslMode,
-	)
-
+// InitDB opens the database connection described by cfg, configures the
+// connection pool and migrates the schema
+func InitDB(cfg DatabaseConfig) *gorm.DB {
 	// Configure GORM logger
 	newLogger := logger.New(
 		log.New(os.Stdout, "\r\n", log.LstdFlags),
 		logger.Config{
-			SlowThreshold:             time.Second,  // Threshold for slow queries
+			SlowThreshold:             cfg.SlowQueryThreshold, // Threshold for slow queries
 			LogLevel:                  logger.Error, // Log level (Error, Warn, Info)
 			IgnoreRecordNotFoundError: true,
 			Colorful:                  true,
@@ -42,7 +27,7 @@ func InitDB() {
 	)
 
 	// Open database connection with optimized configuration
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(openDialector(cfg), &gorm.Config{
 		Logger: newLogger,
 		// Disable default transaction for better performance
 		SkipDefaultTransaction: true,
@@ -60,69 +45,111 @@ func InitDB() {
 	}
 	
 	// Set connection pool parameters
-	sqlDB.SetMaxIdleConns(10)        // Maximum number of idle connections
-	sqlDB.SetMaxOpenConns(100)       // Maximum number of open connections
-	sqlDB.SetConnMaxLifetime(1 * time.Hour) // Maximum connection lifetime
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)       // Maximum number of idle connections
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)       // Maximum number of open connections
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime) // Maximum connection lifetime
+
+	// An in-memory SQLite database lives as long as its connection, so keep
+	// exactly one connection open for the lifetime of the process
+	if cfg.Driver == DriverSQLite && cfg.Path == SQLiteMemory {
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+	}
+
+	if err := migrateAssignmentIDs(db); err != nil {
+		log.Fatal("Failed to migrate assignments:", err)
+	}
 
 	// Auto Migrate the schema with optimized indices
-	err = db.AutoMigrate(&model.Worker{}, &model.Project{}, &model.User{}, &model.WorkerProject{}, &model.ActivityLog{})
+	err = db.AutoMigrate(&model.Worker{}, &model.Project{}, &model.User{}, &model.WorkerProject{}, &model.Timesheet{}, &model.ActivityLog{}, &model.EntityChange{})
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
-	// Create indexes for frequently queried fields
-	createIndexes(db)
+	if cfg.Driver == DriverPostgres {
+		if err := migrateSearchColumns(db); err != nil {
+			log.Fatal("Failed to migrate search columns:", err)
+		}
+	}
 
-	DB = db
+	return db
 }
 
-// createIndexes adds database indexes for query optimization
-func createIndexes(db *gorm.DB) {
-	// Add indexes to Worker table
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_workers_name ON workers(name)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_workers_position ON workers(position)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_workers_salary ON workers(salary)")
-	
-	// Add indexes to Project table
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_projects_name ON projects(name)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_projects_status ON projects(status)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_projects_start_date ON projects(start_date)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_projects_end_date ON projects(end_date)")
-	
-	// Add composite index for geospatial queries
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_projects_location ON projects(latitude, longitude)")
-	
-	// Add index for the many-to-many relationship
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_worker_projects_worker_id ON worker_projects(worker_id)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_worker_projects_project_id ON worker_projects(project_id)")
-	
-	// Add indexes for User table
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_users_username ON users(username)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_users_role ON users(role)")
+// migrateAssignmentIDs rebuilds the worker_projects table of databases created
+// before assignments had an ID of their own, when the worker and project IDs
+// made up its primary key. The assignments are copied over as they were.
+func migrateAssignmentIDs(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&model.WorkerProject{}) || migrator.HasColumn(&model.WorkerProject{}, "id") {
+		return nil
+	}
 
-	// Add index for user_id in worker_projects table
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_worker_projects_user_id ON worker_projects(user_id)")
-	
-	// Add indexes for ActivityLog table
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_activity_logs_user_id ON activity_logs(user_id)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_activity_logs_log_type ON activity_logs(log_type)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_activity_logs_entity_type ON activity_logs(entity_type)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_activity_logs_entity_id ON activity_logs(entity_id)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_activity_logs_created_at ON activity_logs(created_at)")
-	
-	log.Println("Database indexes created successfully")
+	return db.Transaction(func(tx *gorm.DB) error {
+		var assignments []model.WorkerProject
+		if err := tx.Find(&assignments).Error; err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropTable(&model.WorkerProject{}); err != nil {
+			return err
+		}
+		if err := tx.AutoMigrate(&model.WorkerProject{}); err != nil {
+			return err
+		}
+		if len(assignments) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&assignments, 500).Error
+	})
+}
+
+// searchColumns are the weighted tsvector expressions behind full-text search.
+// The "simple" configuration neither stems nor drops stop words, which suits
+// names and trade positions, and matches the tokenizer of the search package.
+var searchColumns = map[string]string{
+	"workers": "setweight(to_tsvector('simple', coalesce(name, '')), 'A') || " +
+		"setweight(to_tsvector('simple', coalesce(position, '')), 'B')",
+	"projects": "setweight(to_tsvector('simple', coalesce(name, '')), 'A') || " +
+		"setweight(to_tsvector('simple', coalesce(description, '')), 'B')",
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		// In production, don't use defaults for sensitive information
-		if os.Getenv("ENV") == "production" && 
-		   (key == "DB_PASSWORD" || key == "JWT_SECRET") {
-			log.Fatalf("Environment variable %s must be set in production mode", key)
+// migrateSearchColumns adds the generated search_vector columns and their GIN
+// indexes on Postgres. The columns are not part of the models, so GORM never
+// writes them; Postgres keeps them up to date.
+func migrateSearchColumns(db *gorm.DB) error {
+	for table, expression := range searchColumns {
+		statements := []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (%s) STORED", table, expression),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_search_vector ON %s USING GIN (search_vector)", table, table),
 		}
-		return defaultValue
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// openDialector returns the GORM dialector for the configured driver
+func openDialector(cfg DatabaseConfig) gorm.Dialector {
+	if cfg.Driver == DriverSQLite {
+		// Enforce foreign keys and wait on locks held by concurrent writers
+		// (such as the background activity logger) instead of failing
+		dsn := cfg.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+		if cfg.Path != SQLiteMemory {
+			dsn += "&_pragma=journal_mode(WAL)"
+		}
+		return sqlite.Open(dsn)
 	}
-	return value
-} 
\ No newline at end of file
+
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		cfg.Host,
+		cfg.User,
+		cfg.Password,
+		cfg.Name,
+		cfg.Port,
+		cfg.SSLMode,
+	)
+	return postgres.Open(dsn)
+}
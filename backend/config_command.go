@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
+)
+
+// runConfigCommand handles "config <subcommand>" and returns the exit code
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: main config print [--redacted] [flags]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	redacted := fs.Bool("redacted", false, "mask secrets in the output")
+
+	cfg, err := config.Load(fs, args[1:])
+	var validationErr *config.ValidationError
+	if err != nil && !errors.As(err, &validationErr) {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *redacted {
+		cfg = cfg.Redacted()
+	}
+	if err := cfg.Write(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// Still report validation problems so the printed config can be fixed
+	if validationErr != nil {
+		fmt.Fprintln(os.Stderr, validationErr)
+		return 1
+	}
+	return 0
+}
@@ -2,9 +2,12 @@ package controller
 
 import (
 	"net/http"
-	"strconv"
 
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/pagination"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 )
 
@@ -12,58 +15,51 @@ type AdminController interface {
 	GetAllUsers(c echo.Context) error
 	UpdateUserStatus(c echo.Context) error
 	UpdateUserRole(c echo.Context) error
+	UpdateUserManager(c echo.Context) error
 	GetUserActivity(c echo.Context) error
 }
 
 type adminController struct {
 	userRepo repository.UserRepository
-	logRepo  *repository.LogRepository
+	logRepo  repository.LogRepository
+	validate *validator.Validate
 }
 
-func NewAdminController(userRepo repository.UserRepository, logRepo *repository.LogRepository) AdminController {
+func NewAdminController(userRepo repository.UserRepository, logRepo repository.LogRepository) AdminController {
 	return &adminController{
 		userRepo: userRepo,
 		logRepo:  logRepo,
+		validate: newValidator(),
 	}
 }
 
 // GetAllUsers returns a list of all users
 func (c *adminController) GetAllUsers(ctx echo.Context) error {
 	// Extract query parameters for pagination
-	page, _ := strconv.Atoi(ctx.QueryParam("page"))
-	if page < 1 {
-		page = 1
-	}
-	
-	pageSize, _ := strconv.Atoi(ctx.QueryParam("pageSize"))
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20 // Default page size
+	params, err := pagination.Parse(ctx, 20)
+	if err != nil {
+		return err
 	}
 	
 	// Extract query parameter for search
 	search := ctx.QueryParam("search")
 	
 	// Get users from repository
-	users, total, err := c.userRepo.GetAllUsers(page, pageSize, search)
+	users, total, err := c.userRepo.GetAllUsers(ctx.Request().Context(), params.Page, params.PageSize, search)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch users")
+		return err
 	}
 	
 	// Return paginated response
-	return ctx.JSON(http.StatusOK, map[string]interface{}{
-		"data":     users,
-		"total":    total,
-		"page":     page,
-		"pageSize": pageSize,
-	})
+	return pagination.Respond(ctx, "data", users, params, repository.PageInfo{Total: total})
 }
 
 // UpdateUserStatus activates or deactivates a user
 func (c *adminController) UpdateUserStatus(ctx echo.Context) error {
 	// Get user ID from path parameter
-	userID, err := strconv.Atoi(ctx.Param("id"))
+	userID, err := pathID(ctx, "id")
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+		return err
 	}
 	
 	// Parse request body
@@ -72,12 +68,12 @@ func (c *adminController) UpdateUserStatus(ctx echo.Context) error {
 	}
 	
 	if err := ctx.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+		return err
 	}
 	
 	// Update user status
-	if err := c.userRepo.UpdateUserStatus(uint(userID), req.Active); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update user status")
+	if err := c.userRepo.UpdateUserStatus(ctx.Request().Context(), userID, req.Active); err != nil {
+		return notFound(err, "User not found")
 	}
 	
 	return ctx.JSON(http.StatusOK, map[string]interface{}{
@@ -88,28 +84,28 @@ func (c *adminController) UpdateUserStatus(ctx echo.Context) error {
 // UpdateUserRole changes a user's role
 func (c *adminController) UpdateUserRole(ctx echo.Context) error {
 	// Get user ID from path parameter
-	userID, err := strconv.Atoi(ctx.Param("id"))
+	userID, err := pathID(ctx, "id")
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+		return err
 	}
 	
 	// Parse request body
 	var req struct {
-		Role string `json:"role" validate:"required,oneof=user admin"`
+		Role string `json:"role" validate:"required,oneof=user foreman manager admin"`
 	}
 	
 	if err := ctx.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+		return err
 	}
 	
 	// Check if role is valid
-	if req.Role != "user" && req.Role != "admin" {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid role. Must be 'user' or 'admin'")
+	if err := c.validate.Struct(req); err != nil {
+		return err
 	}
 	
 	// Update user role
-	if err := c.userRepo.UpdateUserRole(uint(userID), req.Role); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update user role")
+	if err := c.userRepo.UpdateUserRole(ctx.Request().Context(), userID, req.Role); err != nil {
+		return notFound(err, "User not found")
 	}
 	
 	return ctx.JSON(http.StatusOK, map[string]interface{}{
@@ -117,50 +113,105 @@ func (c *adminController) UpdateUserRole(ctx echo.Context) error {
 	})
 }
 
+// UpdateUserManager sets the manager approving a user's timesheets. The
+// manager must be another user with the manager or admin role; a null
+// manager_id clears it.
+func (c *adminController) UpdateUserManager(ctx echo.Context) error {
+	// Get user ID from path parameter
+	userID, err := pathID(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req struct {
+		ManagerID *uint `json:"manager_id"`
+	}
+
+	if err := ctx.Bind(&req); err != nil {
+		return err
+	}
+
+	// Check that the manager can approve timesheets
+	if req.ManagerID != nil {
+		invalid := func(message string) error {
+			return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Validation failed").
+				WithDetails(apierror.FieldError{Field: "manager_id", Code: "manager", Message: message})
+		}
+		if *req.ManagerID == userID {
+			return invalid("must be another user")
+		}
+		manager, err := c.userRepo.GetUserByID(ctx.Request().Context(), *req.ManagerID)
+		if err != nil {
+			return notFound(err, "Manager not found")
+		}
+		if manager.Role != model.RoleManager && manager.Role != model.RoleAdmin {
+			return invalid("must have the manager or admin role")
+		}
+	}
+
+	// Update user manager
+	if err := c.userRepo.UpdateUserManager(ctx.Request().Context(), userID, req.ManagerID); err != nil {
+		return notFound(err, "User not found")
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"message": "User manager updated successfully",
+	})
+}
+
 // GetUserActivity returns a user's recent activity
 func (c *adminController) GetUserActivity(ctx echo.Context) error {
 	// Get user ID from path parameter
-	userID, err := strconv.Atoi(ctx.Param("id"))
+	userID, err := pathID(ctx, "id")
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+		return err
 	}
 	
 	// Get user to verify existence
-	user, err := c.userRepo.GetUserByID(uint(userID))
+	user, err := c.userRepo.GetUserByID(ctx.Request().Context(), userID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+		return notFound(err, "User not found")
 	}
 	
 	// Extract pagination parameters
-	page, _ := strconv.Atoi(ctx.QueryParam("page"))
-	if page < 1 {
-		page = 1
+	params, err := pagination.Parse(ctx, 20)
+	if err != nil {
+		return err
 	}
 	
-	pageSize, _ := strconv.Atoi(ctx.QueryParam("pageSize"))
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20 // Default page size
+	// Activity is listed newest first unless another order is requested
+	spec, err := parseListQuery(ctx, repository.LogFields, nil)
+	if err != nil {
+		return err
 	}
-	
+	if len(spec.Sort) == 0 {
+		spec.AddSort(repository.LogFields, "created_at", true)
+	}
+
+	cursor, err := parseCursor(spec, params)
+	if err != nil {
+		return err
+	}
+
 	// Get user activity logs
-	logs, total, err := c.logRepo.GetLogsByUser(uint(userID), page, pageSize)
+	opts := repository.ListOptions{Query: spec, Cursor: cursor, Page: params.Page, PageSize: params.PageSize}
+	logs, info, err := c.logRepo.ListLogs(ctx.Request().Context(), repository.LogFilter{UserID: userID}, opts)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch user activity")
+		return err
 	}
-	
-	return ctx.JSON(http.StatusOK, map[string]interface{}{
-		"user": map[string]interface{}{
-			"id":         user.ID,
-			"username":   user.Username,
-			"email":      user.Email,
-			"role":       user.Role,
-			"active":     user.Active,
-			"last_login": user.LastLogin,
-			"created_at": user.CreatedAt,
-		},
-		"activity": logs,
-		"total":    total,
-		"page":     page,
-		"pageSize": pageSize,
-	})
-} 
\ No newline at end of file
+
+	response := pagination.Body("activity", logs, params, info)
+	response["user"] = map[string]interface{}{
+		"id":         user.ID,
+		"username":   user.Username,
+		"email":      user.Email,
+		"role":       user.Role,
+		"manager_id": user.ManagerID,
+		"active":     user.Active,
+		"last_login": user.LastLogin,
+		"created_at": user.CreatedAt,
+	}
+	pagination.SetLinkHeader(ctx, params, info)
+	return ctx.JSON(http.StatusOK, response)
+}
\ No newline at end of file
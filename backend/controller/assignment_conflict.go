@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// conflictFields maps the kinds of conflicts to the request field causing them
+var conflictFields = map[string]string{
+	repository.ConflictOverAllocation: "allocation_percent",
+	repository.ConflictBeforeProject:  "start_date",
+	repository.ConflictAfterProject:   "end_date",
+}
+
+// parseOverride reads the override query parameter, which lets an
+// assignment through despite its conflicts
+func parseOverride(ctx echo.Context) (bool, error) {
+	raw := ctx.QueryParam("override")
+	if raw == "" {
+		return false, nil
+	}
+	override, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, apierror.InvalidParameter("override", "must be true or false")
+	}
+	return override, nil
+}
+
+// checkConflicts rejects an assignment that would over-allocate the worker or
+// run outside its project, with one detail per conflict
+func checkConflicts(ctx context.Context, projects repository.ProjectRepository, assignment model.WorkerProject) error {
+	conflicts, err := projects.AssignmentConflicts(ctx, assignment)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	apiErr := apierror.New(http.StatusConflict, apierror.CodeAssignmentConflict,
+		"The assignment conflicts with the worker's schedule; retry with override=true to keep it anyway")
+	for _, conflict := range conflicts {
+		apiErr.WithDetails(apierror.FieldError{
+			Field:   conflictFields[conflict.Kind],
+			Code:    conflict.Kind,
+			Message: conflict.Message,
+		})
+	}
+	return apiErr
+}
+
+// GetConflicts handles GET /api/conflicts, listing the over-allocations and
+// the assignments outside their project for all of the user's workers
+func (c *ProjectController) GetConflicts(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	conflicts, err := c.repo.GetConflicts(ctx.Request().Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"data": conflicts,
+	})
+}
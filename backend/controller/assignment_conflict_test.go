@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+)
+
+func TestAssignmentConflictsAreRejectedUnlessOverridden(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+	workerID := createWorker(t, e, owner, "Ana Pop")
+	body := `{"workerId":` + strconv.FormatUint(uint64(workerID), 10) + `}`
+
+	var paths []string
+	for _, name := range []string{"North", "Depot"} {
+		rec := do(t, e, owner, http.MethodPost, "/api/projects", projectBody(name))
+		expectStatus(t, rec, http.StatusCreated)
+		var project struct {
+			ID uint `json:"id"`
+		}
+		decode(t, rec, &project)
+		paths = append(paths, "/api/projects/"+strconv.FormatUint(uint64(project.ID), 10)+"/workers")
+	}
+	expectStatus(t, do(t, e, owner, http.MethodPost, paths[0], body), http.StatusOK)
+
+	rec := do(t, e, owner, http.MethodPost, paths[1], body)
+	expectStatus(t, rec, http.StatusConflict)
+	var apiErr apierror.Error
+	decode(t, rec, &apiErr)
+	if apiErr.Code != apierror.CodeAssignmentConflict || len(apiErr.Details) != 1 ||
+		apiErr.Details[0].Code != repository.ConflictOverAllocation || apiErr.Details[0].Field != "allocation_percent" {
+		t.Fatalf("expected an over-allocation conflict, got %+v", apiErr)
+	}
+
+	// The rejected assignment was rolled back
+	rec = do(t, e, owner, http.MethodGet, "/api/workers/"+strconv.FormatUint(uint64(workerID), 10), "")
+	expectStatus(t, rec, http.StatusOK)
+	var worker struct {
+		Projects []struct{} `json:"projects"`
+	}
+	decode(t, rec, &worker)
+	if len(worker.Projects) != 1 {
+		t.Fatalf("expected the worker to stay on 1 project, got %d", len(worker.Projects))
+	}
+
+	expectStatus(t, do(t, e, owner, http.MethodPost, paths[1]+"?override=maybe", body), http.StatusBadRequest)
+	expectStatus(t, do(t, e, owner, http.MethodPost, paths[1]+"?override=true", body), http.StatusOK)
+}
@@ -4,9 +4,11 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/auth"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 )
 
@@ -17,11 +19,15 @@ type AuthController interface {
 
 type authController struct {
 	userRepo repository.UserRepository
+	tokens   *auth.TokenService
+	validate *validator.Validate
 }
 
-func NewAuthController(userRepo repository.UserRepository) AuthController {
+func NewAuthController(userRepo repository.UserRepository, tokens *auth.TokenService) AuthController {
 	return &authController{
 		userRepo: userRepo,
+		tokens:   tokens,
+		validate: newValidator(),
 	}
 }
 
@@ -42,37 +48,38 @@ type RegisterRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
-	Role     string `json:"role" validate:"omitempty,oneof=user admin"`
+	// Role may only ask for the default role; the others are granted by an admin
+	Role     string `json:"role" validate:"omitempty,oneof=user"`
 }
 
 // Login handles user authentication and returns a JWT token
 func (c *authController) Login(ctx echo.Context) error {
 	var req LoginRequest
 	if err := ctx.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+		return err
 	}
 	
 	// Validate the request
-	if req.Username == "" || req.Password == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "Username and password are required")
+	if err := c.validate.Struct(req); err != nil {
+		return err
 	}
 	
 	// Authenticate the user
-	user, err := c.userRepo.ValidateCredentials(req.Username, req.Password)
+	user, err := c.userRepo.ValidateCredentials(ctx.Request().Context(), req.Username, req.Password)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid credentials")
+		return apierror.Unauthorized("Invalid credentials")
 	}
 	
 	// Generate JWT token
-	token, err := auth.GenerateToken(user)
+	token, err := c.tokens.GenerateToken(user)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate token")
+		return apierror.Internal(err)
 	}
 	
 	// Update last login timestamp
 	now := time.Now()
 	user.LastLogin = &now
-	c.userRepo.UpdateLastLogin(user.ID)
+	c.userRepo.UpdateLastLogin(ctx.Request().Context(), user.ID)
 	
 	// Clear sensitive data
 	user.PasswordHash = ""
@@ -93,49 +100,58 @@ func (c *authController) Login(ctx echo.Context) error {
 func (c *authController) Register(ctx echo.Context) error {
 	var req RegisterRequest
 	if err := ctx.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+		return err
+	}
+	
+	// Validate the request
+	if err := c.validate.Struct(req); err != nil {
+		return err
 	}
 	
 	// Check if username already exists
-	existingUser, err := c.userRepo.GetUserByUsername(req.Username)
+	existingUser, err := c.userRepo.GetUserByUsername(ctx.Request().Context(), req.Username)
 	if err == nil && existingUser != nil {
-		return echo.NewHTTPError(http.StatusConflict, "Username already taken")
+		return apierror.Conflict("Username already taken").WithDetails(apierror.FieldError{
+			Field:   "username",
+			Code:    "unique",
+			Message: "is already taken",
+		})
 	}
 	
 	// Check if email already exists
-	existingEmail, err := c.userRepo.GetUserByEmail(req.Email)
+	existingEmail, err := c.userRepo.GetUserByEmail(ctx.Request().Context(), req.Email)
 	if err == nil && existingEmail != nil {
-		return echo.NewHTTPError(http.StatusConflict, "Email already registered")
-	}
-	
-	// Set default role if not provided
-	if req.Role == "" {
-		req.Role = "user"
+		return apierror.Conflict("Email already registered").WithDetails(apierror.FieldError{
+			Field:   "email",
+			Code:    "unique",
+			Message: "is already registered",
+		})
 	}
 	
-	// Create user object
+	// Create user object. New accounts always start as plain users; other
+	// roles are granted by an admin.
 	user := &model.User{
 		Username: req.Username,
 		Email:    req.Email,
-		Role:     req.Role,
+		Role:     model.RoleUser,
 		Active:   true,
 	}
 	
 	// Create the user in the database with hashed password
-	if err := c.userRepo.CreateUser(user, req.Password); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user")
+	if err := c.userRepo.CreateUser(ctx.Request().Context(), user, req.Password); err != nil {
+		return err
 	}
 	
 	// Generate JWT token
-	token, err := auth.GenerateToken(user)
+	token, err := c.tokens.GenerateToken(user)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate token")
+		return apierror.Internal(err)
 	}
 	
 	// Set last login timestamp
 	now := time.Now()
 	user.LastLogin = &now
-	c.userRepo.UpdateLastLogin(user.ID)
+	c.userRepo.UpdateLastLogin(ctx.Request().Context(), user.ID)
 	
 	// Clear sensitive data
 	user.PasswordHash = ""
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/auth"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository/memory"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRegisterOnlyGrantsTheUserRole(t *testing.T) {
+	cfg := config.Default().Auth
+	cfg.JWTSecret = "test-secret"
+	ctrl := NewAuthController(memory.NewUserRepository(memory.NewStore()), auth.NewTokenService(cfg))
+	e := echo.New()
+	e.HTTPErrorHandler = apierror.Handler
+	e.POST("/api/auth/register", ctrl.Register)
+
+	tests := []struct {
+		username string
+		role     string
+		status   int
+	}{
+		{username: "ana", role: "", status: http.StatusCreated},
+		{username: "ion", role: model.RoleUser, status: http.StatusCreated},
+		{username: "eve", role: model.RoleAdmin, status: http.StatusBadRequest},
+		{username: "max", role: model.RoleManager, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		body := `{"username":"` + tt.username + `","email":"` + tt.username + `@example.com","password":"password1"`
+		if tt.role != "" {
+			body += `,"role":"` + tt.role + `"`
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/register", strings.NewReader(body+"}"))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Fatalf("registering with role %q: expected status %d, got %d: %s", tt.role, tt.status, rec.Code, rec.Body.String())
+		}
+		if rec.Code != http.StatusCreated {
+			continue
+		}
+		var response LoginResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.User.Role != model.RoleUser {
+			t.Fatalf("registering with role %q: expected role %q, got %q", tt.role, model.RoleUser, response.User.Role)
+		}
+	}
+}
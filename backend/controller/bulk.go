@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/middleware"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// maxBulkOperations bounds the number of operations of one batch
+const maxBulkOperations = 500
+
+// Batch modes
+const (
+	// bulkAtomic writes every operation or, when any fails, none
+	bulkAtomic = "atomic"
+	// bulkBestEffort writes the operations that succeed and reports the others
+	bulkBestEffort = "best_effort"
+)
+
+// Batch operations
+const (
+	bulkCreate = "create"
+	bulkUpdate = "update"
+	bulkDelete = "delete"
+)
+
+// errBatchFailed rolls back an atomic batch with a failed operation
+var errBatchFailed = errors.New("batch failed")
+
+// bulkRequest is the body of a bulk endpoint
+type bulkRequest struct {
+	Mode       string          `json:"mode"`
+	Operations []bulkOperation `json:"operations"`
+}
+
+// bulkOperation creates a record from its data, updates the record with the
+// ID by applying its data as a merge patch, or deletes the record with the ID
+type bulkOperation struct {
+	Op   string          `json:"op"`
+	ID   uint            `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// bulkResult describes what a batch did with one operation. Status is the
+// status the operation would have been answered with on its own endpoint.
+type bulkResult struct {
+	Index  int             `json:"index"`
+	Op     string          `json:"op"`
+	ID     uint            `json:"id,omitempty"`
+	Status int             `json:"status"`
+	Data   interface{}     `json:"data,omitempty"`
+	Error  *apierror.Error `json:"error,omitempty"`
+}
+
+// bulkReport summarizes a batch
+type bulkReport struct {
+	Mode    string       `json:"mode"`
+	Created int          `json:"created"`
+	Updated int          `json:"updated"`
+	Deleted int          `json:"deleted"`
+	Failed  int          `json:"failed"`
+	Results []bulkResult `json:"results"`
+}
+
+// bulkExecutor runs one operation of a batch with the repositories of its
+// unit of work, returning the ID and state of the record written
+type bulkExecutor func(ctx context.Context, repos *repository.Repositories, op bulkOperation) (uint, interface{}, error)
+
+// runBulk runs a batch of operations in a single unit of work, each one in a
+// nested unit of work rolled back on its own when it fails. An atomic batch
+// (the default) is then rolled back as a whole and answered with the
+// failures; a best-effort batch keeps the operations that succeed. The
+// batch is logged as one activity entry summarizing what it wrote.
+func runBulk(ctx echo.Context, uow repository.UnitOfWork, entityType model.EntityType, execute bulkExecutor) error {
+	var request bulkRequest
+	if err := ctx.Bind(&request); err != nil {
+		return err
+	}
+	if err := checkBulkRequest(&request); err != nil {
+		return err
+	}
+
+	report := bulkReport{Mode: request.Mode, Results: make([]bulkResult, 0, len(request.Operations))}
+	reqCtx := ctx.Request().Context()
+	err := uow.Do(reqCtx, func(repos *repository.Repositories) error {
+		for i, op := range request.Operations {
+			result := bulkResult{Index: i, Op: op.Op, ID: op.ID}
+			var id uint
+			var data interface{}
+			err := repos.Nested.Do(reqCtx, func(repos *repository.Repositories) error {
+				var err error
+				id, data, err = execute(reqCtx, repos, op)
+				return err
+			})
+			if err != nil {
+				apiErr := apierror.From(err)
+				// Failures of the database or the request abort the batch
+				if apiErr.Status >= http.StatusInternalServerError {
+					return err
+				}
+				result.Status, result.Error = apiErr.Status, apiErr
+				report.Failed++
+				report.Results = append(report.Results, result)
+				continue
+			}
+
+			result.ID, result.Data = id, data
+			switch op.Op {
+			case bulkCreate:
+				result.Status = http.StatusCreated
+				report.Created++
+			case bulkUpdate:
+				result.Status = http.StatusOK
+				report.Updated++
+			case bulkDelete:
+				result.Status = http.StatusNoContent
+				report.Deleted++
+			}
+			report.Results = append(report.Results, result)
+		}
+
+		if request.Mode == bulkAtomic && report.Failed > 0 {
+			return errBatchFailed
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchFailed) {
+		return bulkFailure(report, len(request.Operations))
+	}
+	if err != nil {
+		return err
+	}
+
+	// Log the batch once; a batch that wrote nothing is not logged
+	summary := ""
+	if written := report.Created + report.Updated + report.Deleted; written > 0 {
+		summary = fmt.Sprintf("%s %s: %d created, %d updated, %d deleted, %d failed",
+			model.LogTypeBulk, entityType, report.Created, report.Updated, report.Deleted, report.Failed)
+	}
+	ctx.Set(middleware.LogSummaryKey, summary)
+
+	return ctx.JSON(http.StatusOK, report)
+}
+
+// checkBulkRequest checks the mode and the shape of every operation of a
+// batch before any of them runs
+func checkBulkRequest(request *bulkRequest) error {
+	if request.Mode == "" {
+		request.Mode = bulkAtomic
+	}
+	if request.Mode != bulkAtomic && request.Mode != bulkBestEffort {
+		return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid batch").
+			WithDetails(apierror.FieldError{Field: "mode", Code: "oneof", Message: "must be one of atomic best_effort"})
+	}
+	if len(request.Operations) == 0 || len(request.Operations) > maxBulkOperations {
+		return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid batch").
+			WithDetails(apierror.FieldError{Field: "operations", Code: "len", Message: fmt.Sprintf("must hold between 1 and %d operations", maxBulkOperations)})
+	}
+
+	var details []apierror.FieldError
+	invalid := func(i int, field, code, message string) {
+		details = append(details, apierror.FieldError{Field: fmt.Sprintf("operations[%d].%s", i, field), Code: code, Message: message})
+	}
+	for i, op := range request.Operations {
+		switch op.Op {
+		case bulkCreate, bulkUpdate, bulkDelete:
+		default:
+			invalid(i, "op", "oneof", "must be one of create update delete")
+			continue
+		}
+		if op.Op != bulkCreate && op.ID == 0 {
+			invalid(i, "id", "required", "is required")
+		}
+		if op.Op != bulkDelete && !isJSONObject(op.Data) {
+			invalid(i, "data", "required", "must be an object")
+		}
+	}
+	if len(details) > 0 {
+		return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid batch").WithDetails(details...)
+	}
+	return nil
+}
+
+// bulkFailure reports the failed operations of a rolled back atomic batch,
+// their details prefixed with the path of the operation
+func bulkFailure(report bulkReport, total int) error {
+	var details []apierror.FieldError
+	for _, result := range report.Results {
+		if result.Error == nil {
+			continue
+		}
+		prefix := fmt.Sprintf("operations[%d]", result.Index)
+		if len(result.Error.Details) == 0 {
+			details = append(details, apierror.FieldError{Field: prefix, Code: result.Error.Code, Message: result.Error.Message})
+			continue
+		}
+		for _, detail := range result.Error.Details {
+			detail.Field = prefix + ".data." + detail.Field
+			details = append(details, detail)
+		}
+	}
+	return apierror.New(http.StatusBadRequest, apierror.CodeBulkFailed,
+		fmt.Sprintf("%d of %d operations failed; nothing was written", report.Failed, total)).WithDetails(details...)
+}
+
+// decodeBulkData decodes the data of an operation, reporting mistyped members
+// as field errors
+func decodeBulkData(data json.RawMessage, target interface{}) error {
+	if err := json.Unmarshal(data, target); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Validation failed").
+				WithDetails(apierror.FieldError{Field: typeErr.Field, Code: "type", Message: "must be of type " + typeErr.Type.String()})
+		}
+		return apierror.BadRequest("The data must be a JSON object").Wrap(err)
+	}
+	return nil
+}
+
+// isJSONObject reports whether a raw JSON value is an object
+func isJSONObject(data json.RawMessage) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(data)), "{")
+}
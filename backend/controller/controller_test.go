@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository/memory"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/spreadsheet"
+	"github.com/labstack/echo/v4"
+)
+
+// testUserHeader names the user a test request is made by, standing in for
+// the JWT middleware
+const testUserHeader = "X-Test-User"
+
+// newTestServer routes the endpoints under test to controllers backed by a
+// fresh in-memory store
+func newTestServer() *echo.Echo {
+	return newTestServerOn(memory.NewStore())
+}
+
+// newTestServerOn routes the endpoints under test to controllers backed by
+// the store, for tests preparing data the endpoints cannot, such as users
+func newTestServerOn(store *memory.Store) *echo.Echo {
+	uow := memory.NewUnitOfWork(store)
+	workerCtrl := NewWorkerController(memory.NewWorkerRepository(store), uow)
+	projectCtrl := NewProjectController(memory.NewProjectRepository(store), uow)
+	timesheetCtrl := NewTimesheetController(memory.NewTimesheetRepository(store), memory.NewUserRepository(store), uow)
+
+	e := echo.New()
+	e.HTTPErrorHandler = apierror.Handler
+	api := e.Group("/api", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if id, err := strconv.ParseUint(c.Request().Header.Get(testUserHeader), 10, 32); err == nil {
+				c.Set("user_id", uint(id))
+			}
+			return next(c)
+		}
+	})
+	api.GET("/workers", workerCtrl.GetAllWorkers)
+	api.GET("/workers/export.csv", workerCtrl.ExportWorkers(spreadsheet.CSV))
+	api.GET("/workers/export.xlsx", workerCtrl.ExportWorkers(spreadsheet.XLSX))
+	api.GET("/workers/:id", workerCtrl.GetWorker)
+	api.POST("/workers", workerCtrl.CreateWorker)
+	api.POST("/workers/import", workerCtrl.ImportWorkers)
+	api.PUT("/workers/:id", workerCtrl.UpdateWorker)
+	api.PATCH("/workers/:id", workerCtrl.PatchWorker)
+	api.DELETE("/workers/:id", workerCtrl.DeleteWorker)
+	api.POST("/workers/:id/transfer", workerCtrl.TransferWorker)
+	api.GET("/projects", projectCtrl.GetAllProjects)
+	api.GET("/projects/:id", projectCtrl.GetProject)
+	api.GET("/projects/assignments/export.csv", projectCtrl.ExportAssignments(spreadsheet.CSV))
+	api.GET("/projects/:id/report.pdf", projectCtrl.ProjectReport)
+	api.POST("/projects", projectCtrl.CreateProject)
+	api.POST("/projects/:id/workers", projectCtrl.AssignWorkerToProject)
+	api.GET("/timesheets/approvals", timesheetCtrl.GetApprovals)
+	api.POST("/timesheets", timesheetCtrl.CreateTimesheet)
+	api.PUT("/timesheets/:id", timesheetCtrl.UpdateTimesheet)
+	api.POST("/timesheets/:id/submit", timesheetCtrl.SubmitTimesheet)
+	api.POST("/timesheets/:id/approve", timesheetCtrl.ApproveTimesheet)
+	api.POST("/timesheets/:id/reject", timesheetCtrl.RejectTimesheet)
+	return e
+}
+
+// do sends a request as the user and returns the recorded response
+func do(t *testing.T, e *echo.Echo, userID uint, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(testUserHeader, strconv.FormatUint(uint64(userID), 10))
+	req.Header.Set("If-Match", "*")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// expectStatus fails the test unless the response has the status
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("expected status %d, got %d: %s", status, rec.Code, rec.Body.String())
+	}
+}
+
+// decode unmarshals the body of a response into v
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+}
+
+// createWorker creates a worker of the user and returns its ID
+func createWorker(t *testing.T, e *echo.Echo, userID uint, name string) uint {
+	t.Helper()
+	rec := do(t, e, userID, http.MethodPost, "/api/workers",
+		`{"name":"`+name+`","age":30,"position":"Mason","salary":3000}`)
+	expectStatus(t, rec, http.StatusCreated)
+	var worker struct {
+		ID uint `json:"id"`
+	}
+	decode(t, rec, &worker)
+	return worker.ID
+}
+
+// projectBody is the body of a valid project assigning the workers
+func projectBody(name string, workerIDs ...uint) string {
+	ids := make([]string, len(workerIDs))
+	for i, id := range workerIDs {
+		ids[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return `{"name":"` + name + `","description":"A test building site","status":"active",` +
+		`"start_date":"2026-03-01T00:00:00Z","latitude":46.77,"longitude":23.59,` +
+		`"worker_ids":[` + strings.Join(ids, ",") + `]}`
+}
+
+// total returns the total of a list response
+func total(t *testing.T, rec *httptest.ResponseRecorder) int64 {
+	t.Helper()
+	var page struct {
+		Total int64 `json:"total"`
+	}
+	decode(t, rec, &page)
+	return page.Total
+}
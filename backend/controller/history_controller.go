@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/pagination"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/labstack/echo/v4"
+)
+
+type HistoryController struct {
+	repo repository.HistoryRepository
+}
+
+func NewHistoryController(repo repository.HistoryRepository) *HistoryController {
+	return &HistoryController{
+		repo: repo,
+	}
+}
+
+// GetWorkerHistory handles GET /api/workers/:id/history
+func (c *HistoryController) GetWorkerHistory(ctx echo.Context) error {
+	return c.history(ctx, model.EntityTypeWorker, "Worker not found")
+}
+
+// GetProjectHistory handles GET /api/projects/:id/history
+func (c *HistoryController) GetProjectHistory(ctx echo.Context) error {
+	return c.history(ctx, model.EntityTypeProject, "Project not found")
+}
+
+// history lists the changes of an entity, newest first
+func (c *HistoryController) history(ctx echo.Context, entityType model.EntityType, notFoundMessage string) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := pathID(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	params, err := pagination.Parse(ctx, 20)
+	if err != nil {
+		return err
+	}
+	if params.CursorMode {
+		return apierror.InvalidParameter("cursor", "is not supported by the history")
+	}
+
+	changes, total, err := c.repo.List(ctx.Request().Context(), userID, entityType, id, params.Page, params.PageSize)
+	if err != nil {
+		return notFound(err, notFoundMessage)
+	}
+
+	return pagination.Respond(ctx, "data", changes, params, repository.PageInfo{Total: total})
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/pagination"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/query"
+	"github.com/labstack/echo/v4"
+)
+
+// legacyFilter maps an older single-purpose query parameter (e.g. min_age)
+// onto a condition of the filter language
+type legacyFilter struct {
+	param string
+	field string
+	op    query.Operator
+}
+
+// parseListQuery builds the filter and sort spec of a list endpoint from the
+// "filter" and "sort" parameters, the legacy filter parameters and the legacy
+// "sort_by"/"sort_order" pair. Every field is checked against the schema.
+func parseListQuery(ctx echo.Context, schema query.Schema, legacyFilters []legacyFilter) (*query.Spec, error) {
+	spec, err := query.Parse(ctx.QueryParam("filter"), ctx.QueryParam("sort"), schema)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, legacy := range legacyFilters {
+		if value := ctx.QueryParam(legacy.param); value != "" {
+			if err := spec.AddCondition(schema, legacy.field, legacy.op, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if sortBy := ctx.QueryParam("sort_by"); sortBy != "" {
+		if err := spec.AddSort(schema, sortBy, ctx.QueryParam("sort_order") == "desc"); err != nil {
+			return nil, err
+		}
+	}
+
+	return spec, nil
+}
+
+// parseCursor decodes the cursor of a keyset paginated request against the
+// sort spec. It returns a nil cursor in offset mode.
+func parseCursor(spec *query.Spec, params pagination.Params) (*query.Cursor, error) {
+	if !params.CursorMode {
+		return nil, nil
+	}
+	return spec.ParseCursor(params.Cursor)
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/mergepatch"
+	"github.com/labstack/echo/v4"
+)
+
+// workerPatchFields are the worker fields a merge patch may change
+var workerPatchFields = []string{"name", "age", "position", "salary"}
+
+// projectPatchFields are the project fields a merge patch may change
+var projectPatchFields = []string{
+	"name", "description", "status", "start_date", "end_date",
+	"latitude", "longitude", "boundaries", "locations",
+}
+
+// mergePatch applies the merge patch in the request body to the current
+// state of a record, like applyMergePatch, taking the expected version from
+// If-Match when present
+func mergePatch[T any](ctx echo.Context, current *T, version uint, editable []string, entity string) (*T, []string, error) {
+	mediaType, _, _ := mime.ParseMediaType(ctx.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != mergepatch.MediaType && mediaType != echo.MIMEApplicationJSON {
+		return nil, nil, apierror.New(http.StatusUnsupportedMediaType, "unsupported_media_type",
+			fmt.Sprintf("Send the patch as %s", mergepatch.MediaType))
+	}
+
+	body, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	expected, present, err := ifMatch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	patched, changed, err := applyMergePatch(body, expected, present, current, version, editable, entity)
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusConflict {
+		setETag(ctx, version)
+	}
+	return patched, changed, err
+}
+
+// applyMergePatch applies a merge patch to the current state of a record and
+// returns the patched record with the JSON names of the fields whose value
+// changes. Members left untouched or set to their current value are not
+// reported, so a client may send a whole record back; changing any other
+// member than the editable ones is rejected. The patch may state the version
+// it is based on as a "version" member, unless an expected version is
+// present already; a stated version must be the current one.
+func applyMergePatch[T any](body []byte, expected uint, present bool, current *T, version uint, editable []string, entity string) (*T, []string, error) {
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		return nil, nil, apierror.BadRequest("The merge patch must be a JSON object")
+	}
+
+	// The version is a precondition, not a change
+	if raw, ok := patch["version"]; ok {
+		delete(patch, "version")
+		var bodyVersion uint
+		if err := json.Unmarshal(raw, &bodyVersion); err != nil {
+			return nil, nil, invalidPatchValue(err, "version")
+		}
+		if !present {
+			expected = bodyVersion
+		}
+	}
+	if expected != 0 && expected != version {
+		return nil, nil, apierror.Conflict(fmt.Sprintf("The %s was modified by another request", entity)).WithCurrent(current)
+	}
+
+	document, err := json.Marshal(current)
+	if err != nil {
+		return nil, nil, err
+	}
+	var before map[string]json.RawMessage
+	if err := json.Unmarshal(document, &before); err != nil {
+		return nil, nil, err
+	}
+
+	var details []apierror.FieldError
+	for name := range patch {
+		if _, ok := before[name]; !ok {
+			details = append(details, apierror.FieldError{Field: name, Code: "unknown_field", Message: "is not a field of the " + entity})
+		}
+	}
+
+	patchDocument, err := json.Marshal(patch)
+	if err != nil {
+		return nil, nil, err
+	}
+	merged, err := mergepatch.Apply(document, patchDocument)
+	if err != nil {
+		return nil, nil, err
+	}
+	patched := new(T)
+	if err := json.Unmarshal(merged, patched); err != nil {
+		return nil, nil, invalidPatchValue(err, "")
+	}
+
+	// Compare the record before and after, in its JSON form
+	document, err = json.Marshal(patched)
+	if err != nil {
+		return nil, nil, err
+	}
+	var after map[string]json.RawMessage
+	if err := json.Unmarshal(document, &after); err != nil {
+		return nil, nil, err
+	}
+	changed := make([]string, 0)
+	for name, value := range before {
+		if bytes.Equal(value, after[name]) {
+			continue
+		}
+		if slices.Contains(editable, name) {
+			changed = append(changed, name)
+			continue
+		}
+		details = append(details, apierror.FieldError{Field: name, Code: "read_only", Message: "cannot be changed"})
+	}
+
+	if len(details) > 0 {
+		sort.Slice(details, func(i, j int) bool { return details[i].Field < details[j].Field })
+		return nil, nil, apierror.BadRequest("Invalid merge patch").WithDetails(details...)
+	}
+	sort.Strings(changed)
+	return patched, changed, nil
+}
+
+// invalidPatchValue reports a member of a merge patch whose value does not
+// decode into its field, naming the field when the decoding error does
+func invalidPatchValue(err error, field string) *apierror.Error {
+	apiErr := apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid merge patch").Wrap(err)
+	message := "has an invalid value"
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field != "" {
+			field = typeErr.Field
+		}
+		message = fmt.Sprintf("must be of type %s", typeErr.Type)
+	}
+	if field == "" {
+		return apiErr
+	}
+	return apiErr.WithDetails(apierror.FieldError{Field: field, Code: "type", Message: message})
+}
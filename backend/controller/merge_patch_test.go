@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	current := &model.Worker{ID: 7, Name: "Ana Pop", Age: 30, Position: "Mason", Salary: 3000, UserID: 1, Version: 3}
+
+	patched, changed, err := applyMergePatch([]byte(`{"salary":3500,"position":"Mason","version":3}`),
+		0, false, current, current.Version, workerPatchFields, "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changed, []string{"salary"}) {
+		t.Fatalf("expected only the salary to change, got %v", changed)
+	}
+	if patched.Salary != 3500 || patched.Name != "Ana Pop" || current.Salary != 3000 {
+		t.Fatalf("expected a patched copy, got %+v from %+v", patched, current)
+	}
+}
+
+func TestApplyMergePatchErrors(t *testing.T) {
+	current := &model.Worker{ID: 7, Name: "Ana Pop", Age: 30, Position: "Mason", Salary: 3000, UserID: 1, Version: 3}
+	tests := []struct {
+		name   string
+		patch  string
+		status int
+		code   string
+		field  string
+	}{
+		{name: "not an object", patch: `[1]`, status: http.StatusBadRequest, code: apierror.CodeBadRequest},
+		{name: "unknown field", patch: `{"nickname":"Ana"}`, status: http.StatusBadRequest, code: apierror.CodeBadRequest, field: "nickname"},
+		{name: "read-only field", patch: `{"user_id":2}`, status: http.StatusBadRequest, code: apierror.CodeBadRequest, field: "user_id"},
+		{name: "mistyped field", patch: `{"age":"thirty"}`, status: http.StatusBadRequest, code: apierror.CodeValidationFailed, field: "age"},
+		{name: "mistyped version", patch: `{"version":"3"}`, status: http.StatusBadRequest, code: apierror.CodeValidationFailed, field: "version"},
+		{name: "stale version", patch: `{"salary":1,"version":2}`, status: http.StatusConflict, code: apierror.CodeConflict},
+	}
+	for _, tt := range tests {
+		_, _, err := applyMergePatch([]byte(tt.patch), 0, false, current, current.Version, workerPatchFields, "worker")
+		var apiErr *apierror.Error
+		if !errors.As(err, &apiErr) {
+			t.Errorf("%s: expected an API error, got %v", tt.name, err)
+			continue
+		}
+		if apiErr.Status != tt.status || apiErr.Code != tt.code {
+			t.Errorf("%s: expected %d %s, got %d %s", tt.name, tt.status, tt.code, apiErr.Status, apiErr.Code)
+		}
+		if tt.field != "" && (len(apiErr.Details) != 1 || apiErr.Details[0].Field != tt.field) {
+			t.Errorf("%s: expected details on %s, got %+v", tt.name, tt.field, apiErr.Details)
+		}
+	}
+}
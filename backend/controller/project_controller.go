@@ -1,24 +1,35 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/geo"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/middleware"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/pagination"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/query"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 type ProjectController struct {
-	repo *repository.ProjectRepository
+	repo repository.ProjectRepository
+	uow  repository.UnitOfWork
 	validate *validator.Validate
 }
 
-func NewProjectController(repo *repository.ProjectRepository) *ProjectController {
+func NewProjectController(repo repository.ProjectRepository, uow repository.UnitOfWork) *ProjectController {
 	return &ProjectController{
 		repo: repo,
-		validate: validator.New(),
+		uow:  uow,
+		validate: newValidator(),
 	}
 }
 
@@ -30,51 +41,164 @@ func (c *ProjectController) GetAllProjects(ctx echo.Context) error {
 		return err
 	}
 
-	// Get query parameters for filtering and sorting
-	filters := make(map[string]interface{})
-	if name := ctx.QueryParam("name"); name != "" {
-		filters["name"] = name
+	// Get query parameters for filtering and sorting, validated against the project whitelist
+	spec, err := parseProjectQuery(ctx)
+	if err != nil {
+		return err
 	}
-	if status := ctx.QueryParam("status"); status != "" {
-		filters["status"] = status
+
+	// Get pagination parameters
+	params, err := pagination.Parse(ctx, pagination.DefaultPageSize)
+	if err != nil {
+		return err
 	}
-	if search := ctx.QueryParam("search"); search != "" {
-		filters["search"] = search
+
+	cursor, err := parseCursor(spec, params)
+	if err != nil {
+		return err
 	}
 
-	sortBy := ctx.QueryParam("sort_by")
-	sortOrder := ctx.QueryParam("sort_order")
+	// Location queries (near, bbox) are sorted by distance
+	area, err := parseGeoQuery(ctx)
+	if err != nil {
+		return err
+	}
+	if area != nil && params.CursorMode {
+		return apierror.InvalidParameter("cursor", "is not supported with near or bbox")
+	}
 
-	// Get pagination parameters
-	page := 1
-	pageSize := 10
+	opts := repository.ListOptions{
+		Search:   ctx.QueryParam("search"),
+		Query:    spec,
+		Cursor:   cursor,
+		Page:     params.Page,
+		PageSize: params.PageSize,
+	}
 
-	if pageParam := ctx.QueryParam("page"); pageParam != "" {
-		if parsedPage, err := strconv.Atoi(pageParam); err == nil && parsedPage > 0 {
-			page = parsedPage
+	if area != nil {
+		projects, info, err := c.repo.GetNearby(ctx.Request().Context(), userID, *area, opts)
+		if err != nil {
+			return err
 		}
+		return pagination.Respond(ctx, "data", projects, params, info)
 	}
 
-	if pageSizeParam := ctx.QueryParam("page_size"); pageSizeParam != "" {
-		if parsedPageSize, err := strconv.Atoi(pageSizeParam); err == nil && parsedPageSize > 0 {
-			pageSize = parsedPageSize
+	projects, info, err := c.repo.GetAll(ctx.Request().Context(), userID, opts)
+	if err != nil {
+		return err
+	}
+
+	// Return paginated response
+	return pagination.Respond(ctx, "data", projects, params, info)
+}
+
+// parseProjectQuery parses the filters and sort of a project list
+func parseProjectQuery(ctx echo.Context) (*query.Spec, error) {
+	return parseListQuery(ctx, repository.ProjectFields, []legacyFilter{
+		{param: "name", field: "name", op: query.OpEq},
+		{param: "status", field: "status", op: query.OpEq},
+	})
+}
+
+// GetNearestProjects handles GET /api/projects/nearest?near=lat,lng
+func (c *ProjectController) GetNearestProjects(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	raw := ctx.QueryParam("near")
+	if raw == "" {
+		return apierror.InvalidParameter("near", "is required")
+	}
+	origin, err := geo.ParsePoint(raw)
+	if err != nil {
+		return apierror.InvalidParameter("near", err.Error())
+	}
+
+	limit := defaultNearestLimit
+	if raw := ctx.QueryParam("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > pagination.MaxPageSize {
+			return apierror.InvalidParameter("limit", "must be an integer between 1 and 100")
 		}
 	}
 
-	projects, total, err := c.repo.GetAll(userID, filters, sortBy, sortOrder, page, pageSize)
+	// Filters narrow the candidates down; the sort only breaks distance ties
+	spec, err := parseListQuery(ctx, repository.ProjectFields, []legacyFilter{
+		{param: "status", field: "status", op: query.OpEq},
+	})
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return err
+	}
+
+	opts := repository.ListOptions{
+		Search: ctx.QueryParam("search"),
+		Query:  spec,
+	}
+	projects, err := c.repo.GetNearest(ctx.Request().Context(), userID, origin, limit, opts)
+	if err != nil {
+		return err
 	}
 
-	// Return paginated response
 	return ctx.JSON(http.StatusOK, map[string]interface{}{
-		"data":     projects,
-		"total":    total,
-		"page":     page,
-		"pageSize": pageSize,
+		"origin": origin,
+		"data":   projects,
 	})
 }
 
+// defaultNearestLimit is the number of projects returned by the nearest
+// endpoint when no limit is given
+const defaultNearestLimit = 5
+
+// parseGeoQuery reads the location parameters of the project list: near=lat,lng
+// with an optional radius_km, and bbox=minLng,minLat,maxLng,maxLat. Distances
+// are measured from near, or from the center of the box. It returns nil when
+// neither is given.
+func parseGeoQuery(ctx echo.Context) (*repository.GeoQuery, error) {
+	rawNear := ctx.QueryParam("near")
+	rawRadius := ctx.QueryParam("radius_km")
+	rawBox := ctx.QueryParam("bbox")
+	if rawNear == "" && rawBox == "" {
+		if rawRadius != "" {
+			return nil, apierror.InvalidParameter("radius_km", "requires near")
+		}
+		return nil, nil
+	}
+
+	var area repository.GeoQuery
+	if rawBox != "" {
+		box, err := geo.ParseBoundingBox(rawBox)
+		if err != nil {
+			return nil, apierror.InvalidParameter("bbox", err.Error())
+		}
+		area.Box = &box
+		area.Origin = box.Center()
+	}
+
+	if rawNear != "" {
+		origin, err := geo.ParsePoint(rawNear)
+		if err != nil {
+			return nil, apierror.InvalidParameter("near", err.Error())
+		}
+		area.Origin = origin
+	}
+
+	if rawRadius != "" {
+		if rawNear == "" {
+			return nil, apierror.InvalidParameter("radius_km", "requires near")
+		}
+		radius, err := strconv.ParseFloat(rawRadius, 64)
+		if err != nil || !(radius > 0) || radius > geo.MaxDistanceKm {
+			return nil, apierror.InvalidParameter("radius_km", "must be a positive number of kilometers up to 20015")
+		}
+		area.RadiusKm = radius
+	}
+
+	return &area, nil
+}
+
 // GetProject handles GET /api/projects/:id
 func (c *ProjectController) GetProject(ctx echo.Context) error {
 	// Get user ID from context
@@ -83,16 +207,17 @@ func (c *ProjectController) GetProject(ctx echo.Context) error {
 		return err
 	}
 
-	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	id, err := pathID(ctx, "id")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
+		return err
 	}
 
-	project, err := c.repo.GetByID(uint(id), userID)
+	project, err := c.repo.GetByID(ctx.Request().Context(), id, userID)
 	if err != nil {
-		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "Project not found"})
+		return notFound(err, "Project not found")
 	}
 
+	setETag(ctx, project.Version)
 	return ctx.JSON(http.StatusOK, project)
 }
 
@@ -104,21 +229,47 @@ func (c *ProjectController) CreateProject(ctx echo.Context) error {
 		return err
 	}
 
-	var project model.Project
-	if err := ctx.Bind(&project); err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	// The project may come with the IDs of workers to assign right away
+	var request struct {
+		model.Project
+		WorkerIDs []uint `json:"worker_ids"`
+	}
+	if err := ctx.Bind(&request); err != nil {
+		return err
 	}
+	project := request.Project
 
 	// Set user ID for the project
 	project.UserID = userID
 
 	// Validate project
 	if err := c.validate.Struct(project); err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return err
 	}
 
-	if err := c.repo.Create(&project); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	// Create the project and assign its workers atomically
+	err = c.uow.Do(ctx.Request().Context(), func(repos *repository.Repositories) error {
+		if err := repos.Projects.Create(ctx.Request().Context(), &project); err != nil {
+			return err
+		}
+		for _, workerID := range request.WorkerIDs {
+			if err := repos.Workers.AddToProject(ctx.Request().Context(), workerID, project.ID, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return notFound(err, "Worker not found")
+	}
+
+	// Reload the project so the response includes the assigned workers
+	if len(request.WorkerIDs) > 0 {
+		created, err := c.repo.GetByID(ctx.Request().Context(), project.ID, userID)
+		if err != nil {
+			return err
+		}
+		return ctx.JSON(http.StatusCreated, created)
 	}
 
 	return ctx.JSON(http.StatusCreated, project)
@@ -132,27 +283,186 @@ func (c *ProjectController) UpdateProject(ctx echo.Context) error {
 		return err
 	}
 
-	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	id, err := pathID(ctx, "id")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
+		return err
 	}
 
 	var project model.Project
 	if err := ctx.Bind(&project); err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return err
 	}
 
 	// Set project ID and user ID
-	project.ID = uint(id)
+	project.ID = id
 	project.UserID = userID
 
-	if err := c.repo.Update(&project, userID); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	// Only the version the client has seen may be overwritten
+	project.Version, err = expectedVersion(ctx, project.Version)
+	if err != nil {
+		return err
+	}
+
+	// New site geometry is checked against the location it will have
+	if project.Boundaries != nil || project.Locations != nil {
+		existing, err := c.repo.GetByID(ctx.Request().Context(), id, userID)
+		if err != nil {
+			return notFound(err, "Project not found")
+		}
+		site := *existing
+		if project.Latitude != 0 {
+			site.Latitude = project.Latitude
+		}
+		if project.Longitude != 0 {
+			site.Longitude = project.Longitude
+		}
+		if project.Boundaries != nil {
+			site.Boundaries = project.Boundaries
+		}
+		if project.Locations != nil {
+			site.Locations = project.Locations
+		}
+		// Only the site is checked; the other fields keep their partial update semantics
+		if err := c.validate.StructExcept(site, "Name", "Description", "Status", "StartDate", "UserID"); err != nil {
+			return err
+		}
 	}
 
+	if err := c.repo.Update(ctx.Request().Context(), &project, userID); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return c.conflict(ctx, id, userID, err)
+		}
+		return notFound(err, "Project not found")
+	}
+
+	setETag(ctx, project.Version)
 	return ctx.JSON(http.StatusOK, project)
 }
 
+// PatchProject handles PATCH /api/projects/:id with a JSON merge patch. The
+// worker assignments have their own endpoints and cannot be patched.
+func (c *ProjectController) PatchProject(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := pathID(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	current, err := c.repo.GetByID(ctx.Request().Context(), id, userID)
+	if err != nil {
+		return notFound(err, "Project not found")
+	}
+
+	project, changed, err := mergePatch(ctx, current, current.Version, projectPatchFields, "project")
+	if err != nil {
+		return err
+	}
+	if err := c.validate.Struct(project); err != nil {
+		return err
+	}
+
+	ctx.Set(middleware.ChangedFieldsKey, changed)
+	if len(changed) > 0 {
+		project.Version = current.Version
+		if err := c.repo.Patch(ctx.Request().Context(), project, changed, userID); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return c.conflict(ctx, id, userID, err)
+			}
+			return notFound(err, "Project not found")
+		}
+	}
+
+	setETag(ctx, project.Version)
+	return ctx.JSON(http.StatusOK, project)
+}
+
+// conflict reports an update based on an outdated version of a project,
+// together with the current project
+func (c *ProjectController) conflict(ctx echo.Context, id, userID uint, cause error) error {
+	current, err := c.repo.GetByID(ctx.Request().Context(), id, userID)
+	if err != nil {
+		return notFound(err, "Project not found")
+	}
+	setETag(ctx, current.Version)
+	return apierror.Conflict("The project was modified by another request").WithCurrent(current).Wrap(cause)
+}
+
+// BulkProjects handles POST /api/projects/bulk. Like POST /api/projects, a
+// created project may come with the IDs of workers to assign right away.
+func (c *ProjectController) BulkProjects(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return runBulk(ctx, c.uow, model.EntityTypeProject, func(reqCtx context.Context, repos *repository.Repositories, op bulkOperation) (uint, interface{}, error) {
+		switch op.Op {
+		case bulkCreate:
+			var data struct {
+				model.Project
+				WorkerIDs []uint `json:"worker_ids"`
+			}
+			if err := decodeBulkData(op.Data, &data); err != nil {
+				return 0, nil, err
+			}
+			project := data.Project
+			project.ID, project.UserID, project.Workers = 0, userID, nil
+			if err := c.validate.Struct(project); err != nil {
+				return 0, nil, err
+			}
+			if err := repos.Projects.Create(reqCtx, &project); err != nil {
+				return 0, nil, err
+			}
+			for _, workerID := range data.WorkerIDs {
+				if err := repos.Workers.AddToProject(reqCtx, workerID, project.ID, userID); err != nil {
+					return 0, nil, notFound(err, fmt.Sprintf("Worker %d not found", workerID))
+				}
+			}
+			if len(data.WorkerIDs) > 0 {
+				created, err := repos.Projects.GetByID(reqCtx, project.ID, userID)
+				if err != nil {
+					return 0, nil, err
+				}
+				return project.ID, created, nil
+			}
+			return project.ID, project, nil
+
+		case bulkUpdate:
+			current, err := repos.Projects.GetByID(reqCtx, op.ID, userID)
+			if err != nil {
+				return 0, nil, notFound(err, "Project not found")
+			}
+			project, changed, err := applyMergePatch(op.Data, 0, false, current, current.Version, projectPatchFields, "project")
+			if err != nil {
+				return 0, nil, err
+			}
+			if err := c.validate.Struct(project); err != nil {
+				return 0, nil, err
+			}
+			if len(changed) > 0 {
+				project.Version = current.Version
+				if err := repos.Projects.Patch(reqCtx, project, changed, userID); err != nil {
+					return 0, nil, notFound(err, "Project not found")
+				}
+			}
+			return project.ID, project, nil
+
+		default:
+			// Unlike DELETE /api/projects/:id, a batch reports missing projects
+			if _, err := repos.Projects.GetByID(reqCtx, op.ID, userID); err != nil {
+				return 0, nil, notFound(err, "Project not found")
+			}
+			return op.ID, nil, repos.Projects.Delete(reqCtx, op.ID, userID)
+		}
+	})
+}
+
 // DeleteProject handles DELETE /api/projects/:id
 func (c *ProjectController) DeleteProject(ctx echo.Context) error {
 	// Get user ID from context
@@ -161,19 +471,22 @@ func (c *ProjectController) DeleteProject(ctx echo.Context) error {
 		return err
 	}
 
-	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	id, err := pathID(ctx, "id")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
+		return err
 	}
 
-	if err := c.repo.Delete(uint(id), userID); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := c.repo.Delete(ctx.Request().Context(), id, userID); err != nil {
+		return err
 	}
 
 	return ctx.NoContent(http.StatusNoContent)
 }
 
-// AssignWorkerToProject handles POST /api/projects/:id/workers
+// AssignWorkerToProject handles POST /api/projects/:id/workers. Besides
+// workerId, the body may hold the details of the assignment; by default the
+// worker is assigned to the whole project at full allocation. Assignments
+// conflicting with the worker's schedule are refused unless override=true.
 func (c *ProjectController) AssignWorkerToProject(ctx echo.Context) error {
 	// Get user ID from context
 	userID, err := getUserID(ctx)
@@ -181,92 +494,173 @@ func (c *ProjectController) AssignWorkerToProject(ctx echo.Context) error {
 		return err
 	}
 
-	projectId, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	projectId, err := pathID(ctx, "id")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+		return err
 	}
 
 	var request struct {
 		WorkerId uint `json:"workerId"`
+		model.AssignmentDetails
 	}
+	request.AllocationPercent = model.FullAllocation
 	if err := ctx.Bind(&request); err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return err
+	}
+	if err := c.validate.Struct(request.AssignmentDetails); err != nil {
+		return err
 	}
 
-	if err := c.repo.AddWorker(uint(projectId), request.WorkerId, userID); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	override, err := parseOverride(ctx)
+	if err != nil {
+		return err
+	}
+
+	assignment := model.WorkerProject{
+		WorkerID:          request.WorkerId,
+		ProjectID:         projectId,
+		UserID:            userID,
+		AssignmentDetails: request.AssignmentDetails,
+	}
+	reqCtx := ctx.Request().Context()
+	err = c.uow.Do(reqCtx, func(repos *repository.Repositories) error {
+		if err := repos.Projects.AddWorker(reqCtx, &assignment); err != nil {
+			return err
+		}
+		if override {
+			return nil
+		}
+		return checkConflicts(reqCtx, repos.Projects, assignment)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return apierror.Conflict("Worker is already assigned to the project for some of these days").Wrap(err)
+		}
+		return notFound(err, "Worker or project not found")
 	}
 
-	project, err := c.repo.GetByID(uint(projectId), userID)
+	project, err := c.repo.GetByID(ctx.Request().Context(), projectId, userID)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return err
 	}
 
 	return ctx.JSON(http.StatusOK, project)
 }
 
-// GetAvailableWorkers handles GET /api/projects/:id/workers/available
-func (c *ProjectController) GetAvailableWorkers(ctx echo.Context) error {
+// UpdateWorkerAssignment handles PUT /api/projects/:id/workers/:workerId,
+// replacing the details of an assignment. Details left out are cleared, and
+// the allocation goes back to 100%. As when assigning, conflicts are refused
+// unless override=true.
+func (c *ProjectController) UpdateWorkerAssignment(ctx echo.Context) error {
 	// Get user ID from context
 	userID, err := getUserID(ctx)
 	if err != nil {
 		return err
 	}
 
-	projectId, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	projectId, err := pathID(ctx, "id")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+		return err
 	}
 
-	project, err := c.repo.GetByID(uint(projectId), userID)
+	workerId, err := pathID(ctx, "workerId")
 	if err != nil {
-		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "Project not found"})
+		return err
 	}
 
-	// Get pagination parameters
-	page := 1
-	pageSize := 10
+	details := model.AssignmentDetails{AllocationPercent: model.FullAllocation}
+	if err := ctx.Bind(&details); err != nil {
+		return err
+	}
+	if err := c.validate.Struct(details); err != nil {
+		return err
+	}
 
-	if pageParam := ctx.QueryParam("page"); pageParam != "" {
-		if parsedPage, err := strconv.Atoi(pageParam); err == nil && parsedPage > 0 {
-			page = parsedPage
-		}
+	override, err := parseOverride(ctx)
+	if err != nil {
+		return err
 	}
 
-	if pageSizeParam := ctx.QueryParam("page_size"); pageSizeParam != "" {
-		if parsedPageSize, err := strconv.Atoi(pageSizeParam); err == nil && parsedPageSize > 0 {
-			pageSize = parsedPageSize
+	assignment := model.WorkerProject{
+		WorkerID:          workerId,
+		ProjectID:         projectId,
+		UserID:            userID,
+		AssignmentDetails: details,
+	}
+	reqCtx := ctx.Request().Context()
+	err = c.uow.Do(reqCtx, func(repos *repository.Repositories) error {
+		if err := repos.Projects.UpdateAssignment(reqCtx, &assignment); err != nil {
+			return err
 		}
+		if override {
+			return nil
+		}
+		return checkConflicts(reqCtx, repos.Projects, assignment)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return apierror.Conflict("The assignment would share days with an earlier assignment of the worker to the project").Wrap(err)
+		}
+		return notFound(err, "Assignment not found")
 	}
 
-	// Get all workers with pagination
-	workers, total, err := c.repo.GetAllWorkers(userID, page, pageSize)
+	return ctx.JSON(http.StatusOK, assignment)
+}
+
+// GetAvailableWorkers handles GET /api/projects/:id/workers/available. It
+// takes the list parameters of GET /api/workers; with exclude_busy=true the
+// workers on another active project overlapping this one are left out too.
+func (c *ProjectController) GetAvailableWorkers(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return err
 	}
 
-	// Filter out workers that are already assigned to the project
-	availableWorkers := make([]model.Worker, 0)
-	for _, worker := range workers {
-		isAssigned := false
-		for _, assignedWorker := range project.Workers {
-			if worker.ID == assignedWorker.ID {
-				isAssigned = true
-				break
-			}
-		}
-		if !isAssigned {
-			availableWorkers = append(availableWorkers, worker)
+	projectId, err := pathID(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	excludeBusy := false
+	if raw := ctx.QueryParam("exclude_busy"); raw != "" {
+		if excludeBusy, err = strconv.ParseBool(raw); err != nil {
+			return apierror.InvalidParameter("exclude_busy", "must be true or false")
 		}
 	}
 
+	// Get query parameters for filtering and sorting, validated against the worker whitelist
+	spec, err := parseWorkerQuery(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Get pagination parameters
+	params, err := pagination.Parse(ctx, pagination.DefaultPageSize)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := parseCursor(spec, params)
+	if err != nil {
+		return err
+	}
+
+	opts := repository.ListOptions{
+		Search:   ctx.QueryParam("search"),
+		Query:    spec,
+		Cursor:   cursor,
+		Page:     params.Page,
+		PageSize: params.PageSize,
+	}
+	available := repository.AvailableWorkers{ProjectID: projectId, ExcludeBusy: excludeBusy}
+	workers, info, err := c.repo.GetAvailableWorkers(ctx.Request().Context(), userID, available, opts)
+	if err != nil {
+		return notFound(err, "Project not found")
+	}
+
 	// Return paginated response
-	return ctx.JSON(http.StatusOK, map[string]interface{}{
-		"data":     availableWorkers,
-		"total":    total,
-		"page":     page,
-		"pageSize": pageSize,
-	})
+	return pagination.Respond(ctx, "data", workers, params, info)
 }
 
 // UnassignWorkerFromProject handles DELETE /api/projects/:id/workers/:workerId
@@ -277,18 +671,18 @@ func (c *ProjectController) UnassignWorkerFromProject(ctx echo.Context) error {
 		return err
 	}
 
-	projectId, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	projectId, err := pathID(ctx, "id")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+		return err
 	}
 
-	workerId, err := strconv.ParseUint(ctx.Param("workerId"), 10, 32)
+	workerId, err := pathID(ctx, "workerId")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid worker ID"})
+		return err
 	}
 
-	if err := c.repo.RemoveWorker(uint(projectId), uint(workerId), userID); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := c.repo.RemoveWorker(ctx.Request().Context(), projectId, workerId, userID); err != nil {
+		return notFound(err, "Worker or project not found")
 	}
 
 	return ctx.NoContent(http.StatusNoContent)
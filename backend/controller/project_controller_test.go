@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestProjectsAreIsolatedPerUser(t *testing.T) {
+	e := newTestServer()
+	const owner, other = 1, 2
+
+	rec := do(t, e, owner, http.MethodPost, "/api/projects", projectBody("North"))
+	expectStatus(t, rec, http.StatusCreated)
+	var project struct {
+		ID uint `json:"id"`
+	}
+	decode(t, rec, &project)
+
+	expectStatus(t, do(t, e, other, http.MethodGet, "/api/projects/"+strconv.FormatUint(uint64(project.ID), 10), ""),
+		http.StatusNotFound)
+	rec = do(t, e, other, http.MethodGet, "/api/projects", "")
+	expectStatus(t, rec, http.StatusOK)
+	if n := total(t, rec); n != 0 {
+		t.Fatalf("expected the other user to list no projects, got %d", n)
+	}
+}
+
+func TestCreateProjectRollsBackWhenAWorkerIsMissing(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+	workerID := createWorker(t, e, owner, "Ana Pop")
+
+	rec := do(t, e, owner, http.MethodPost, "/api/projects", projectBody("North", workerID, 999))
+	expectStatus(t, rec, http.StatusNotFound)
+
+	rec = do(t, e, owner, http.MethodGet, "/api/projects", "")
+	expectStatus(t, rec, http.StatusOK)
+	if n := total(t, rec); n != 0 {
+		t.Fatalf("expected the failed project to be rolled back, got %d projects", n)
+	}
+
+	rec = do(t, e, owner, http.MethodGet, "/api/workers/"+strconv.FormatUint(uint64(workerID), 10), "")
+	expectStatus(t, rec, http.StatusOK)
+	var worker struct {
+		Projects []struct{} `json:"projects"`
+	}
+	decode(t, rec, &worker)
+	if len(worker.Projects) != 0 {
+		t.Fatalf("expected the assignment to be rolled back, got %d projects", len(worker.Projects))
+	}
+}
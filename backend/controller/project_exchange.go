@@ -0,0 +1,371 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/geo"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Content types of the exchange formats
+const (
+	mimeGeoJSON = "application/geo+json"
+	mimeKML     = "application/vnd.google-earth.kml+xml"
+)
+
+// maxImportFeatures bounds the number of features of one import
+const maxImportFeatures = 1000
+
+// siteProperties are the properties of an exported project feature
+type siteProperties struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date"`
+	WorkerCount int        `json:"worker_count"`
+}
+
+// siteImport are the properties read from an imported feature. Missing
+// properties keep the current values of an updated project.
+type siteImport struct {
+	ID          *uint      `json:"id"`
+	Name        *string    `json:"name"`
+	Description *string    `json:"description"`
+	Status      *string    `json:"status"`
+	StartDate   *time.Time `json:"start_date"`
+	EndDate     *time.Time `json:"end_date"`
+}
+
+// siteImportResult describes what an import does with one feature
+type siteImportResult struct {
+	Index int `json:"index"`
+	// Action is "create", "update" or "invalid"
+	Action string                `json:"action"`
+	ID     uint                  `json:"id,omitempty"`
+	Name   string                `json:"name,omitempty"`
+	Errors []apierror.FieldError `json:"errors,omitempty"`
+}
+
+// siteImportReport summarizes an import
+type siteImportReport struct {
+	DryRun  bool               `json:"dry_run"`
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Invalid int                `json:"invalid"`
+	Results []siteImportResult `json:"results"`
+}
+
+// ExportGeoJSON handles GET /api/projects/export.geojson
+func (c *ProjectController) ExportGeoJSON(ctx echo.Context) error {
+	projects, err := c.exportedProjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	features := make([]geo.Feature[siteProperties], len(projects))
+	for i, project := range projects {
+		features[i] = geo.Feature[siteProperties]{
+			Type:       geo.TypeFeature,
+			ID:         project.ID,
+			Geometry:   geo.PointGeometry(geo.Point{Lat: project.Latitude, Lng: project.Longitude}),
+			Properties: exportedProperties(project),
+		}
+	}
+
+	data, err := json.Marshal(geo.NewFeatureCollection(features))
+	if err != nil {
+		return err
+	}
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="projects.geojson"`)
+	return ctx.Blob(http.StatusOK, mimeGeoJSON, data)
+}
+
+// ExportKML handles GET /api/projects/export.kml
+func (c *ProjectController) ExportKML(ctx echo.Context) error {
+	projects, err := c.exportedProjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	placemarks := make([]geo.Placemark, len(projects))
+	for i, project := range projects {
+		properties := exportedProperties(project)
+		data := []geo.KMLData{
+			{Name: "id", Value: strconv.FormatUint(uint64(project.ID), 10)},
+			{Name: "status", Value: properties.Status},
+			{Name: "start_date", Value: properties.StartDate.Format(time.RFC3339)},
+		}
+		if properties.EndDate != nil {
+			data = append(data, geo.KMLData{Name: "end_date", Value: properties.EndDate.Format(time.RFC3339)})
+		}
+		data = append(data, geo.KMLData{Name: "worker_count", Value: strconv.Itoa(properties.WorkerCount)})
+
+		placemarks[i] = geo.Placemark{
+			ID:          fmt.Sprintf("project-%d", project.ID),
+			Name:        project.Name,
+			Description: project.Description,
+			Data:        data,
+			Point:       geo.NewKMLPoint(geo.Point{Lat: project.Latitude, Lng: project.Longitude}),
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := geo.WriteKML(&buf, "Projects", placemarks); err != nil {
+		return err
+	}
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="projects.kml"`)
+	return ctx.Blob(http.StatusOK, mimeKML, buf.Bytes())
+}
+
+// exportedProjects returns every project matching the list parameters of
+// the request (filter, sort, search, status, near, bbox), without paging
+func (c *ProjectController) exportedProjects(ctx echo.Context) ([]model.Project, error) {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := parseProjectQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	area, err := parseGeoQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := repository.ListOptions{
+		Search: ctx.QueryParam("search"),
+		Query:  spec,
+	}
+	if area == nil {
+		projects, _, err := c.repo.GetAll(ctx.Request().Context(), userID, opts)
+		return projects, err
+	}
+
+	nearby, _, err := c.repo.GetNearby(ctx.Request().Context(), userID, *area, opts)
+	if err != nil {
+		return nil, err
+	}
+	projects := make([]model.Project, len(nearby))
+	for i, project := range nearby {
+		projects[i] = project.Project
+	}
+	return projects, nil
+}
+
+// exportedProperties returns the feature properties of a project
+func exportedProperties(project model.Project) siteProperties {
+	return siteProperties{
+		ID:          project.ID,
+		Name:        project.Name,
+		Description: project.Description,
+		Status:      project.Status,
+		StartDate:   project.StartDate,
+		EndDate:     project.EndDate,
+		WorkerCount: len(project.Workers),
+	}
+}
+
+// ImportGeoJSON handles POST /api/projects/import. Each Point feature of the
+// FeatureCollection creates a project, or updates the project named by its
+// "id" property (or feature id). The import is all or nothing: with any
+// invalid feature nothing is written. With dry_run=true only the report is
+// returned.
+func (c *ProjectController) ImportGeoJSON(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	dryRun := false
+	if raw := ctx.QueryParam("dry_run"); raw != "" {
+		dryRun, err = strconv.ParseBool(raw)
+		if err != nil {
+			return apierror.InvalidParameter("dry_run", "must be true or false")
+		}
+	}
+
+	// The body is GeoJSON, which Bind does not recognize as JSON
+	var collection geo.FeatureCollection[json.RawMessage]
+	body := http.MaxBytesReader(ctx.Response(), ctx.Request().Body, maxImportBytes)
+	if err := json.NewDecoder(body).Decode(&collection); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return apierror.New(http.StatusRequestEntityTooLarge, "request_entity_too_large",
+				fmt.Sprintf("The GeoJSON must not exceed %d MB", maxImportBytes>>20)).Wrap(err)
+		}
+		return apierror.BadRequest("Invalid GeoJSON").Wrap(err)
+	}
+	if collection.Type != geo.TypeFeatureCollection {
+		return apierror.BadRequest("Invalid GeoJSON").WithDetails(apierror.FieldError{Field: "type", Code: "oneof", Message: "must be FeatureCollection"})
+	}
+	if len(collection.Features) == 0 || len(collection.Features) > maxImportFeatures {
+		return apierror.BadRequest("Invalid GeoJSON").WithDetails(apierror.FieldError{Field: "features", Code: "len", Message: fmt.Sprintf("must hold between 1 and %d features", maxImportFeatures)})
+	}
+
+	// Plan the import, checking every feature
+	report := siteImportReport{DryRun: dryRun, Results: make([]siteImportResult, len(collection.Features))}
+	planned := make([]*model.Project, len(collection.Features))
+	var details []apierror.FieldError
+	for i, feature := range collection.Features {
+		project, action, fieldErrs, err := c.planSite(ctx, userID, feature)
+		if err != nil {
+			return err
+		}
+
+		result := siteImportResult{Index: i, Action: action, Errors: fieldErrs}
+		if project != nil {
+			result.ID = project.ID
+			result.Name = project.Name
+		}
+		switch action {
+		case "create":
+			report.Created++
+		case "update":
+			report.Updated++
+		default:
+			report.Invalid++
+			for _, fieldErr := range fieldErrs {
+				fieldErr.Field = fmt.Sprintf("features[%d].%s", i, fieldErr.Field)
+				details = append(details, fieldErr)
+			}
+		}
+		report.Results[i] = result
+		planned[i] = project
+	}
+
+	if dryRun {
+		return ctx.JSON(http.StatusOK, report)
+	}
+	if report.Invalid > 0 {
+		return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed,
+			fmt.Sprintf("%d of %d features are invalid", report.Invalid, len(collection.Features))).WithDetails(details...)
+	}
+
+	// Write every project atomically
+	err = c.uow.Do(ctx.Request().Context(), func(repos *repository.Repositories) error {
+		for i, project := range planned {
+			if report.Results[i].Action == "create" {
+				if err := repos.Projects.Create(ctx.Request().Context(), project); err != nil {
+					return err
+				}
+				report.Results[i].ID = project.ID
+				continue
+			}
+			if err := repos.Projects.Update(ctx.Request().Context(), project, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return notFound(err, "Project not found")
+	}
+
+	return ctx.JSON(http.StatusOK, report)
+}
+
+// planSite turns an imported feature into the project to create or update.
+// Problems with the feature are returned as field errors with the "invalid"
+// action; the error is only set when the lookup of the project fails.
+func (c *ProjectController) planSite(ctx echo.Context, userID uint, feature geo.Feature[json.RawMessage]) (*model.Project, string, []apierror.FieldError, error) {
+	invalid := func(field, code, message string) (*model.Project, string, []apierror.FieldError, error) {
+		return nil, "invalid", []apierror.FieldError{{Field: field, Code: code, Message: message}}, nil
+	}
+
+	if feature.Type != geo.TypeFeature {
+		return invalid("type", "oneof", "must be Feature")
+	}
+	point, err := feature.Geometry.Point()
+	if err != nil {
+		return invalid("geometry", "invalid", err.Error())
+	}
+
+	var properties siteImport
+	if len(feature.Properties) > 0 && string(feature.Properties) != "null" {
+		if err := json.Unmarshal(feature.Properties, &properties); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				return invalid("properties."+typeErr.Field, "type", "must be of type "+typeErr.Type.String())
+			}
+			return invalid("properties", "type", "must be an object")
+		}
+	}
+
+	// Update the project named by the id property, or by a numeric feature id
+	id := properties.ID
+	if id == nil {
+		if number, ok := feature.ID.(float64); ok && number > 0 && number == float64(uint(number)) {
+			featureID := uint(number)
+			id = &featureID
+		}
+	}
+
+	project := &model.Project{UserID: userID}
+	action := "create"
+	if id != nil {
+		existing, err := c.repo.GetByID(ctx.Request().Context(), *id, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalid("properties.id", "not_found", "Project not found")
+		}
+		if err != nil {
+			return nil, "", nil, err
+		}
+		project = existing
+		// Keep the current assignments
+		project.Workers = nil
+		action = "update"
+	}
+
+	if properties.Name != nil {
+		project.Name = *properties.Name
+	}
+	if properties.Description != nil {
+		project.Description = *properties.Description
+	}
+	if properties.Status != nil {
+		project.Status = *properties.Status
+	}
+	if properties.StartDate != nil {
+		project.StartDate = *properties.StartDate
+	}
+	if properties.EndDate != nil {
+		project.EndDate = properties.EndDate
+	}
+	project.Latitude = point.Lat
+	project.Longitude = point.Lng
+
+	if err := c.validate.Struct(project); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return nil, "", nil, err
+		}
+		fieldErrs := apierror.Validation(validationErrs).Details
+		for i := range fieldErrs {
+			// The location comes from the geometry, everything else from the properties
+			if fieldErrs[i].Field == "latitude" || fieldErrs[i].Field == "longitude" {
+				fieldErrs[i].Field = "geometry"
+			} else {
+				fieldErrs[i].Field = "properties." + fieldErrs[i].Field
+			}
+		}
+		return project, "invalid", fieldErrs, nil
+	}
+	return project, action, nil, nil
+}
@@ -0,0 +1,417 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/geo"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/pdf"
+	"github.com/labstack/echo/v4"
+)
+
+// daysPerMonth is the average length of a month, to turn monthly salaries
+// into day rates
+const daysPerMonth = 30.4375
+
+// ProjectReport handles GET /api/projects/:id/report.pdf, a printable report
+// of a project: its details, its site and the labor cost of its workers.
+// With download=true the report is sent as an attachment.
+func (c *ProjectController) ProjectReport(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := pathID(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	project, err := c.repo.GetWithHistory(ctx.Request().Context(), id, userID)
+	if err != nil {
+		return notFound(err, "Project not found")
+	}
+
+	var buf bytes.Buffer
+	if _, err := writeProjectReport(project, time.Now()).WriteTo(&buf); err != nil {
+		return err
+	}
+
+	disposition := "inline"
+	if ctx.QueryParam("download") == "true" {
+		disposition = "attachment"
+	}
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`%s; filename="project-%d.pdf"`, disposition, project.ID))
+	return ctx.Blob(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// writeProjectReport lays out the report of a project generated at now
+func writeProjectReport(project *model.Project, now time.Time) *pdf.Document {
+	doc := pdf.New(project.Name)
+	r := newReportLayout(doc)
+
+	r.title(project.Name)
+	r.note(fmt.Sprintf("Project report #%d, generated on %s", project.ID, now.UTC().Format("2006-01-02 15:04 UTC")))
+
+	r.heading("Details")
+	end, until := "open", now
+	if project.EndDate != nil {
+		end, until = project.EndDate.Format(time.DateOnly), *project.EndDate
+	}
+	days := projectDays(project.StartDate, until)
+	r.field("Status", strings.ReplaceAll(project.Status, "_", " "))
+	r.field("Start date", project.StartDate.Format(time.DateOnly))
+	r.field("End date", end)
+	r.field("Duration", fmt.Sprintf("%d days (%s months)", days, formatDecimal(float64(days)/daysPerMonth, 2)))
+	r.field("Description", project.Description)
+
+	r.heading("Site")
+	r.field("Main location", formatPoint(project.Point()))
+	if len(project.Locations) > 0 {
+		rows := make([][]string, len(project.Locations))
+		for i, location := range project.Locations {
+			rows[i] = []string{location.Name, location.Kind, formatPoint(location.Point()),
+				formatDecimal(geo.DistanceKm(project.Point(), location.Point()), 2)}
+		}
+		r.table([]reportColumn{
+			{title: "Location", width: 170},
+			{title: "Kind", width: 70},
+			{title: "Coordinates", width: 165},
+			{title: "Distance (km)", width: 90, right: true},
+		}, rows, nil)
+	}
+	if len(project.Boundaries) > 0 {
+		rows := make([][]string, len(project.Boundaries))
+		for i, boundary := range project.Boundaries {
+			name := boundary.Name
+			if name == "" {
+				name = fmt.Sprintf("Boundary %d", i+1)
+			}
+			// The ring is closed, so its first point is counted once
+			rows[i] = []string{name, strconv.Itoa(max(len(boundary.Points)-1, 0)),
+				formatDecimal(geo.RingAreaKm2(boundary.Points), 4)}
+		}
+		r.table([]reportColumn{
+			{title: "Boundary", width: 305},
+			{title: "Points", width: 90, right: true},
+			{title: "Area (km²)", width: 100, right: true},
+		}, rows, nil)
+	}
+
+	r.heading("Workers and labor cost")
+	if len(project.Workers) == 0 {
+		r.paragraph("No workers are assigned to this project.")
+	} else {
+		rows := make([][]string, len(project.Workers))
+		total := 0.0
+		for i, worker := range project.Workers {
+			cost := laborCost(project, worker, until)
+			total += cost.amount
+			role := worker.Position
+			if cost.assignment.Role != "" {
+				role = cost.assignment.Role
+			}
+			rows[i] = []string{worker.Name, role, fmt.Sprintf("%d%%", cost.assignment.AllocationPercent),
+				strconv.Itoa(cost.days), formatMoney(cost.dayRate), formatMoney(cost.amount)}
+		}
+		r.table([]reportColumn{
+			{title: "Worker", width: 120},
+			{title: "Role", width: 95},
+			{title: "Allocation", width: 55, right: true},
+			{title: "Days", width: 45, right: true},
+			{title: "Day rate (RON)", width: 80, right: true},
+			{title: "Labor cost (RON)", width: 100, right: true},
+		}, rows, []string{fmt.Sprintf("Total, %d workers", len(project.Workers)), "", "", "", "", formatMoney(total)})
+		r.note("Labor cost is the days of each assignment within the project, up to its end date or, while it is open, the date of the report, " +
+			"times the day rate and the allocation. Without a day rate of its own, an assignment is paid the monthly salary divided by 30.44 days.")
+	}
+
+	r.finish()
+	return doc
+}
+
+// projectDays returns the number of days from start to end, counting both,
+// or 0 when the project has not started by then
+func projectDays(start, end time.Time) int {
+	days := int(math.Floor(end.Sub(start).Hours()/24)) + 1
+	return max(days, 0)
+}
+
+// workerCost is the labor cost of a worker on a project
+type workerCost struct {
+	assignment model.WorkerProject
+	days       int
+	dayRate    float64
+	amount     float64
+}
+
+// laborCost returns the cost of a worker over the days of their assignment
+// that fall within the project, which runs until the given date
+func laborCost(project *model.Project, worker model.Worker, until time.Time) workerCost {
+	cost := workerCost{assignment: model.NewAssignment(worker.ID, project.ID, project.UserID)}
+	if worker.Assignment != nil {
+		cost.assignment = *worker.Assignment
+	}
+
+	start, end := cost.assignment.Period(*project)
+	if start.Before(project.StartDate) {
+		start = project.StartDate
+	}
+	if end == nil || end.After(until) {
+		end = &until
+	}
+	cost.days = projectDays(start, *end)
+
+	cost.dayRate = float64(worker.Salary) / daysPerMonth
+	if cost.assignment.DayRate != nil {
+		cost.dayRate = float64(*cost.assignment.DayRate)
+	}
+	cost.amount = float64(cost.days) * cost.dayRate * float64(cost.assignment.AllocationPercent) / 100
+	return cost
+}
+
+// formatPoint formats coordinates as latitude, longitude
+func formatPoint(p geo.Point) string {
+	return fmt.Sprintf("%.6f, %.6f", p.Lat, p.Lng)
+}
+
+// formatDecimal formats a number with a fixed number of decimals
+func formatDecimal(v float64, decimals int) string {
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// formatMoney formats an amount with two decimals and thousands separators
+func formatMoney(amount float64) string {
+	text := formatDecimal(math.Abs(amount), 2)
+	whole, cents := text[:len(text)-3], text[len(text)-3:]
+	var out strings.Builder
+	if amount < 0 && text != "0.00" {
+		out.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			out.WriteByte(',')
+		}
+		out.WriteRune(digit)
+	}
+	out.WriteString(cents)
+	return out.String()
+}
+
+// Page geometry and type sizes of reports
+const (
+	reportMargin     = 50.0
+	reportWidth      = pdf.A4Width - 2*reportMargin
+	reportLabelWidth = 110.0
+	reportTextSize   = 10.0
+	reportLineHeight = 14.0
+)
+
+// reportColumn is a column of a report table
+type reportColumn struct {
+	title string
+	width float64
+	// right aligns the column to the right, for numbers
+	right bool
+}
+
+// reportLayout writes the blocks of a report top to bottom, starting a new
+// page when a block does not fit on the current one
+type reportLayout struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	// y is the top of the next block
+	y float64
+}
+
+func newReportLayout(doc *pdf.Document) *reportLayout {
+	r := &reportLayout{doc: doc}
+	r.newPage()
+	return r
+}
+
+func (r *reportLayout) newPage() {
+	r.page = r.doc.AddPage()
+	r.y = pdf.A4Height - reportMargin
+}
+
+// reserve starts a new page unless height fits above the bottom margin
+func (r *reportLayout) reserve(height float64) {
+	if r.y-height < reportMargin {
+		r.newPage()
+	}
+}
+
+func (r *reportLayout) title(text string) {
+	for _, line := range wrapText(text, pdf.HelveticaBold, 18, reportWidth) {
+		r.reserve(24)
+		r.y -= 24
+		r.page.Text(reportMargin, r.y+6, pdf.HelveticaBold, 18, line)
+	}
+}
+
+func (r *reportLayout) heading(text string) {
+	// Keep a heading on the same page as the first lines under it
+	r.reserve(28 + 3*reportLineHeight)
+	r.y -= 28
+	r.page.Text(reportMargin, r.y+4, pdf.HelveticaBold, 13, text)
+	r.page.Line(reportMargin, r.y, reportMargin+reportWidth, r.y, 0.5)
+	r.y -= 6
+}
+
+// field writes a label and its value, wrapped in the column beside it
+func (r *reportLayout) field(label, value string) {
+	for i, line := range wrapText(value, pdf.Helvetica, reportTextSize, reportWidth-reportLabelWidth) {
+		r.reserve(reportLineHeight)
+		r.y -= reportLineHeight
+		if i == 0 {
+			r.page.Text(reportMargin, r.y+3, pdf.HelveticaBold, reportTextSize, label)
+		}
+		r.page.Text(reportMargin+reportLabelWidth, r.y+3, pdf.Helvetica, reportTextSize, line)
+	}
+}
+
+func (r *reportLayout) paragraph(text string) {
+	r.y -= 4
+	for _, line := range wrapText(text, pdf.Helvetica, reportTextSize, reportWidth) {
+		r.reserve(reportLineHeight)
+		r.y -= reportLineHeight
+		r.page.Text(reportMargin, r.y+3, pdf.Helvetica, reportTextSize, line)
+	}
+}
+
+// note writes small gray text
+func (r *reportLayout) note(text string) {
+	r.y -= 4
+	for _, line := range wrapText(text, pdf.Helvetica, 8, reportWidth) {
+		r.reserve(11)
+		r.y -= 11
+		r.page.SetGray(0.4)
+		r.page.Text(reportMargin, r.y+2, pdf.Helvetica, 8, line)
+		r.page.SetGray(0)
+	}
+}
+
+// table writes rows under a header that is repeated on every page the table
+// spans, then the total row when there is one. Cells too wide for their
+// column are shortened.
+func (r *reportLayout) table(columns []reportColumn, rows [][]string, total []string) {
+	const rowHeight = 16.0
+	header := func() {
+		r.y -= rowHeight
+		r.page.FillRect(reportMargin, r.y, reportWidth, rowHeight, 0.9)
+		r.row(columns, nil, pdf.HelveticaBold)
+	}
+
+	r.y -= 8
+	r.reserve(2 * rowHeight)
+	header()
+	for _, row := range rows {
+		if r.y-rowHeight < reportMargin {
+			r.newPage()
+			header()
+		}
+		r.y -= rowHeight
+		r.row(columns, row, pdf.Helvetica)
+		r.page.Line(reportMargin, r.y, reportMargin+reportWidth, r.y, 0.25)
+	}
+	if total != nil {
+		r.reserve(rowHeight)
+		r.y -= rowHeight
+		r.row(columns, total, pdf.HelveticaBold)
+	}
+}
+
+// row writes the cells of a table row, or the column titles when cells is nil
+func (r *reportLayout) row(columns []reportColumn, cells []string, font pdf.Font) {
+	const size, padding = 9.0, 4.0
+	x := reportMargin
+	for i, column := range columns {
+		text := column.title
+		if cells != nil {
+			text = cells[i]
+		}
+		if text == "" {
+			x += column.width
+			continue
+		}
+		text = truncateText(text, font, size, column.width-2*padding)
+		left := x + padding
+		if column.right {
+			left = x + column.width - padding - pdf.TextWidth(text, font, size)
+		}
+		r.page.Text(left, r.y+5, font, size, text)
+		x += column.width
+	}
+}
+
+// finish numbers the pages
+func (r *reportLayout) finish() {
+	pages := r.doc.Pages()
+	for i, page := range pages {
+		text := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		page.SetGray(0.4)
+		page.Text(pdf.A4Width-reportMargin-pdf.TextWidth(text, pdf.Helvetica, 8), reportMargin/2, pdf.Helvetica, 8, text)
+		page.SetGray(0)
+	}
+}
+
+// wrapText splits text in lines no wider than width, breaking between words
+// and, for words longer than a line, inside them
+func wrapText(text string, font pdf.Font, size, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if pdf.TextWidth(candidate, font, size) <= width {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			for pdf.TextWidth(word, font, size) > width {
+				cut := fitRunes(word, font, size, width)
+				lines = append(lines, word[:cut])
+				word = word[cut:]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// truncateText shortens text with an ellipsis to fit in width
+func truncateText(text string, font pdf.Font, size, width float64) string {
+	if pdf.TextWidth(text, font, size) <= width {
+		return text
+	}
+	return text[:fitRunes(text, font, size, width-pdf.TextWidth("…", font, size))] + "…"
+}
+
+// fitRunes returns the length in bytes of the longest prefix of text that
+// fits in width, keeping at least one character so wrapping always advances
+func fitRunes(text string, font pdf.Font, size, width float64) int {
+	fit := 0
+	for i, r := range text {
+		next := i + utf8.RuneLen(r)
+		if fit > 0 && pdf.TextWidth(text[:next], font, size) > width {
+			break
+		}
+		fit = next
+	}
+	return fit
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/pagination"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultSearchLimit is the number of hits returned when no limit is given
+const defaultSearchLimit = 20
+
+type SearchController struct {
+	repo repository.SearchRepository
+}
+
+func NewSearchController(repo repository.SearchRepository) *SearchController {
+	return &SearchController{
+		repo: repo,
+	}
+}
+
+// entityTypes maps the values of "type" parameters to entity types
+var entityTypes = map[string]model.EntityType{
+	"worker":  model.EntityTypeWorker,
+	"project": model.EntityTypeProject,
+}
+
+// parseEntityTypes reads a comma-separated "type" query parameter; no value
+// means all types
+func parseEntityTypes(ctx echo.Context) ([]model.EntityType, error) {
+	var types []model.EntityType
+	if raw := ctx.QueryParam("type"); raw != "" {
+		for _, name := range strings.Split(raw, ",") {
+			entityType, ok := entityTypes[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return nil, apierror.InvalidParameter("type", "must be worker or project")
+			}
+			types = append(types, entityType)
+		}
+	}
+	return types, nil
+}
+
+// Search handles GET /api/search?q=
+func (c *SearchController) Search(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	q := strings.TrimSpace(ctx.QueryParam("q"))
+	if q == "" {
+		return apierror.InvalidParameter("q", "is required")
+	}
+
+	limit := defaultSearchLimit
+	if raw := ctx.QueryParam("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > pagination.MaxPageSize {
+			return apierror.InvalidParameter("limit", "must be an integer between 1 and 100")
+		}
+	}
+
+	// Optionally restrict the search to some entity types, e.g. type=worker
+	types, err := parseEntityTypes(ctx)
+	if err != nil {
+		return err
+	}
+
+	hits, err := c.repo.Search(ctx.Request().Context(), userID, q, types, limit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"query": q,
+		"data":  hits,
+	})
+}
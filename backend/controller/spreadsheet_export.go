@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/spreadsheet"
+	"github.com/labstack/echo/v4"
+)
+
+// Spreadsheet exports take the list parameters of the matching GET endpoint,
+// without paging, and stream their rows to the response as they are read
+// from the database.
+
+// ExportWorkers handles GET /api/workers/export.csv and /api/workers/export.xlsx
+func (c *WorkerController) ExportWorkers(format spreadsheet.Format) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		// Get user ID from context
+		userID, err := getUserID(ctx)
+		if err != nil {
+			return err
+		}
+
+		spec, err := parseWorkerQuery(ctx)
+		if err != nil {
+			return err
+		}
+		opts := repository.ListOptions{Search: ctx.QueryParam("search"), Query: spec}
+
+		return streamSpreadsheet(ctx, format, "workers", func(w spreadsheet.Writer) error {
+			if err := w.Write("id", "name", "age", "position", "salary", "created_at", "updated_at"); err != nil {
+				return err
+			}
+			return c.repo.Each(ctx.Request().Context(), userID, opts, func(worker model.Worker) error {
+				return w.Write(worker.ID, worker.Name, worker.Age, worker.Position, worker.Salary, worker.CreatedAt, worker.UpdatedAt)
+			})
+		})
+	}
+}
+
+// ExportProjects handles GET /api/projects/export.csv and
+// /api/projects/export.xlsx. With near or bbox, only the projects in the area
+// are exported, with their distance when near is given.
+func (c *ProjectController) ExportProjects(format spreadsheet.Format) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		// Get user ID from context
+		userID, err := getUserID(ctx)
+		if err != nil {
+			return err
+		}
+
+		spec, err := parseProjectQuery(ctx)
+		if err != nil {
+			return err
+		}
+		area, err := parseGeoQuery(ctx)
+		if err != nil {
+			return err
+		}
+		opts := repository.ListOptions{Search: ctx.QueryParam("search"), Query: spec}
+		withDistance := area != nil && ctx.QueryParam("near") != ""
+
+		return streamSpreadsheet(ctx, format, "projects", func(w spreadsheet.Writer) error {
+			header := []interface{}{"id", "name", "description", "status", "start_date", "end_date",
+				"latitude", "longitude", "created_at", "updated_at"}
+			if withDistance {
+				header = append(header, "distance_km")
+			}
+			if err := w.Write(header...); err != nil {
+				return err
+			}
+
+			return c.repo.Each(ctx.Request().Context(), userID, opts, area, func(project repository.ProjectDistance) error {
+				row := []interface{}{project.ID, project.Name, project.Description, project.Status,
+					exportDate(&project.StartDate), exportDate(project.EndDate),
+					project.Latitude, project.Longitude, project.CreatedAt, project.UpdatedAt}
+				if withDistance {
+					row = append(row, roundKm(project.DistanceKm))
+				}
+				return w.Write(row...)
+			})
+		})
+	}
+}
+
+// ExportAssignments handles GET /api/projects/assignments/export.csv and
+// /api/projects/assignments/export.xlsx, exporting the worker assignments of
+// the projects matching the project list parameters
+func (c *ProjectController) ExportAssignments(format spreadsheet.Format) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		// Get user ID from context
+		userID, err := getUserID(ctx)
+		if err != nil {
+			return err
+		}
+
+		spec, err := parseProjectQuery(ctx)
+		if err != nil {
+			return err
+		}
+		opts := repository.ListOptions{Search: ctx.QueryParam("search"), Query: spec}
+
+		return streamSpreadsheet(ctx, format, "assignments", func(w spreadsheet.Writer) error {
+			if err := w.Write("project_id", "project_name", "project_status", "worker_id", "worker_name", "position", "salary",
+				"role", "start_date", "end_date", "allocation_percent", "day_rate", "notes"); err != nil {
+				return err
+			}
+			return c.repo.EachAssignment(ctx.Request().Context(), userID, opts, func(a repository.Assignment) error {
+				var dayRate interface{}
+				if a.DayRate != nil {
+					dayRate = *a.DayRate
+				}
+				return w.Write(a.ProjectID, a.ProjectName, a.ProjectStatus, a.WorkerID, a.WorkerName, a.Position, a.Salary,
+					a.Role, exportDate(a.StartDate), exportDate(a.EndDate), a.AllocationPercent, dayRate, a.Notes)
+			})
+		})
+	}
+}
+
+// streamSpreadsheet answers with a spreadsheet attachment written by write.
+// The response is committed before the rows are read, so a failure past
+// that point (e.g. the route timeout) is logged and the connection aborted:
+// the client sees an incomplete transfer rather than a short file ending
+// like a complete one.
+func streamSpreadsheet(ctx echo.Context, format spreadsheet.Format, name string, write func(spreadsheet.Writer) error) error {
+	res := ctx.Response()
+	res.Header().Set(echo.HeaderContentType, spreadsheet.ContentType(format))
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	res.WriteHeader(http.StatusOK)
+
+	w, err := spreadsheet.NewWriter(res, format, name)
+	if err == nil {
+		err = write(w)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		ctx.Logger().Errorf("export of %s failed: %v", name, err)
+		panic(http.ErrAbortHandler)
+	}
+	return nil
+}
+
+// exportDate formats a date column, blank when there is no date
+func exportDate(date *time.Time) interface{} {
+	if date == nil {
+		return nil
+	}
+	return date.Format(time.DateOnly)
+}
+
+// roundKm rounds a distance to the meter
+func roundKm(km float64) float64 {
+	return math.Round(km*1000) / 1000
+}
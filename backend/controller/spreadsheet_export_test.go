@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/pdf"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/spreadsheet"
+)
+
+func TestExportWorkers(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+	for _, body := range []string{
+		`{"name":"Ana Pop","age":30,"position":"Mason","salary":3500}`,
+		`{"name":"Ion Rus","age":40,"position":"Welder","salary":2500}`,
+		`{"name":"=cmd()","age":35,"position":"Mason","salary":4000}`,
+	} {
+		expectStatus(t, do(t, e, owner, http.MethodPost, "/api/workers", body), http.StatusCreated)
+	}
+	createWorker(t, e, 2, "Eva Lup")
+	params := url.Values{"filter": {"position=Mason"}, "sort": {"-salary"}}.Encode()
+
+	rec := do(t, e, owner, http.MethodGet, "/api/workers/export.csv?"+params, "")
+	expectStatus(t, rec, http.StatusOK)
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != `attachment; filename="workers.csv"` {
+		t.Fatalf("expected a workers.csv attachment, got %q", disposition)
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, record := range records[1:] {
+		names = append(names, record[1])
+	}
+	if !reflect.DeepEqual(names, []string{"'=cmd()", "Ana Pop"}) {
+		t.Fatalf("expected the masons of the user by salary, with the formula quoted, got %q", names)
+	}
+
+	rec = do(t, e, owner, http.MethodGet, "/api/workers/export.xlsx?"+params, "")
+	expectStatus(t, rec, http.StatusOK)
+	if contentType := rec.Header().Get("Content-Type"); contentType != spreadsheet.ContentType(spreadsheet.XLSX) {
+		t.Fatalf("expected an XLSX workbook, got %q", contentType)
+	}
+	data := rec.Body.Bytes()
+	reader, err := spreadsheet.NewReader(bytes.NewReader(data), int64(len(data)), spreadsheet.XLSX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []spreadsheet.Row
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) != 3 || rows[1].Cells[1] != "=cmd()" || rows[2].Cells[4] != "3500" {
+		t.Fatalf("expected the header and 2 workers, got %q", rows)
+	}
+
+	// Parameters are checked before the response starts
+	rec = do(t, e, owner, http.MethodGet, "/api/workers/export.csv?"+url.Values{"filter": {"user_id=2"}}.Encode(), "")
+	expectStatus(t, rec, http.StatusBadRequest)
+}
+
+func TestExportAssignments(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+	workerID := createWorker(t, e, owner, "Ana Pop")
+	expectStatus(t, do(t, e, owner, http.MethodPost, "/api/projects", projectBody("North", workerID)), http.StatusCreated)
+
+	rec := do(t, e, owner, http.MethodGet, "/api/projects/assignments/export.csv", "")
+	expectStatus(t, rec, http.StatusOK)
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1][1] != "North" || records[1][4] != "Ana Pop" || records[1][10] != "100" {
+		t.Fatalf("expected the assignment of Ana Pop to North at 100%%, got %q", records)
+	}
+}
+
+func TestProjectReport(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+	workerID := createWorker(t, e, owner, "Ana Pop")
+	rec := do(t, e, owner, http.MethodPost, "/api/projects", projectBody("North", workerID))
+	expectStatus(t, rec, http.StatusCreated)
+	var project struct {
+		ID uint `json:"id"`
+	}
+	decode(t, rec, &project)
+	path := "/api/projects/" + strconv.FormatUint(uint64(project.ID), 10) + "/report.pdf"
+
+	rec = do(t, e, owner, http.MethodGet, path+"?download=true", "")
+	expectStatus(t, rec, http.StatusOK)
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/pdf" {
+		t.Fatalf("expected a PDF, got %q", contentType)
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment;") {
+		t.Fatalf("expected an attachment, got %q", disposition)
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("expected a PDF document, got %q", rec.Body.String())
+	}
+
+	expectStatus(t, do(t, e, 2, http.MethodGet, path, ""), http.StatusNotFound)
+}
+
+func TestWrapText(t *testing.T) {
+	const size, width = 10, 100
+	lines := wrapText("The foundations of the north wing\n\nAntidisestablishmentarianism", pdf.Helvetica, size, width)
+	if len(lines) < 4 || lines[len(lines)-3] != "" {
+		t.Fatalf("expected the paragraphs to be kept apart, got %q", lines)
+	}
+	for _, line := range lines {
+		if pdf.TextWidth(line, pdf.Helvetica, size) > width {
+			t.Fatalf("expected every line to fit in %v points, got %q", float64(width), line)
+		}
+	}
+	if joined := strings.Join(lines[len(lines)-2:], ""); joined != "Antidisestablishmentarianism" {
+		t.Fatalf("expected the long word to be cut across lines, got %q", lines)
+	}
+	if text := truncateText("The foundations of the north wing", pdf.Helvetica, size, width); !strings.HasSuffix(text, "…") ||
+		pdf.TextWidth(text, pdf.Helvetica, size) > width {
+		t.Fatalf("expected the text shortened to fit, got %q", text)
+	}
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/middleware"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/pagination"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// timesheetReview is the body of the approve and reject actions
+type timesheetReview struct {
+	Comment string `json:"comment" validate:"max=500"`
+}
+
+// SubmitTimesheet handles POST /api/timesheets/:id/submit, sending a draft or
+// rejected entry of the user to their manager for approval
+func (c *TimesheetController) SubmitTimesheet(ctx echo.Context) error {
+	return c.transition(ctx, model.TimesheetSubmit)
+}
+
+// ApproveTimesheet handles POST /api/timesheets/:id/approve. The comment of
+// the body is optional.
+func (c *TimesheetController) ApproveTimesheet(ctx echo.Context) error {
+	return c.transition(ctx, model.TimesheetApprove)
+}
+
+// RejectTimesheet handles POST /api/timesheets/:id/reject, sending the entry
+// back to its user with a comment saying why
+func (c *TimesheetController) RejectTimesheet(ctx echo.Context) error {
+	return c.transition(ctx, model.TimesheetReject)
+}
+
+// transition takes an action on the timesheet entry of the path. Entries are
+// submitted by their own user and reviewed by the manager of that user, or by
+// an admin, but never by the user who submitted them.
+func (c *TimesheetController) transition(ctx echo.Context, action string) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := pathID(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	transition := model.TimesheetTransitions[action]
+	role, err := c.currentRole(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !transition.Allows(role) {
+		return apierror.Forbidden(fmt.Sprintf("The %s role cannot %s timesheet entries", role, action))
+	}
+
+	var review timesheetReview
+	if action != model.TimesheetSubmit {
+		if err := ctx.Bind(&review); err != nil {
+			return err
+		}
+		if err := c.validate.Struct(review); err != nil {
+			return err
+		}
+		if action == model.TimesheetReject && review.Comment == "" {
+			return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Validation failed").
+				WithDetails(apierror.FieldError{Field: "comment", Code: "required", Message: "is required"})
+		}
+	}
+
+	reqCtx := ctx.Request().Context()
+	var entry *model.Timesheet
+	if action == model.TimesheetSubmit {
+		entry, err = c.repo.GetByID(reqCtx, id, userID)
+	} else {
+		entry, err = c.repo.GetForReview(reqCtx, id, reviewScope(userID, role))
+	}
+	if err != nil {
+		return notFound(err, "Timesheet entry not found")
+	}
+	if action != model.TimesheetSubmit && entry.UserID == userID {
+		return apierror.Forbidden("Timesheet entries cannot be reviewed by the user who submitted them")
+	}
+	if !transition.AppliesTo(entry.Status) {
+		return apierror.Conflict(fmt.Sprintf("Cannot %s a timesheet entry that is %s", action, entry.Status))
+	}
+
+	from := entry.Status
+	now := time.Now()
+	entry.Status = transition.To
+	if action == model.TimesheetSubmit {
+		entry.SubmittedAt = &now
+		entry.ReviewedBy, entry.ReviewedAt, entry.ReviewComment = nil, nil, ""
+	} else {
+		entry.ReviewedBy, entry.ReviewedAt, entry.ReviewComment = &userID, &now, review.Comment
+	}
+	if err := c.repo.Transition(reqCtx, entry, from); err != nil {
+		return err
+	}
+
+	ctx.Set(middleware.LogEntryKey, middleware.LogEntry{
+		Type: transition.LogType,
+		Description: fmt.Sprintf("%s %s with ID: %d (worker %d on project %d, %s)", transition.LogType,
+			model.EntityTypeTimesheet, entry.ID, entry.WorkerID, entry.ProjectID, entry.Date.Format(time.DateOnly)),
+	})
+	return ctx.JSON(http.StatusOK, entry)
+}
+
+// currentRole reads the role of the user from the database rather than the
+// token, so a role taken away by an admin stops applying before the token
+// expires. Deactivated users are refused.
+func (c *TimesheetController) currentRole(ctx echo.Context, userID uint) (string, error) {
+	user, err := c.userRepo.GetUserByID(ctx.Request().Context(), userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !user.Active) {
+		return "", apierror.Unauthorized("User account is no longer active")
+	}
+	if err != nil {
+		return "", err
+	}
+	return user.Role, nil
+}
+
+// reviewScope returns the manager whose reports a user may review entries
+// of: the user themselves, or 0 for everyone when they are an admin
+func reviewScope(userID uint, role string) uint {
+	if role == model.RoleAdmin {
+		return 0
+	}
+	return userID
+}
+
+// GetApprovals handles GET /api/timesheets/approvals, the queue of submitted
+// entries awaiting the review of the manager, oldest day first.
+// Admins see the queues of every manager, or of one with manager_id.
+func (c *TimesheetController) GetApprovals(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	role, err := c.currentRole(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !model.TimesheetTransitions[model.TimesheetApprove].Allows(role) {
+		return apierror.Forbidden("Manager access required")
+	}
+
+	managerID := reviewScope(userID, role)
+	if requested, err := parseOptionalID(ctx, "manager_id"); err != nil {
+		return err
+	} else if requested != 0 && requested != userID && role != model.RoleAdmin {
+		return apierror.Forbidden("Only admins can list the approvals of other managers")
+	} else if requested != 0 {
+		managerID = requested
+	}
+
+	// Get query parameters for filtering and sorting, validated against the timesheet whitelist
+	spec, err := parseTimesheetQuery(ctx)
+	if err != nil {
+		return err
+	}
+	if len(spec.Sort) == 0 {
+		spec.AddSort(repository.TimesheetFields, "date", false)
+	}
+
+	// Get pagination parameters
+	params, err := pagination.Parse(ctx, pagination.DefaultPageSize)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := parseCursor(spec, params)
+	if err != nil {
+		return err
+	}
+
+	opts := repository.ListOptions{
+		Search:   ctx.QueryParam("search"),
+		Query:    spec,
+		Cursor:   cursor,
+		Page:     params.Page,
+		PageSize: params.PageSize,
+	}
+	approvals, info, err := c.repo.ListApprovals(ctx.Request().Context(), managerID, opts)
+	if err != nil {
+		return err
+	}
+
+	// Return paginated response
+	return pagination.Respond(ctx, "data", approvals, params, info)
+}
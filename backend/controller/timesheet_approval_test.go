@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository/memory"
+	"github.com/labstack/echo/v4"
+)
+
+// The users of the approval tests, in the order they are created
+const (
+	foremanID uint = iota + 1
+	managerID
+	adminID
+	otherManagerID
+)
+
+// newApprovalServer creates a foreman reporting to a manager, an admin and
+// a manager of nobody, and returns the server with the user repository
+func newApprovalServer(t *testing.T) (*echo.Echo, repository.UserRepository) {
+	t.Helper()
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	manager := managerID
+	for _, user := range []model.User{
+		{Username: "foreman", Email: "foreman@example.com", Role: model.RoleForeman, ManagerID: &manager},
+		{Username: "manager", Email: "manager@example.com", Role: model.RoleManager},
+		{Username: "admin", Email: "admin@example.com", Role: model.RoleAdmin},
+		{Username: "other", Email: "other@example.com", Role: model.RoleManager},
+	} {
+		if err := users.CreateUser(context.Background(), &user, "password"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return newTestServerOn(store), users
+}
+
+// createTimesheet creates a draft entry of the user for a new worker on a new
+// project and returns its path
+func createTimesheet(t *testing.T, e *echo.Echo, userID uint) string {
+	t.Helper()
+	workerID := createWorker(t, e, userID, "Ana Pop")
+	rec := do(t, e, userID, http.MethodPost, "/api/projects", projectBody("North", workerID))
+	expectStatus(t, rec, http.StatusCreated)
+	var project struct {
+		ID uint `json:"id"`
+	}
+	decode(t, rec, &project)
+
+	rec = do(t, e, userID, http.MethodPost, "/api/timesheets",
+		`{"worker_id":`+strconv.FormatUint(uint64(workerID), 10)+`,"project_id":`+strconv.FormatUint(uint64(project.ID), 10)+
+			`,"date":"2026-03-02T00:00:00Z","hours":8}`)
+	expectStatus(t, rec, http.StatusCreated)
+	var entry model.Timesheet
+	decode(t, rec, &entry)
+	return "/api/timesheets/" + strconv.FormatUint(uint64(entry.ID), 10)
+}
+
+// expectTimesheetStatus fails the test unless the response is the entry in the status
+func expectTimesheetStatus(t *testing.T, rec *httptest.ResponseRecorder, status string) {
+	t.Helper()
+	expectStatus(t, rec, http.StatusOK)
+	var entry model.Timesheet
+	decode(t, rec, &entry)
+	if entry.Status != status {
+		t.Fatalf("expected the entry to be %s, got %s", status, entry.Status)
+	}
+}
+
+func TestTimesheetApproval(t *testing.T) {
+	e, _ := newApprovalServer(t)
+	path := createTimesheet(t, e, foremanID)
+
+	// Reviews wait for the submission
+	expectStatus(t, do(t, e, managerID, http.MethodPost, path+"/approve", ""), http.StatusConflict)
+	expectTimesheetStatus(t, do(t, e, foremanID, http.MethodPost, path+"/submit", ""), model.TimesheetSubmitted)
+	expectStatus(t, do(t, e, foremanID, http.MethodPost, path+"/submit", ""), http.StatusConflict)
+
+	// Submitted entries can no longer be changed by their user
+	rec := do(t, e, foremanID, http.MethodPut, path, `{"worker_id":1,"project_id":1,"date":"2026-03-02T00:00:00Z","hours":6}`)
+	expectStatus(t, rec, http.StatusConflict)
+	var apiErr apierror.Error
+	decode(t, rec, &apiErr)
+	if apiErr.Code != apierror.CodeTimesheetLocked {
+		t.Fatalf("expected the entry to be locked, got %+v", apiErr)
+	}
+
+	// Only the manager of the foreman, or an admin, reviews the entry
+	expectStatus(t, do(t, e, foremanID, http.MethodPost, path+"/approve", ""), http.StatusForbidden)
+	expectStatus(t, do(t, e, otherManagerID, http.MethodPost, path+"/approve", ""), http.StatusNotFound)
+
+	rec = do(t, e, managerID, http.MethodPost, path+"/reject", `{}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+	apiErr = apierror.Error{}
+	decode(t, rec, &apiErr)
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Field != "comment" {
+		t.Fatalf("expected the comment to be required, got %+v", apiErr)
+	}
+
+	rec = do(t, e, managerID, http.MethodPost, path+"/reject", `{"comment":"Hours missing"}`)
+	expectTimesheetStatus(t, rec, model.TimesheetRejected)
+	var entry model.Timesheet
+	decode(t, rec, &entry)
+	if entry.ReviewedBy == nil || *entry.ReviewedBy != managerID || entry.ReviewComment != "Hours missing" {
+		t.Fatalf("expected the review of the manager, got %+v", entry)
+	}
+
+	// Rejected entries are submitted again, then approved for good
+	expectTimesheetStatus(t, do(t, e, foremanID, http.MethodPost, path+"/submit", ""), model.TimesheetSubmitted)
+	expectTimesheetStatus(t, do(t, e, adminID, http.MethodPost, path+"/approve", ""), model.TimesheetApproved)
+	expectStatus(t, do(t, e, managerID, http.MethodPost, path+"/reject", `{"comment":"Too late"}`), http.StatusConflict)
+	expectStatus(t, do(t, e, foremanID, http.MethodPost, path+"/submit", ""), http.StatusConflict)
+}
+
+func TestTimesheetEntriesAreNotReviewedByTheirUser(t *testing.T) {
+	e, _ := newApprovalServer(t)
+	path := createTimesheet(t, e, adminID)
+
+	expectTimesheetStatus(t, do(t, e, adminID, http.MethodPost, path+"/submit", ""), model.TimesheetSubmitted)
+	expectStatus(t, do(t, e, adminID, http.MethodPost, path+"/approve", ""), http.StatusForbidden)
+}
+
+func TestTimesheetReviewUsesTheCurrentRole(t *testing.T) {
+	e, users := newApprovalServer(t)
+	ctx := context.Background()
+	path := createTimesheet(t, e, foremanID)
+	expectTimesheetStatus(t, do(t, e, foremanID, http.MethodPost, path+"/submit", ""), model.TimesheetSubmitted)
+
+	// The role taken away applies at once, whatever the token says
+	if err := users.UpdateUserRole(ctx, managerID, model.RoleUser); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, do(t, e, managerID, http.MethodPost, path+"/approve", ""), http.StatusForbidden)
+
+	if err := users.UpdateUserRole(ctx, managerID, model.RoleManager); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.UpdateUserStatus(ctx, managerID, false); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, do(t, e, managerID, http.MethodPost, path+"/approve", ""), http.StatusUnauthorized)
+}
+
+func TestTimesheetApprovalQueue(t *testing.T) {
+	e, _ := newApprovalServer(t)
+	path := createTimesheet(t, e, foremanID)
+	createTimesheet(t, e, foremanID)
+	expectTimesheetStatus(t, do(t, e, foremanID, http.MethodPost, path+"/submit", ""), model.TimesheetSubmitted)
+
+	tests := []struct {
+		name      string
+		userID    uint
+		managerID uint
+		status    int
+		total     int64
+	}{
+		{name: "manager", userID: managerID, status: http.StatusOK, total: 1},
+		{name: "manager of nobody", userID: otherManagerID, status: http.StatusOK, total: 0},
+		{name: "foreman", userID: foremanID, status: http.StatusForbidden},
+		{name: "queue of another manager", userID: otherManagerID, managerID: managerID, status: http.StatusForbidden},
+		{name: "admin", userID: adminID, status: http.StatusOK, total: 1},
+		{name: "admin for a manager", userID: adminID, managerID: otherManagerID, status: http.StatusOK, total: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := url.Values{}
+			if tt.managerID != 0 {
+				params.Set("manager_id", strconv.FormatUint(uint64(tt.managerID), 10))
+			}
+			rec := do(t, e, tt.userID, http.MethodGet, "/api/timesheets/approvals?"+params.Encode(), "")
+			expectStatus(t, rec, tt.status)
+			if tt.status == http.StatusOK {
+				if n := total(t, rec); n != tt.total {
+					t.Fatalf("expected %d entries awaiting approval, got %d", tt.total, n)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/middleware"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/pagination"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/query"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type TimesheetController struct {
+	repo     repository.TimesheetRepository
+	userRepo repository.UserRepository
+	uow      repository.UnitOfWork
+	validate *validator.Validate
+}
+
+func NewTimesheetController(repo repository.TimesheetRepository, userRepo repository.UserRepository, uow repository.UnitOfWork) *TimesheetController {
+	return &TimesheetController{
+		repo:     repo,
+		userRepo: userRepo,
+		uow:      uow,
+		validate: newValidator(),
+	}
+}
+
+// timesheetWeek is the body of PUT /api/timesheets/week: the days one worker
+// spent on one project during a week
+type timesheetWeek struct {
+	WorkerID  uint `json:"worker_id" validate:"required"`
+	ProjectID uint `json:"project_id" validate:"required"`
+	// WeekStart is the Monday the week starts on
+	WeekStart time.Time         `json:"week_start" validate:"required"`
+	Days      []model.Timesheet `json:"days" validate:"min=1,max=7,dive"`
+}
+
+// timesheetStatuses lists the statuses of timesheet entries
+var timesheetStatuses = []string{model.TimesheetDraft, model.TimesheetSubmitted, model.TimesheetApproved, model.TimesheetRejected}
+
+// timesheetError reports the repository errors of writing a timesheet entry;
+// field prefixes the fields of the details, e.g. "days[2]."
+func timesheetError(err error, field string) error {
+	switch {
+	case errors.Is(err, repository.ErrNotAssigned):
+		return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Validation failed").Wrap(err).
+			WithDetails(apierror.FieldError{Field: field + "date", Code: "not_assigned", Message: "the worker is not assigned to the project on this day"})
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return apierror.Conflict("The worker already has a timesheet entry for the project on this day").Wrap(err)
+	case errors.Is(err, repository.ErrLocked):
+		return apierror.New(http.StatusConflict, apierror.CodeTimesheetLocked, "The timesheet entry is locked").Wrap(err).
+			WithDetails(apierror.FieldError{Field: field + "status", Code: "locked", Message: "submitted and approved entries can no longer be changed"})
+	case errors.Is(err, repository.ErrPeriodLocked):
+		return apierror.New(http.StatusConflict, apierror.CodeTimesheetLocked, "The timesheet entry is locked").Wrap(err).
+			WithDetails(apierror.FieldError{Field: field + "date", Code: "period_locked", Message: "the week of this day is approved"})
+	}
+	return notFound(err, "Worker or project not found")
+}
+
+// parseTimesheetQuery builds the filter and sort spec of the timesheet list,
+// with worker_id, project_id, from and to as shorthands
+func parseTimesheetQuery(ctx echo.Context) (*query.Spec, error) {
+	return parseListQuery(ctx, repository.TimesheetFields, []legacyFilter{
+		{param: "worker_id", field: "worker_id", op: query.OpEq},
+		{param: "project_id", field: "project_id", op: query.OpEq},
+		{param: "from", field: "date", op: query.OpGte},
+		{param: "to", field: "date", op: query.OpLte},
+	})
+}
+
+// GetTimesheets handles GET /api/timesheets
+func (c *TimesheetController) GetTimesheets(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Get query parameters for filtering and sorting, validated against the timesheet whitelist
+	spec, err := parseTimesheetQuery(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Get pagination parameters
+	params, err := pagination.Parse(ctx, pagination.DefaultPageSize)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := parseCursor(spec, params)
+	if err != nil {
+		return err
+	}
+
+	opts := repository.ListOptions{
+		Search:   ctx.QueryParam("search"),
+		Query:    spec,
+		Cursor:   cursor,
+		Page:     params.Page,
+		PageSize: params.PageSize,
+	}
+	entries, info, err := c.repo.GetAll(ctx.Request().Context(), userID, opts)
+	if err != nil {
+		return err
+	}
+
+	// Return paginated response
+	return pagination.Respond(ctx, "data", entries, params, info)
+}
+
+// GetTimesheet handles GET /api/timesheets/:id
+func (c *TimesheetController) GetTimesheet(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := pathID(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	entry, err := c.repo.GetByID(ctx.Request().Context(), id, userID)
+	if err != nil {
+		return notFound(err, "Timesheet entry not found")
+	}
+
+	return ctx.JSON(http.StatusOK, entry)
+}
+
+// CreateTimesheet handles POST /api/timesheets, adding a draft entry. The
+// worker must be assigned to the project on the date of the entry.
+func (c *TimesheetController) CreateTimesheet(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var entry model.Timesheet
+	if err := ctx.Bind(&entry); err != nil {
+		return err
+	}
+	entry.ID = 0
+	entry.UserID = userID
+	entry.Date = dateOf(entry.Date)
+
+	if err := c.validate.Struct(entry); err != nil {
+		return err
+	}
+
+	if err := c.repo.Create(ctx.Request().Context(), &entry); err != nil {
+		return timesheetError(err, "")
+	}
+
+	return ctx.JSON(http.StatusCreated, entry)
+}
+
+// UpdateTimesheet handles PUT /api/timesheets/:id, replacing the time of the
+// entry with the checks of CreateTimesheet. Its status is left as it is.
+func (c *TimesheetController) UpdateTimesheet(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := pathID(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	var entry model.Timesheet
+	if err := ctx.Bind(&entry); err != nil {
+		return err
+	}
+	entry.ID = id
+	entry.UserID = userID
+	entry.Date = dateOf(entry.Date)
+
+	if err := c.validate.Struct(entry); err != nil {
+		return err
+	}
+
+	reqCtx := ctx.Request().Context()
+	if _, err := c.repo.GetByID(reqCtx, id, userID); err != nil {
+		return notFound(err, "Timesheet entry not found")
+	}
+	if err := c.repo.Update(reqCtx, &entry); err != nil {
+		return timesheetError(err, "")
+	}
+
+	return ctx.JSON(http.StatusOK, entry)
+}
+
+// DeleteTimesheet handles DELETE /api/timesheets/:id
+func (c *TimesheetController) DeleteTimesheet(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := pathID(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := c.repo.Delete(ctx.Request().Context(), id, userID); err != nil {
+		if errors.Is(err, repository.ErrLocked) || errors.Is(err, repository.ErrPeriodLocked) {
+			return timesheetError(err, "")
+		}
+		return notFound(err, "Timesheet entry not found")
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// SaveTimesheetWeek handles PUT /api/timesheets/week, saving the days of a
+// week of one worker on one project at once. Each day replaces the entry of
+// its date, if any; days left out are left alone. Every day is checked
+// against the assignment, and nothing is saved unless all of them pass.
+func (c *TimesheetController) SaveTimesheetWeek(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var week timesheetWeek
+	if err := ctx.Bind(&week); err != nil {
+		return err
+	}
+	week.WeekStart = dateOf(week.WeekStart)
+	for i := range week.Days {
+		week.Days[i].ID = 0
+		week.Days[i].WorkerID = week.WorkerID
+		week.Days[i].ProjectID = week.ProjectID
+		week.Days[i].UserID = userID
+		week.Days[i].Date = dateOf(week.Days[i].Date)
+	}
+
+	if err := c.validate.Struct(week); err != nil {
+		return err
+	}
+	if err := checkTimesheetWeek(week); err != nil {
+		return err
+	}
+
+	created := 0
+	reqCtx := ctx.Request().Context()
+	err = c.uow.Do(reqCtx, func(repos *repository.Repositories) error {
+		var details []apierror.FieldError
+		for i := range week.Days {
+			isNew, err := repos.Timesheets.Save(reqCtx, &week.Days[i])
+			if errors.Is(err, repository.ErrNotAssigned) {
+				details = append(details, apierror.FieldError{
+					Field:   fmt.Sprintf("days[%d].date", i),
+					Code:    "not_assigned",
+					Message: "the worker is not assigned to the project on this day",
+				})
+				continue
+			}
+			if err != nil {
+				return timesheetError(err, fmt.Sprintf("days[%d].", i))
+			}
+			if isNew {
+				created++
+			}
+		}
+		if len(details) > 0 {
+			return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Validation failed").WithDetails(details...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx.Set(middleware.LogSummaryKey, fmt.Sprintf("%s %s: week of %s for worker %d on project %d, %d created, %d updated",
+		model.LogTypeBulk, model.EntityTypeTimesheet, week.WeekStart.Format(time.DateOnly),
+		week.WorkerID, week.ProjectID, created, len(week.Days)-created))
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"data": week.Days,
+	})
+}
+
+// checkTimesheetWeek checks that a week starts on Monday and that its days
+// are distinct days of the week
+func checkTimesheetWeek(week timesheetWeek) error {
+	var details []apierror.FieldError
+	if week.WeekStart.Weekday() != time.Monday {
+		details = append(details, apierror.FieldError{Field: "week_start", Code: "weekday", Message: "must be a Monday"})
+	}
+
+	weekEnd := week.WeekStart.AddDate(0, 0, 6)
+	seen := make(map[time.Time]bool)
+	for i, day := range week.Days {
+		field := fmt.Sprintf("days[%d].date", i)
+		switch {
+		case day.Date.Before(week.WeekStart) || day.Date.After(weekEnd):
+			details = append(details, apierror.FieldError{Field: field, Code: "week",
+				Message: fmt.Sprintf("must fall between %s and %s", week.WeekStart.Format(time.DateOnly), weekEnd.Format(time.DateOnly))})
+		case seen[day.Date]:
+			details = append(details, apierror.FieldError{Field: field, Code: "unique", Message: "must not repeat another day"})
+		}
+		seen[day.Date] = true
+	}
+
+	if len(details) > 0 {
+		return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Validation failed").WithDetails(details...)
+	}
+	return nil
+}
+
+// GetTimesheetSummary handles GET /api/timesheets/summary, adding up hours
+// by worker or project (group_by) and by week or month (period). worker_id,
+// project_id, status, from and to narrow down the entries.
+func (c *TimesheetController) GetTimesheetSummary(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	summary := repository.TimesheetSummaryQuery{
+		GroupBy: repository.SummaryByWorker,
+		Period:  repository.SummaryWeek,
+	}
+	if groupBy := ctx.QueryParam("group_by"); groupBy != "" {
+		if groupBy != repository.SummaryByWorker && groupBy != repository.SummaryByProject {
+			return apierror.InvalidParameter("group_by", "must be worker or project")
+		}
+		summary.GroupBy = groupBy
+	}
+	if period := ctx.QueryParam("period"); period != "" {
+		if period != repository.SummaryWeek && period != repository.SummaryMonth {
+			return apierror.InvalidParameter("period", "must be week or month")
+		}
+		summary.Period = period
+	}
+	if status := ctx.QueryParam("status"); status != "" {
+		if !slices.Contains(timesheetStatuses, status) {
+			return apierror.InvalidParameter("status", "must be one of: "+strings.Join(timesheetStatuses, ", "))
+		}
+		summary.Status = status
+	}
+	if summary.WorkerID, err = parseOptionalID(ctx, "worker_id"); err != nil {
+		return err
+	}
+	if summary.ProjectID, err = parseOptionalID(ctx, "project_id"); err != nil {
+		return err
+	}
+	if summary.From, err = parseOptionalDate(ctx, "from"); err != nil {
+		return err
+	}
+	if summary.To, err = parseOptionalDate(ctx, "to"); err != nil {
+		return err
+	}
+
+	summaries, err := c.repo.Summarize(ctx.Request().Context(), userID, summary)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"group_by": summary.GroupBy,
+		"period":   summary.Period,
+		"data":     summaries,
+	})
+}
+
+// parseOptionalID parses an optional numeric ID query parameter; 0 means missing
+func parseOptionalID(ctx echo.Context, name string) (uint, error) {
+	raw := ctx.QueryParam(name)
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || id == 0 {
+		return 0, apierror.InvalidParameter(name, "must be a positive integer")
+	}
+	return uint(id), nil
+}
+
+// parseOptionalDate parses an optional date query parameter, as YYYY-MM-DD
+// or RFC 3339, to the date at midnight UTC
+func parseOptionalDate(ctx echo.Context, name string) (*time.Time, error) {
+	raw := ctx.QueryParam(name)
+	if raw == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if value, err := time.Parse(layout, raw); err == nil {
+			date := dateOf(value)
+			return &date, nil
+		}
+	}
+	return nil, apierror.InvalidParameter(name, "must be a date (YYYY-MM-DD or RFC 3339)")
+}
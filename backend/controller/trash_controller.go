@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/pagination"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/labstack/echo/v4"
+)
+
+type TrashController struct {
+	repo repository.TrashRepository
+	// retention is how long items stay in the trash; zero keeps them forever
+	retention time.Duration
+}
+
+func NewTrashController(repo repository.TrashRepository, retention time.Duration) *TrashController {
+	return &TrashController{
+		repo:      repo,
+		retention: retention,
+	}
+}
+
+// trashEntry is a trashed item with the time the retention job purges it
+type trashEntry struct {
+	repository.TrashItem
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
+}
+
+// entry adds the purge time to a trashed item
+func (c *TrashController) entry(item repository.TrashItem) trashEntry {
+	entry := trashEntry{TrashItem: item}
+	if c.retention > 0 {
+		purgeAt := item.DeletedAt.Add(c.retention)
+		entry.PurgeAt = &purgeAt
+	}
+	return entry
+}
+
+// trashParams parses the type and ID path parameters of a trashed item
+func trashParams(ctx echo.Context) (model.EntityType, uint, error) {
+	entityType, ok := entityTypes[strings.ToLower(ctx.Param("type"))]
+	if !ok {
+		return "", 0, apierror.InvalidParameter("type", "must be worker or project")
+	}
+	id, err := pathID(ctx, "id")
+	if err != nil {
+		return "", 0, err
+	}
+	return entityType, id, nil
+}
+
+// GetTrash handles GET /api/trash
+func (c *TrashController) GetTrash(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	params, err := pagination.Parse(ctx, pagination.DefaultPageSize)
+	if err != nil {
+		return err
+	}
+	if params.CursorMode {
+		return apierror.InvalidParameter("cursor", "is not supported by the trash")
+	}
+
+	// Optionally restrict the listing to some entity types, e.g. type=worker
+	types, err := parseEntityTypes(ctx)
+	if err != nil {
+		return err
+	}
+
+	items, total, err := c.repo.List(ctx.Request().Context(), userID, types, params.Page, params.PageSize)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]trashEntry, len(items))
+	for i, item := range items {
+		entries[i] = c.entry(item)
+	}
+	return pagination.Respond(ctx, "data", entries, params, repository.PageInfo{Total: total})
+}
+
+// RestoreItem handles POST /api/trash/:type/:id/restore
+func (c *TrashController) RestoreItem(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	entityType, id, err := trashParams(ctx)
+	if err != nil {
+		return err
+	}
+
+	item, err := c.repo.Restore(ctx.Request().Context(), userID, entityType, id)
+	if err != nil {
+		return notFound(err, "Item not found in trash")
+	}
+
+	return ctx.JSON(http.StatusOK, item)
+}
+
+// PurgeItem handles DELETE /api/trash/:type/:id
+func (c *TrashController) PurgeItem(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	entityType, id, err := trashParams(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.repo.Purge(ctx.Request().Context(), userID, entityType, id); err != nil {
+		return notFound(err, "Item not found in trash")
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/geo"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/go-playground/validator/v10"
+)
+
+// newValidator creates a validator reporting fields by their JSON name, with
+// the custom rules of the models
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	validate.RegisterValidation("polygon", validatePolygon)
+	validate.RegisterStructValidation(validateProjectSite, model.Project{})
+	validate.RegisterStructValidation(validateAssignmentDates, model.AssignmentDetails{})
+	validate.RegisterStructValidation(validateTimesheetDay, model.Timesheet{})
+	return validate
+}
+
+// validatePolygon checks that a site boundary is a valid polygon ring
+func validatePolygon(fl validator.FieldLevel) bool {
+	ring, ok := fl.Field().Interface().([]geo.Point)
+	return ok && geo.ValidateRing(ring) == nil
+}
+
+// validateProjectSite checks how far a site reaches from its main location
+func validateProjectSite(sl validator.StructLevel) {
+	project := sl.Current().Interface().(model.Project)
+	if field := project.OutsideExtent(); field != "" {
+		sl.ReportError(nil, field, field, "site_extent", strconv.FormatFloat(model.MaxSiteExtentKm, 'f', -1, 64))
+	}
+}
+
+// validateAssignmentDates checks that an assignment does not end before it starts
+func validateAssignmentDates(sl validator.StructLevel) {
+	details := sl.Current().Interface().(model.AssignmentDetails)
+	if details.StartDate != nil && details.EndDate != nil && details.EndDate.Before(*details.StartDate) {
+		sl.ReportError(details.EndDate, "end_date", "EndDate", "gtefield", "start_date")
+	}
+}
+
+// validateTimesheetDay checks that overtime, on top of the regular hours,
+// still fits in the day
+func validateTimesheetDay(sl validator.StructLevel) {
+	entry := sl.Current().Interface().(model.Timesheet)
+	if entry.TotalHours() > 24 {
+		sl.ReportError(entry.OvertimeHours, "overtime_hours", "OvertimeHours", "day_hours", "24")
+	}
+}
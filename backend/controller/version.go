@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/labstack/echo/v4"
+)
+
+// setETag exposes the version of a record as its entity tag
+func setETag(c echo.Context, version uint) {
+	c.Response().Header().Set("ETag", strconv.Quote(strconv.FormatUint(uint64(version), 10)))
+}
+
+// expectedVersion returns the version of the record an update is based on:
+// the If-Match header, e.g. If-Match: "3", or else the version in the body.
+// If-Match: * accepts any version and yields zero. Updates stating neither
+// are rejected, so clients cannot overwrite changes they have not seen.
+func expectedVersion(c echo.Context, bodyVersion uint) (uint, error) {
+	version, present, err := ifMatch(c)
+	if err != nil || present {
+		return version, err
+	}
+	if bodyVersion == 0 {
+		return 0, apierror.PreconditionRequired("The If-Match header or the version field is required")
+	}
+	return bodyVersion, nil
+}
+
+// ifMatch parses the If-Match header and reports whether it is present. The
+// wildcard yields zero.
+func ifMatch(c echo.Context) (uint, bool, error) {
+	raw := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+	if raw == "" {
+		return 0, false, nil
+	}
+	if raw == "*" {
+		return 0, true, nil
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(raw, "W/"), `"`)
+	version, err := strconv.ParseUint(tag, 10, 32)
+	if err != nil || version == 0 {
+		return 0, true, apierror.InvalidParameter("If-Match", "must be the ETag of the record, e.g. \"3\"")
+	}
+	return uint(version), true, nil
+}
@@ -1,24 +1,33 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/middleware"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/pagination"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/query"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 type WorkerController struct {
-	repo *repository.WorkerRepository
+	repo repository.WorkerRepository
+	uow  repository.UnitOfWork
 	validate *validator.Validate
 }
 
-func NewWorkerController(repo *repository.WorkerRepository) *WorkerController {
+func NewWorkerController(repo repository.WorkerRepository, uow repository.UnitOfWork) *WorkerController {
 	return &WorkerController{
 		repo: repo,
-		validate: validator.New(),
+		uow:  uow,
+		validate: newValidator(),
 	}
 }
 
@@ -26,11 +35,29 @@ func NewWorkerController(repo *repository.WorkerRepository) *WorkerController {
 func getUserID(c echo.Context) (uint, error) {
 	userID, ok := c.Get("user_id").(uint)
 	if !ok {
-		return 0, echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+		return 0, apierror.Unauthorized("User not authenticated")
 	}
 	return userID, nil
 }
 
+// pathID parses a numeric ID path parameter
+func pathID(c echo.Context, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || id == 0 {
+		return 0, apierror.InvalidParameter(name, "must be a positive integer")
+	}
+	return uint(id), nil
+}
+
+// notFound reports a missing record with the given message; other repository
+// errors are left to the error handler
+func notFound(err error, message string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apierror.NotFound(message).Wrap(err)
+	}
+	return err
+}
+
 // GetAllWorkers handles GET /api/workers
 func (c *WorkerController) GetAllWorkers(ctx echo.Context) error {
 	// Get user ID from context
@@ -39,73 +66,47 @@ func (c *WorkerController) GetAllWorkers(ctx echo.Context) error {
 		return err
 	}
 
-	// Get query parameters for filtering and sorting
-	filters := make(map[string]interface{})
-
-	// Handle search term
-	if search := ctx.QueryParam("search"); search != "" {
-		filters["search"] = search
-	}
-
-	// Handle position filter
-	if position := ctx.QueryParam("position"); position != "" {
-		filters["position"] = position
-	}
-
-	// Handle age range filters
-	if minAge := ctx.QueryParam("min_age"); minAge != "" {
-		if age, err := strconv.Atoi(minAge); err == nil {
-			filters["min_age"] = age
-		}
-	}
-	if maxAge := ctx.QueryParam("max_age"); maxAge != "" {
-		if age, err := strconv.Atoi(maxAge); err == nil {
-			filters["max_age"] = age
-		}
-	}
-
-	// Handle salary range filters
-	if minSalary := ctx.QueryParam("min_salary"); minSalary != "" {
-		if salary, err := strconv.Atoi(minSalary); err == nil {
-			filters["min_salary"] = salary
-		}
-	}
-	if maxSalary := ctx.QueryParam("max_salary"); maxSalary != "" {
-		if salary, err := strconv.Atoi(maxSalary); err == nil {
-			filters["max_salary"] = salary
-		}
+	// Get query parameters for filtering and sorting, validated against the worker whitelist
+	spec, err := parseWorkerQuery(ctx)
+	if err != nil {
+		return err
 	}
 
-	sortBy := ctx.QueryParam("sort_by")
-	sortOrder := ctx.QueryParam("sort_order")
-
 	// Get pagination parameters
-	page := 1
-	pageSize := 10
-
-	if pageParam := ctx.QueryParam("page"); pageParam != "" {
-		if parsedPage, err := strconv.Atoi(pageParam); err == nil && parsedPage > 0 {
-			page = parsedPage
-		}
+	params, err := pagination.Parse(ctx, pagination.DefaultPageSize)
+	if err != nil {
+		return err
 	}
 
-	if pageSizeParam := ctx.QueryParam("page_size"); pageSizeParam != "" {
-		if parsedPageSize, err := strconv.Atoi(pageSizeParam); err == nil && parsedPageSize > 0 {
-			pageSize = parsedPageSize
-		}
+	cursor, err := parseCursor(spec, params)
+	if err != nil {
+		return err
 	}
 
-	workers, total, err := c.repo.GetAll(userID, filters, sortBy, sortOrder, page, pageSize)
+	opts := repository.ListOptions{
+		Search:   ctx.QueryParam("search"),
+		Query:    spec,
+		Cursor:   cursor,
+		Page:     params.Page,
+		PageSize: params.PageSize,
+	}
+	workers, info, err := c.repo.GetAll(ctx.Request().Context(), userID, opts)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return err
 	}
 
 	// Return paginated response
-	return ctx.JSON(http.StatusOK, map[string]interface{}{
-		"data":     workers,
-		"total":    total,
-		"page":     page,
-		"pageSize": pageSize,
+	return pagination.Respond(ctx, "data", workers, params, info)
+}
+
+// parseWorkerQuery parses the filters and sort of a worker list
+func parseWorkerQuery(ctx echo.Context) (*query.Spec, error) {
+	return parseListQuery(ctx, repository.WorkerFields, []legacyFilter{
+		{param: "position", field: "position", op: query.OpEq},
+		{param: "min_age", field: "age", op: query.OpGte},
+		{param: "max_age", field: "age", op: query.OpLte},
+		{param: "min_salary", field: "salary", op: query.OpGte},
+		{param: "max_salary", field: "salary", op: query.OpLte},
 	})
 }
 
@@ -117,16 +118,17 @@ func (c *WorkerController) GetWorker(ctx echo.Context) error {
 		return err
 	}
 
-	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	id, err := pathID(ctx, "id")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
+		return err
 	}
 
-	worker, err := c.repo.GetByID(uint(id), userID)
+	worker, err := c.repo.GetByID(ctx.Request().Context(), id, userID)
 	if err != nil {
-		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "Worker not found"})
+		return notFound(err, "Worker not found")
 	}
 
+	setETag(ctx, worker.Version)
 	return ctx.JSON(http.StatusOK, worker)
 }
 
@@ -140,7 +142,7 @@ func (c *WorkerController) CreateWorker(ctx echo.Context) error {
 
 	var worker model.Worker
 	if err := ctx.Bind(&worker); err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return err
 	}
 
 	// Set user ID for the worker
@@ -148,11 +150,11 @@ func (c *WorkerController) CreateWorker(ctx echo.Context) error {
 
 	// Validate worker
 	if err := c.validate.Struct(worker); err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return err
 	}
 
-	if err := c.repo.Create(&worker); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := c.repo.Create(ctx.Request().Context(), &worker); err != nil {
+		return err
 	}
 
 	return ctx.JSON(http.StatusCreated, worker)
@@ -166,27 +168,148 @@ func (c *WorkerController) UpdateWorker(ctx echo.Context) error {
 		return err
 	}
 
-	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	id, err := pathID(ctx, "id")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
+		return err
 	}
 
 	var worker model.Worker
 	if err := ctx.Bind(&worker); err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return err
 	}
 
 	// Set worker ID and user ID
-	worker.ID = uint(id)
+	worker.ID = id
 	worker.UserID = userID
 
-	if err := c.repo.Update(&worker, userID); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	// The worker is replaced as a whole, so it must be valid as a whole
+	if err := c.validate.Struct(worker); err != nil {
+		return err
+	}
+
+	// Only the version the client has seen may be overwritten
+	worker.Version, err = expectedVersion(ctx, worker.Version)
+	if err != nil {
+		return err
+	}
+
+	if err := c.repo.Update(ctx.Request().Context(), &worker, userID); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return c.conflict(ctx, id, userID, err)
+		}
+		return notFound(err, "Worker not found")
 	}
 
+	setETag(ctx, worker.Version)
 	return ctx.JSON(http.StatusOK, worker)
 }
 
+// PatchWorker handles PATCH /api/workers/:id with a JSON merge patch
+func (c *WorkerController) PatchWorker(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := pathID(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	current, err := c.repo.GetByID(ctx.Request().Context(), id, userID)
+	if err != nil {
+		return notFound(err, "Worker not found")
+	}
+
+	worker, changed, err := mergePatch(ctx, current, current.Version, workerPatchFields, "worker")
+	if err != nil {
+		return err
+	}
+	if err := c.validate.Struct(worker); err != nil {
+		return err
+	}
+
+	ctx.Set(middleware.ChangedFieldsKey, changed)
+	if len(changed) > 0 {
+		worker.Version = current.Version
+		if err := c.repo.Patch(ctx.Request().Context(), worker, changed, userID); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return c.conflict(ctx, id, userID, err)
+			}
+			return notFound(err, "Worker not found")
+		}
+	}
+
+	setETag(ctx, worker.Version)
+	return ctx.JSON(http.StatusOK, worker)
+}
+
+// conflict reports an update based on an outdated version of a worker,
+// together with the current worker
+func (c *WorkerController) conflict(ctx echo.Context, id, userID uint, cause error) error {
+	current, err := c.repo.GetByID(ctx.Request().Context(), id, userID)
+	if err != nil {
+		return notFound(err, "Worker not found")
+	}
+	setETag(ctx, current.Version)
+	return apierror.Conflict("The worker was modified by another request").WithCurrent(current).Wrap(cause)
+}
+
+// BulkWorkers handles POST /api/workers/bulk
+func (c *WorkerController) BulkWorkers(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return runBulk(ctx, c.uow, model.EntityTypeWorker, func(reqCtx context.Context, repos *repository.Repositories, op bulkOperation) (uint, interface{}, error) {
+		switch op.Op {
+		case bulkCreate:
+			var worker model.Worker
+			if err := decodeBulkData(op.Data, &worker); err != nil {
+				return 0, nil, err
+			}
+			worker.ID, worker.UserID, worker.Projects = 0, userID, nil
+			if err := c.validate.Struct(worker); err != nil {
+				return 0, nil, err
+			}
+			if err := repos.Workers.Create(reqCtx, &worker); err != nil {
+				return 0, nil, err
+			}
+			return worker.ID, worker, nil
+
+		case bulkUpdate:
+			current, err := repos.Workers.GetByID(reqCtx, op.ID, userID)
+			if err != nil {
+				return 0, nil, notFound(err, "Worker not found")
+			}
+			worker, changed, err := applyMergePatch(op.Data, 0, false, current, current.Version, workerPatchFields, "worker")
+			if err != nil {
+				return 0, nil, err
+			}
+			if err := c.validate.Struct(worker); err != nil {
+				return 0, nil, err
+			}
+			if len(changed) > 0 {
+				worker.Version = current.Version
+				if err := repos.Workers.Patch(reqCtx, worker, changed, userID); err != nil {
+					return 0, nil, notFound(err, "Worker not found")
+				}
+			}
+			return worker.ID, worker, nil
+
+		default:
+			// Unlike DELETE /api/workers/:id, a batch reports missing workers
+			if _, err := repos.Workers.GetByID(reqCtx, op.ID, userID); err != nil {
+				return 0, nil, notFound(err, "Worker not found")
+			}
+			return op.ID, nil, repos.Workers.Delete(reqCtx, op.ID, userID)
+		}
+	})
+}
+
 // DeleteWorker handles DELETE /api/workers/:id
 func (c *WorkerController) DeleteWorker(ctx echo.Context) error {
 	// Get user ID from context
@@ -195,13 +318,13 @@ func (c *WorkerController) DeleteWorker(ctx echo.Context) error {
 		return err
 	}
 
-	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	id, err := pathID(ctx, "id")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
+		return err
 	}
 
-	if err := c.repo.Delete(uint(id), userID); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := c.repo.Delete(ctx.Request().Context(), id, userID); err != nil {
+		return err
 	}
 
 	return ctx.NoContent(http.StatusNoContent)
@@ -215,18 +338,21 @@ func (c *WorkerController) AddToProject(ctx echo.Context) error {
 		return err
 	}
 
-	workerId, err := strconv.ParseUint(ctx.Param("workerId"), 10, 32)
+	workerId, err := pathID(ctx, "workerId")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid worker ID"})
+		return err
 	}
 
-	projectId, err := strconv.ParseUint(ctx.Param("projectId"), 10, 32)
+	projectId, err := pathID(ctx, "projectId")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+		return err
 	}
 
-	if err := c.repo.AddToProject(uint(workerId), uint(projectId), userID); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := c.repo.AddToProject(ctx.Request().Context(), workerId, projectId, userID); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return apierror.Conflict("Worker is already assigned to the project").Wrap(err)
+		}
+		return notFound(err, "Worker or project not found")
 	}
 
 	return ctx.NoContent(http.StatusNoContent)
@@ -240,18 +366,18 @@ func (c *WorkerController) RemoveFromProject(ctx echo.Context) error {
 		return err
 	}
 
-	workerId, err := strconv.ParseUint(ctx.Param("workerId"), 10, 32)
+	workerId, err := pathID(ctx, "workerId")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid worker ID"})
+		return err
 	}
 
-	projectId, err := strconv.ParseUint(ctx.Param("projectId"), 10, 32)
+	projectId, err := pathID(ctx, "projectId")
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+		return err
 	}
 
-	if err := c.repo.RemoveFromProject(uint(workerId), uint(projectId), userID); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := c.repo.RemoveFromProject(ctx.Request().Context(), workerId, projectId, userID); err != nil {
+		return notFound(err, "Worker or project not found")
 	}
 
 	return ctx.NoContent(http.StatusNoContent)
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/mergepatch"
+	"github.com/labstack/echo/v4"
+)
+
+func TestWorkersAreIsolatedPerUser(t *testing.T) {
+	e := newTestServer()
+	const owner, other = 1, 2
+	workerID := createWorker(t, e, owner, "Ana Pop")
+	path := "/api/workers/" + strconv.FormatUint(uint64(workerID), 10)
+
+	expectStatus(t, do(t, e, other, http.MethodGet, path, ""), http.StatusNotFound)
+	expectStatus(t, do(t, e, other, http.MethodPut, path,
+		`{"name":"Mallory","age":40,"position":"Thief","salary":1}`), http.StatusNotFound)
+	// Deletes are idempotent, so the other user's delete succeeds without
+	// touching the worker
+	expectStatus(t, do(t, e, other, http.MethodDelete, path, ""), http.StatusNoContent)
+
+	rec := do(t, e, other, http.MethodGet, "/api/workers", "")
+	expectStatus(t, rec, http.StatusOK)
+	if n := total(t, rec); n != 0 {
+		t.Fatalf("expected the other user to list no workers, got %d", n)
+	}
+
+	rec = do(t, e, owner, http.MethodGet, path, "")
+	expectStatus(t, rec, http.StatusOK)
+	var worker struct {
+		Name string `json:"name"`
+	}
+	decode(t, rec, &worker)
+	if worker.Name != "Ana Pop" {
+		t.Fatalf("expected the worker to be unchanged, got name %q", worker.Name)
+	}
+}
+
+func TestWorkersOfAnotherUserCannotBeAssigned(t *testing.T) {
+	e := newTestServer()
+	const owner, other = 1, 2
+	workerID := createWorker(t, e, owner, "Ana Pop")
+
+	rec := do(t, e, other, http.MethodPost, "/api/projects", projectBody("Depot"))
+	expectStatus(t, rec, http.StatusCreated)
+	var project struct {
+		ID uint `json:"id"`
+	}
+	decode(t, rec, &project)
+
+	rec = do(t, e, other, http.MethodPost, "/api/projects/"+strconv.FormatUint(uint64(project.ID), 10)+"/workers",
+		`{"workerId":`+strconv.FormatUint(uint64(workerID), 10)+`}`)
+	expectStatus(t, rec, http.StatusNotFound)
+}
+
+func TestWorkerListFiltersAndSorts(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+	for _, body := range []string{
+		`{"name":"Ana Pop","age":30,"position":"Mason","salary":3500}`,
+		`{"name":"Ion Rus","age":40,"position":"Welder","salary":2500}`,
+		`{"name":"Eva Lup","age":35,"position":"Mason","salary":4000}`,
+	} {
+		expectStatus(t, do(t, e, owner, http.MethodPost, "/api/workers", body), http.StatusCreated)
+	}
+
+	params := url.Values{"filter": {"salary>=3000;position:in:Mason"}, "sort": {"-salary"}}
+	rec := do(t, e, owner, http.MethodGet, "/api/workers?"+params.Encode(), "")
+	expectStatus(t, rec, http.StatusOK)
+	var page struct {
+		Data []struct {
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	decode(t, rec, &page)
+	if len(page.Data) != 2 || page.Data[0].Name != "Eva Lup" || page.Data[1].Name != "Ana Pop" {
+		t.Fatalf("expected Eva Lup then Ana Pop, got %+v", page.Data)
+	}
+
+	params = url.Values{"filter": {"user_id=2"}}
+	rec = do(t, e, owner, http.MethodGet, "/api/workers?"+params.Encode(), "")
+	expectStatus(t, rec, http.StatusBadRequest)
+	var apiErr apierror.Error
+	decode(t, rec, &apiErr)
+	if apiErr.Code != apierror.CodeInvalidParameter || len(apiErr.Details) != 1 || apiErr.Details[0].Field != "filter.user_id" {
+		t.Fatalf("expected an invalid_parameter error on filter.user_id, got %+v", apiErr)
+	}
+}
+
+// patchWorker sends a merge patch of the worker as the user, based on the ETag
+func patchWorker(t *testing.T, e *echo.Echo, userID, workerID uint, etag, patch string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPatch, "/api/workers/"+strconv.FormatUint(uint64(workerID), 10),
+		strings.NewReader(patch))
+	req.Header.Set(echo.HeaderContentType, mergepatch.MediaType)
+	req.Header.Set(testUserHeader, strconv.FormatUint(uint64(userID), 10))
+	req.Header.Set("If-Match", etag)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPatchWorker(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+	workerID := createWorker(t, e, owner, "Ana Pop")
+
+	rec := patchWorker(t, e, owner, workerID, `"1"`, `{"salary":3500}`)
+	expectStatus(t, rec, http.StatusOK)
+	var worker struct {
+		Name    string  `json:"name"`
+		Salary  float64 `json:"salary"`
+		Version uint    `json:"version"`
+	}
+	decode(t, rec, &worker)
+	if worker.Name != "Ana Pop" || worker.Salary != 3500 || worker.Version != 2 {
+		t.Fatalf("expected only the salary to change, got %+v", worker)
+	}
+	if etag := rec.Header().Get("ETag"); etag != `"2"` {
+		t.Fatalf("expected the ETag of version 2, got %s", etag)
+	}
+
+	// A patch based on the old version conflicts with the first one
+	expectStatus(t, patchWorker(t, e, owner, workerID, `"1"`, `{"position":"Welder"}`), http.StatusConflict)
+	// Removing a required member fails validation
+	expectStatus(t, patchWorker(t, e, owner, workerID, `"2"`, `{"name":null}`), http.StatusBadRequest)
+
+	rec = patchWorker(t, e, owner, workerID, `"2"`, `{"salary":"a lot"}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+	var apiErr apierror.Error
+	decode(t, rec, &apiErr)
+	if apiErr.Code != apierror.CodeValidationFailed || len(apiErr.Details) != 1 || apiErr.Details[0].Field != "salary" {
+		t.Fatalf("expected a validation_failed error on salary, got %+v", apiErr)
+	}
+
+	// Other users cannot patch the worker
+	expectStatus(t, patchWorker(t, e, 2, workerID, "*", `{"salary":1}`), http.StatusNotFound)
+}
@@ -0,0 +1,485 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/middleware"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/spreadsheet"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxImportBytes bounds the size of an imported spreadsheet or GeoJSON file
+	maxImportBytes = 10 << 20
+	// maxImportRows bounds the number of worker rows of one import
+	maxImportRows = 5000
+	// maxImportBlankRows bounds the number of blank rows skipped by one import
+	maxImportBlankRows = 5000
+)
+
+// workerImportFields are the worker fields spreadsheet columns can be mapped to
+var workerImportFields = []string{"name", "age", "position", "salary"}
+
+// workerStructFields are the struct field names of the import fields
+var workerStructFields = map[string]string{"name": "Name", "age": "Age", "position": "Position", "salary": "Salary"}
+
+// Import actions of a row
+const (
+	importCreate  = "create"
+	importUpdate  = "update"
+	importSkip    = "skip"
+	importInvalid = "invalid"
+)
+
+// workerImportResult describes what an import does with one row
+type workerImportResult struct {
+	// Row is the row number in the spreadsheet, the header being row 1
+	Row int `json:"row"`
+	// Action is "create", "update", "skip" or "invalid"
+	Action string                `json:"action"`
+	ID     uint                  `json:"id,omitempty"`
+	Name   string                `json:"name,omitempty"`
+	Reason string                `json:"reason,omitempty"`
+	Errors []apierror.FieldError `json:"errors,omitempty"`
+}
+
+// workerImportReport summarizes an import
+type workerImportReport struct {
+	DryRun bool `json:"dry_run"`
+	// Mapping maps the header of each imported column to its worker field
+	Mapping   map[string]string    `json:"mapping"`
+	DedupeKey []string             `json:"dedupe_key"`
+	Created   int                  `json:"created"`
+	Updated   int                  `json:"updated"`
+	Skipped   int                  `json:"skipped"`
+	Invalid   int                  `json:"invalid"`
+	Results   []workerImportResult `json:"results"`
+}
+
+// workerImportOptions are the parameters of an import
+type workerImportOptions struct {
+	dryRun    bool
+	dedupeKey []string
+	// update makes rows matching an existing worker update it instead of
+	// being skipped
+	update bool
+	// mapping maps header names, as written, to worker fields
+	mapping map[string]string
+}
+
+// plannedWorker is a worker row to write
+type plannedWorker struct {
+	result  int
+	worker  *model.Worker
+	changed []string
+}
+
+// ImportWorkers handles POST /api/workers/import. The multipart form holds
+// the CSV or XLSX spreadsheet as "file" and optionally a "mapping" JSON
+// object from column headers to worker fields; columns named like a field
+// are mapped to it by default. Every row is validated and checked against
+// the dedupe_key fields of the earlier rows and of the user's workers: rows
+// matching a worker are skipped, or update it with on_duplicate=update. The
+// valid rows are written together, invalid ones are reported; with
+// dry_run=true only the report is returned.
+func (c *WorkerController) ImportWorkers(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	opts, err := parseWorkerImportOptions(ctx)
+	if err != nil {
+		return err
+	}
+	reader, filename, err := openSpreadsheet(ctx)
+	if err != nil {
+		return err
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return apierror.BadRequest("The spreadsheet is empty")
+	}
+	if err != nil {
+		return apierror.BadRequest("Invalid spreadsheet").Wrap(err)
+	}
+	columns, mapping, err := mapColumns(header.Cells, opts)
+	if err != nil {
+		return err
+	}
+
+	// Existing workers by dedupe key
+	existing, _, err := c.repo.GetAll(ctx.Request().Context(), userID, repository.ListOptions{})
+	if err != nil {
+		return err
+	}
+	matches := make(map[string][]model.Worker)
+	for _, worker := range existing {
+		key := dedupeKey(&worker, opts.dedupeKey)
+		matches[key] = append(matches[key], worker)
+	}
+
+	// Plan the import, checking every row
+	report := workerImportReport{DryRun: opts.dryRun, Mapping: mapping, DedupeKey: opts.dedupeKey, Results: make([]workerImportResult, 0)}
+	var planned []plannedWorker
+	seen := make(map[string]int)
+	blank := 0
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return apierror.BadRequest("Invalid spreadsheet").Wrap(err)
+		}
+		if isBlankRow(row.Cells) {
+			if blank++; blank > maxImportBlankRows {
+				return apierror.BadRequest("Invalid spreadsheet").WithDetails(apierror.FieldError{Field: "file", Code: "max", Message: fmt.Sprintf("must hold at most %d blank rows", maxImportBlankRows)})
+			}
+			continue
+		}
+		if len(report.Results) == maxImportRows {
+			return apierror.BadRequest("Invalid spreadsheet").WithDetails(apierror.FieldError{Field: "file", Code: "max", Message: fmt.Sprintf("must hold at most %d rows", maxImportRows)})
+		}
+
+		result, plan := c.planWorkerRow(row, columns, userID, opts, matches, seen)
+		switch result.Action {
+		case importCreate:
+			report.Created++
+		case importUpdate:
+			report.Updated++
+		case importSkip:
+			report.Skipped++
+		default:
+			report.Invalid++
+		}
+		if plan != nil {
+			plan.result = len(report.Results)
+			planned = append(planned, *plan)
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if opts.dryRun || len(planned) == 0 {
+		return ctx.JSON(http.StatusOK, report)
+	}
+
+	// Write the valid rows atomically
+	err = c.uow.Do(ctx.Request().Context(), func(repos *repository.Repositories) error {
+		for _, plan := range planned {
+			if plan.changed == nil {
+				if err := repos.Workers.Create(ctx.Request().Context(), plan.worker); err != nil {
+					return err
+				}
+				report.Results[plan.result].ID = plan.worker.ID
+				continue
+			}
+			if err := repos.Workers.Patch(ctx.Request().Context(), plan.worker, plan.changed, userID); err != nil {
+				return notFound(err, "Worker not found")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx.Set(middleware.LogSummaryKey, fmt.Sprintf("%s %s: imported %s, %d created, %d updated, %d skipped, %d invalid",
+		model.LogTypeBulk, model.EntityTypeWorker, filename, report.Created, report.Updated, report.Skipped, report.Invalid))
+	return ctx.JSON(http.StatusOK, report)
+}
+
+// parseWorkerImportOptions parses the query parameters and the mapping of an
+// import
+func parseWorkerImportOptions(ctx echo.Context) (workerImportOptions, error) {
+	opts := workerImportOptions{dedupeKey: []string{"name"}}
+
+	if raw := ctx.QueryParam("dry_run"); raw != "" {
+		dryRun, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, apierror.InvalidParameter("dry_run", "must be true or false")
+		}
+		opts.dryRun = dryRun
+	}
+
+	if raw := ctx.QueryParam("dedupe_key"); raw != "" {
+		opts.dedupeKey = nil
+		for _, field := range strings.Split(raw, ",") {
+			field = strings.TrimSpace(field)
+			if !slices.Contains(workerImportFields, field) {
+				return opts, apierror.InvalidParameter("dedupe_key", "must list fields among "+strings.Join(workerImportFields, ", "))
+			}
+			if !slices.Contains(opts.dedupeKey, field) {
+				opts.dedupeKey = append(opts.dedupeKey, field)
+			}
+		}
+	}
+
+	switch ctx.QueryParam("on_duplicate") {
+	case "", importSkip:
+	case importUpdate:
+		opts.update = true
+	default:
+		return opts, apierror.InvalidParameter("on_duplicate", "must be skip or update")
+	}
+
+	if raw := ctx.FormValue("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.mapping); err != nil {
+			return opts, apierror.InvalidParameter("mapping", "must be a JSON object mapping column headers to worker fields")
+		}
+	}
+	return opts, nil
+}
+
+// openSpreadsheet opens the spreadsheet uploaded as the "file" form field
+func openSpreadsheet(ctx echo.Context) (spreadsheet.Reader, string, error) {
+	invalidFile := func(code, message string) error {
+		return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid upload").
+			WithDetails(apierror.FieldError{Field: "file", Code: code, Message: message})
+	}
+
+	upload, err := ctx.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, "", invalidFile("required", "is required")
+	}
+	if err != nil {
+		return nil, "", apierror.BadRequest("Send the spreadsheet as multipart/form-data").Wrap(err)
+	}
+	if upload.Size > maxImportBytes {
+		return nil, "", apierror.New(http.StatusRequestEntityTooLarge, "request_entity_too_large",
+			fmt.Sprintf("The spreadsheet must not exceed %d MB", maxImportBytes>>20))
+	}
+	format, err := spreadsheet.FormatOf(upload.Filename)
+	if err != nil {
+		return nil, "", invalidFile("format", "must be a .csv or .xlsx file")
+	}
+
+	file, err := upload.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	// The form keeps the file until the request ends
+	ctx.Response().After(func() { file.Close() })
+
+	reader, err := spreadsheet.NewReader(file, upload.Size, format)
+	if errors.Is(err, spreadsheet.ErrTooLarge) {
+		return nil, "", apierror.New(http.StatusRequestEntityTooLarge, "request_entity_too_large",
+			"The spreadsheet is too large once uncompressed").Wrap(err)
+	}
+	if err != nil {
+		return nil, "", invalidFile("format", "is not a readable "+strings.ToUpper(string(format))+" file")
+	}
+	return reader, upload.Filename, nil
+}
+
+// mapColumns maps the columns of the header row onto worker fields, by the
+// mapping of the options or else by their name. It returns the field of each
+// column ("" for ignored columns) and the mapping applied.
+func mapColumns(header []string, opts workerImportOptions) ([]string, map[string]string, error) {
+	var details []apierror.FieldError
+	columns := make([]string, len(header))
+	mapping := make(map[string]string)
+	mapped := make(map[string]bool)
+	for i, title := range header {
+		title = strings.TrimSpace(title)
+		field, explicit := opts.mapping[title]
+		if !explicit {
+			field = normalizeHeader(title)
+			if !slices.Contains(workerImportFields, field) {
+				continue
+			}
+		}
+		if field == "" {
+			// Mapped to nothing: the column is ignored
+			continue
+		}
+		if !slices.Contains(workerImportFields, field) {
+			details = append(details, apierror.FieldError{Field: "mapping." + title, Code: "oneof", Message: "must be one of " + strings.Join(workerImportFields, " ")})
+			continue
+		}
+		if mapped[field] {
+			details = append(details, apierror.FieldError{Field: "mapping." + title, Code: "unique", Message: fmt.Sprintf("%s is mapped from more than one column", field)})
+			continue
+		}
+		columns[i], mapping[title], mapped[field] = field, field, true
+	}
+
+	for title := range opts.mapping {
+		if !slices.ContainsFunc(header, func(h string) bool { return strings.TrimSpace(h) == title }) {
+			details = append(details, apierror.FieldError{Field: "mapping." + title, Code: "not_found", Message: "is not a column of the spreadsheet"})
+		}
+	}
+	for _, field := range opts.dedupeKey {
+		if !mapped[field] {
+			details = append(details, apierror.FieldError{Field: "dedupe_key", Code: "required", Message: fmt.Sprintf("%s must be mapped from a column", field)})
+		}
+	}
+
+	if len(details) > 0 {
+		slices.SortFunc(details, func(a, b apierror.FieldError) int { return strings.Compare(a.Field, b.Field) })
+		return nil, nil, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid column mapping").WithDetails(details...)
+	}
+	return columns, mapping, nil
+}
+
+// planWorkerRow turns a row into the worker to create or update. Rows
+// matching an earlier row or several existing workers are reported, like
+// invalid rows, without a plan.
+func (c *WorkerController) planWorkerRow(row spreadsheet.Row, columns []string, userID uint, opts workerImportOptions, matches map[string][]model.Worker, seen map[string]int) (workerImportResult, *plannedWorker) {
+	result := workerImportResult{Row: row.Number}
+	invalid := func(errs ...apierror.FieldError) (workerImportResult, *plannedWorker) {
+		result.Action, result.Errors = importInvalid, errs
+		return result, nil
+	}
+
+	// Read the cells of the mapped columns; blank cells are left out
+	values := make(map[string]string)
+	var errs []apierror.FieldError
+	for i, field := range columns {
+		if field == "" || i >= len(row.Cells) || strings.TrimSpace(row.Cells[i]) == "" {
+			continue
+		}
+		value := strings.TrimSpace(row.Cells[i])
+		if field == "age" || field == "salary" {
+			if _, err := parseWholeNumber(value); err != nil {
+				errs = append(errs, apierror.FieldError{Field: field, Code: "type", Message: "must be a whole number"})
+				continue
+			}
+		}
+		values[field] = value
+	}
+	worker := &model.Worker{UserID: userID}
+	setImportedFields(worker, values)
+	result.Name = worker.Name
+	if len(errs) > 0 {
+		// Report the problems of the other cells of the row too
+		present := make([]string, 0, len(values))
+		for field := range values {
+			present = append(present, workerStructFields[field])
+		}
+		if err := c.validate.StructPartial(worker, present...); err != nil {
+			errs = append(errs, apierror.From(err).Details...)
+		}
+		return invalid(errs...)
+	}
+
+	key := dedupeKey(worker, opts.dedupeKey)
+	if first, ok := seen[key]; ok {
+		result.Action, result.Reason = importSkip, fmt.Sprintf("duplicate of row %d", first)
+		return result, nil
+	}
+	seen[key] = row.Number
+
+	existing := matches[key]
+	if len(existing) == 0 {
+		if err := c.validate.Struct(worker); err != nil {
+			return invalid(apierror.From(err).Details...)
+		}
+		result.Action = importCreate
+		return result, &plannedWorker{worker: worker}
+	}
+
+	result.ID = existing[0].ID
+	if !opts.update {
+		result.Action, result.Reason = importSkip, "matches an existing worker"
+		return result, nil
+	}
+	if len(existing) > 1 {
+		result.ID = 0
+		return invalid(apierror.FieldError{Field: "dedupe_key", Code: "ambiguous", Message: fmt.Sprintf("matches %d existing workers", len(existing))})
+	}
+
+	// Cells of the row replace the current values of the worker
+	updated := existing[0]
+	updated.Projects = nil
+	setImportedFields(&updated, values)
+	if err := c.validate.Struct(updated); err != nil {
+		return invalid(apierror.From(err).Details...)
+	}
+	changes, err := repository.DiffFields(existing[0], updated, workerImportFields)
+	if err != nil || len(changes) == 0 {
+		result.Action, result.Reason = importSkip, "unchanged"
+		return result, nil
+	}
+	changed := make([]string, len(changes))
+	for i, change := range changes {
+		changed[i] = change.Field
+	}
+	result.Action = importUpdate
+	return result, &plannedWorker{worker: &updated, changed: changed}
+}
+
+// setImportedFields sets the fields of a worker from the cells of a row,
+// whose numbers are known to be valid
+func setImportedFields(worker *model.Worker, values map[string]string) {
+	for field, value := range values {
+		switch field {
+		case "name":
+			worker.Name = value
+		case "position":
+			worker.Position = value
+		case "age":
+			worker.Age, _ = parseWholeNumber(value)
+		case "salary":
+			worker.Salary, _ = parseWholeNumber(value)
+		}
+	}
+}
+
+// dedupeKey returns the normalized values of the key fields of a worker
+func dedupeKey(worker *model.Worker, fields []string) string {
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		switch field {
+		case "name":
+			values[i] = worker.Name
+		case "position":
+			values[i] = worker.Position
+		case "age":
+			values[i] = strconv.Itoa(worker.Age)
+		case "salary":
+			values[i] = strconv.Itoa(worker.Salary)
+		}
+		values[i] = strings.ToLower(strings.Join(strings.Fields(values[i]), " "))
+	}
+	return strings.Join(values, "\x00")
+}
+
+// parseWholeNumber parses a number cell holding a whole number, which
+// spreadsheet programs may write with a fractional part, e.g. "4200.0"
+func parseWholeNumber(value string) (int, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number != math.Trunc(number) || math.Abs(number) > math.MaxInt32 {
+		return 0, strconv.ErrSyntax
+	}
+	return int(number), nil
+}
+
+// normalizeHeader turns a column header into the worker field it names by
+// default, e.g. "Salary " into "salary"
+func normalizeHeader(title string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(title))
+}
+
+// isBlankRow reports whether every cell of a row is blank
+func isBlankRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/labstack/echo/v4"
+)
+
+// importWorkers uploads a spreadsheet to the import endpoint as the user
+func importWorkers(t *testing.T, e *echo.Echo, userID uint, query, filename, content string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte(content))
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/workers/import"+query, &body)
+	req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+	req.Header.Set(testUserHeader, strconv.FormatUint(uint64(userID), 10))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// importReport decodes the report of an import
+func importReport(t *testing.T, rec *httptest.ResponseRecorder) workerImportReport {
+	t.Helper()
+	expectStatus(t, rec, http.StatusOK)
+	var report workerImportReport
+	decode(t, rec, &report)
+	return report
+}
+
+const importCSV = "Name;Age;Position;Salary;Notes\n" +
+	"Ana Pop;30;Mason;3000;first\n" +
+	";;;;\n" +
+	"Ion Rus;forty;Welder;2500;\n" +
+	"ana  pop;31;Mason;3100;again\n" +
+	"Eva Lup;35;Mason;4200.0;\n"
+
+func TestImportWorkers(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+
+	report := importReport(t, importWorkers(t, e, owner, "?dry_run=true", "workers.csv", importCSV))
+	if report.Created != 2 || report.Skipped != 1 || report.Invalid != 1 || len(report.Results) != 4 {
+		t.Fatalf("expected 2 rows created, 1 skipped and 1 invalid, got %+v", report)
+	}
+	// The blank row is left out and rows keep their number in the file
+	invalid := report.Results[1]
+	if invalid.Row != 4 || invalid.Action != importInvalid || len(invalid.Errors) != 1 || invalid.Errors[0].Field != "age" {
+		t.Fatalf("expected row 4 to be invalid on its age, got %+v", invalid)
+	}
+	if skipped := report.Results[2]; skipped.Row != 5 || skipped.Reason != "duplicate of row 2" {
+		t.Fatalf("expected row 5 to duplicate row 2, got %+v", skipped)
+	}
+	rec := do(t, e, owner, http.MethodGet, "/api/workers", "")
+	expectStatus(t, rec, http.StatusOK)
+	if n := total(t, rec); n != 0 {
+		t.Fatalf("expected the dry run to write nothing, got %d workers", n)
+	}
+
+	report = importReport(t, importWorkers(t, e, owner, "", "workers.csv", importCSV))
+	if report.Created != 2 || report.Results[0].ID == 0 {
+		t.Fatalf("expected 2 workers created, got %+v", report)
+	}
+
+	// Importing again matches the workers now existing
+	report = importReport(t, importWorkers(t, e, owner, "?on_duplicate=update", "workers.csv",
+		"name,salary\nAna Pop,3500\nEva Lup,4200\n"))
+	if report.Updated != 1 || report.Skipped != 1 || report.Results[1].Reason != "unchanged" {
+		t.Fatalf("expected 1 worker updated and 1 unchanged, got %+v", report)
+	}
+	rec = do(t, e, owner, http.MethodGet, "/api/workers/"+strconv.FormatUint(uint64(report.Results[0].ID), 10), "")
+	expectStatus(t, rec, http.StatusOK)
+	var worker struct {
+		Age    int `json:"age"`
+		Salary int `json:"salary"`
+	}
+	decode(t, rec, &worker)
+	if worker.Salary != 3500 || worker.Age != 30 {
+		t.Fatalf("expected only the salary to be updated, got %+v", worker)
+	}
+}
+
+func TestImportWorkersErrors(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+	tests := []struct {
+		name     string
+		query    string
+		filename string
+		content  string
+		field    string
+	}{
+		{name: "unknown format", filename: "workers.txt", content: "name\nAna Pop\n", field: "file"},
+		{name: "unreadable workbook", filename: "workers.xlsx", content: "name\nAna Pop\n", field: "file"},
+		{name: "dedupe key not mapped", query: "?dedupe_key=age", filename: "workers.csv", content: "name\nAna Pop\n", field: "dedupe_key"},
+		{name: "too many blank rows", filename: "workers.csv",
+			content: "name\n" + strings.Repeat(",\n", maxImportBlankRows+1) + "Ana Pop\n", field: "file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := importWorkers(t, e, owner, tt.query, tt.filename, tt.content)
+			expectStatus(t, rec, http.StatusBadRequest)
+			var apiErr apierror.Error
+			decode(t, rec, &apiErr)
+			if len(apiErr.Details) == 0 || apiErr.Details[0].Field != tt.field {
+				t.Fatalf("expected an error on %s, got %+v", tt.field, apiErr)
+			}
+		})
+	}
+
+	expectStatus(t, importWorkers(t, e, owner, "?on_duplicate=merge", "workers.csv", "name\n"), http.StatusBadRequest)
+	rec := do(t, e, owner, http.MethodGet, "/api/workers", "")
+	expectStatus(t, rec, http.StatusOK)
+	if n := total(t, rec); n != 0 {
+		t.Fatalf("expected the failed imports to write nothing, got %d workers", n)
+	}
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/middleware"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// transferRequest is the body of POST /api/workers/:id/transfer
+type transferRequest struct {
+	SourceProjectID uint `json:"source_project_id" validate:"required"`
+	TargetProjectID uint `json:"target_project_id" validate:"required"`
+	// EffectiveDate is the first day on the target project
+	EffectiveDate time.Time `json:"effective_date" validate:"required"`
+}
+
+// transferResult holds both assignments of a transfer
+type transferResult struct {
+	Source model.WorkerProject `json:"source"`
+	Target model.WorkerProject `json:"target"`
+}
+
+// TransferWorker handles POST /api/workers/:id/transfer, moving a worker from
+// one project to another in one transaction. The source assignment is kept,
+// ending the day before the effective date, and the target assignment starts
+// on it with the role, allocation, day rate and notes of the source. As when
+// assigning, conflicts are refused unless override=true.
+func (c *WorkerController) TransferWorker(ctx echo.Context) error {
+	// Get user ID from context
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	workerId, err := pathID(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	var request transferRequest
+	if err := ctx.Bind(&request); err != nil {
+		return err
+	}
+	if err := c.validate.Struct(request); err != nil {
+		return err
+	}
+	if request.TargetProjectID == request.SourceProjectID {
+		return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid transfer").
+			WithDetails(apierror.FieldError{Field: "target_project_id", Code: "nefield", Message: "must differ from source_project_id"})
+	}
+
+	override, err := parseOverride(ctx)
+	if err != nil {
+		return err
+	}
+
+	effective := dateOf(request.EffectiveDate)
+	lastDay := effective.AddDate(0, 0, -1)
+
+	var result transferResult
+	var source, target *model.Project
+	reqCtx := ctx.Request().Context()
+	err = c.uow.Do(reqCtx, func(repos *repository.Repositories) error {
+		assignment, err := repos.Projects.GetAssignment(reqCtx, request.SourceProjectID, workerId, userID)
+		if err != nil {
+			return notFound(err, "Worker is not assigned to the source project")
+		}
+		if source, err = repos.Projects.GetByID(reqCtx, request.SourceProjectID, userID); err != nil {
+			return err
+		}
+		if target, err = repos.Projects.GetByID(reqCtx, request.TargetProjectID, userID); err != nil {
+			return notFound(err, "Target project not found")
+		}
+
+		// The worker must spend at least a day on the source project, and
+		// still be on it the day before the transfer
+		start, end := assignment.Period(*source)
+		if lastDay.Before(dateOf(start)) {
+			return invalidEffectiveDate(fmt.Sprintf("must be after %s, the first day on the source project", start.Format(time.DateOnly)))
+		}
+		if end != nil && lastDay.After(dateOf(*end)) {
+			return invalidEffectiveDate(fmt.Sprintf("must not be after %s, the day following the end of the source assignment", end.AddDate(0, 0, 1).Format(time.DateOnly)))
+		}
+
+		assignment.EndDate = &lastDay
+		if err := repos.Projects.UpdateAssignment(reqCtx, assignment); err != nil {
+			return err
+		}
+
+		next := model.WorkerProject{
+			WorkerID:  workerId,
+			ProjectID: request.TargetProjectID,
+			UserID:    userID,
+			AssignmentDetails: model.AssignmentDetails{
+				Role:              assignment.Role,
+				StartDate:         &effective,
+				AllocationPercent: assignment.AllocationPercent,
+				DayRate:           assignment.DayRate,
+				Notes:             assignment.Notes,
+			},
+		}
+		if err := repos.Projects.AddWorker(reqCtx, &next); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return apierror.Conflict("Worker is already assigned to the target project on the effective date").Wrap(err)
+			}
+			return err
+		}
+		if !override {
+			if err := checkConflicts(reqCtx, repos.Projects, next); err != nil {
+				return err
+			}
+		}
+
+		result = transferResult{Source: *assignment, Target: next}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx.Set(middleware.LogEntryKey, middleware.LogEntry{
+		Type: model.LogTypeTransfer,
+		Description: fmt.Sprintf("%s %s with ID: %d from %s %d (%s) to %s %d (%s) on %s",
+			model.LogTypeTransfer, model.EntityTypeWorker, workerId,
+			model.EntityTypeProject, source.ID, source.Name,
+			model.EntityTypeProject, target.ID, target.Name, effective.Format(time.DateOnly)),
+	})
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// invalidEffectiveDate reports a transfer effective date outside the source assignment
+func invalidEffectiveDate(message string) error {
+	return apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid transfer").
+		WithDetails(apierror.FieldError{Field: "effective_date", Code: "source_period", Message: message})
+}
+
+// dateOf returns the date of a time, at midnight UTC
+func dateOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
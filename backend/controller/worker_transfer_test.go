@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/labstack/echo/v4"
+)
+
+// createProject creates a project of the user starting on 2026-03-01 and
+// returns its ID
+func createProject(t *testing.T, e *echo.Echo, userID uint, name string) uint {
+	t.Helper()
+	rec := do(t, e, userID, http.MethodPost, "/api/projects", projectBody(name))
+	expectStatus(t, rec, http.StatusCreated)
+	var project struct {
+		ID uint `json:"id"`
+	}
+	decode(t, rec, &project)
+	return project.ID
+}
+
+// assign assigns a worker to a project with the details of the JSON members
+func assign(t *testing.T, e *echo.Echo, userID, projectID, workerID uint, details string, override bool) {
+	t.Helper()
+	path := "/api/projects/" + strconv.FormatUint(uint64(projectID), 10) + "/workers"
+	if override {
+		path += "?override=true"
+	}
+	body := `{"workerId":` + strconv.FormatUint(uint64(workerID), 10)
+	if details != "" {
+		body += "," + details
+	}
+	expectStatus(t, do(t, e, userID, http.MethodPost, path, body+"}"), http.StatusOK)
+}
+
+// transfer moves a worker between projects on the effective date
+func transfer(t *testing.T, e *echo.Echo, userID, workerID, sourceID, targetID uint, effective, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	body := `{"source_project_id":` + strconv.FormatUint(uint64(sourceID), 10) +
+		`,"target_project_id":` + strconv.FormatUint(uint64(targetID), 10) +
+		`,"effective_date":"` + effective + `T00:00:00Z"}`
+	return do(t, e, userID, http.MethodPost,
+		"/api/workers/"+strconv.FormatUint(uint64(workerID), 10)+"/transfer"+query, body)
+}
+
+// transferredAssignment is an assignment of a transfer response
+type transferredAssignment struct {
+	ProjectID         uint    `json:"project_id"`
+	Role              string  `json:"role"`
+	StartDate         *string `json:"start_date"`
+	EndDate           *string `json:"end_date"`
+	AllocationPercent int     `json:"allocation_percent"`
+}
+
+// projectWorkers counts the current workers of a project
+func projectWorkers(t *testing.T, e *echo.Echo, userID, projectID uint) int {
+	t.Helper()
+	rec := do(t, e, userID, http.MethodGet, "/api/projects/"+strconv.FormatUint(uint64(projectID), 10), "")
+	expectStatus(t, rec, http.StatusOK)
+	var project struct {
+		Workers []struct{} `json:"workers"`
+	}
+	decode(t, rec, &project)
+	return len(project.Workers)
+}
+
+func TestTransferWorker(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+	workerID := createWorker(t, e, owner, "Ana Pop")
+	north, depot := createProject(t, e, owner, "North"), createProject(t, e, owner, "Depot")
+	assign(t, e, owner, north, workerID, `"role":"Foreman","allocation_percent":80`, false)
+
+	rec := transfer(t, e, owner, workerID, north, depot, "2026-04-01", "")
+	expectStatus(t, rec, http.StatusOK)
+	var result struct {
+		Source transferredAssignment `json:"source"`
+		Target transferredAssignment `json:"target"`
+	}
+	decode(t, rec, &result)
+	if result.Source.ProjectID != north || result.Source.EndDate == nil || (*result.Source.EndDate)[:10] != "2026-03-31" {
+		t.Fatalf("expected the source assignment to end on 2026-03-31, got %+v", result.Source)
+	}
+	target := result.Target
+	if target.ProjectID != depot || target.StartDate == nil || (*target.StartDate)[:10] != "2026-04-01" ||
+		target.EndDate != nil || target.Role != "Foreman" || target.AllocationPercent != 80 {
+		t.Fatalf("expected the target assignment to start on 2026-04-01 as foreman at 80%%, got %+v", target)
+	}
+}
+
+func TestTransferWorkerErrors(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+	workerID := createWorker(t, e, owner, "Ana Pop")
+	north, depot := createProject(t, e, owner, "North"), createProject(t, e, owner, "Depot")
+
+	// The worker is not on the source project yet
+	expectStatus(t, transfer(t, e, owner, workerID, north, depot, "2026-04-01", ""), http.StatusNotFound)
+
+	assign(t, e, owner, north, workerID, "", false)
+	tests := []struct {
+		name      string
+		targetID  uint
+		effective string
+		field     string
+	}{
+		{name: "same project", targetID: north, effective: "2026-04-01", field: "target_project_id"},
+		{name: "first day", targetID: depot, effective: "2026-03-01", field: "effective_date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := transfer(t, e, owner, workerID, north, tt.targetID, tt.effective, "")
+			expectStatus(t, rec, http.StatusBadRequest)
+			var apiErr apierror.Error
+			decode(t, rec, &apiErr)
+			if apiErr.Code != apierror.CodeValidationFailed || len(apiErr.Details) != 1 || apiErr.Details[0].Field != tt.field {
+				t.Fatalf("expected a validation error on %s, got %+v", tt.field, apiErr)
+			}
+		})
+	}
+
+	// Other users cannot transfer the worker
+	expectStatus(t, transfer(t, e, 2, workerID, north, depot, "2026-04-01", ""), http.StatusNotFound)
+}
+
+func TestTransferWorkerConflictsRollBack(t *testing.T) {
+	e := newTestServer()
+	const owner = 1
+	workerID := createWorker(t, e, owner, "Ana Pop")
+	north, depot, yard := createProject(t, e, owner, "North"), createProject(t, e, owner, "Depot"),
+		createProject(t, e, owner, "Yard")
+	assign(t, e, owner, north, workerID, "", false)
+	assign(t, e, owner, yard, workerID, `"allocation_percent":50,"start_date":"2026-05-01T00:00:00Z"`, true)
+
+	// From May on, the full-time target and the yard add up to 150%
+	rec := transfer(t, e, owner, workerID, north, depot, "2026-04-01", "")
+	expectStatus(t, rec, http.StatusConflict)
+	var apiErr apierror.Error
+	decode(t, rec, &apiErr)
+	if apiErr.Code != apierror.CodeAssignmentConflict {
+		t.Fatalf("expected an assignment conflict, got %+v", apiErr)
+	}
+	if n := projectWorkers(t, e, owner, depot); n != 0 {
+		t.Fatalf("expected the transfer to be rolled back, got %d workers on the target", n)
+	}
+
+	expectStatus(t, transfer(t, e, owner, workerID, north, depot, "2026-04-01", "?override=true"), http.StatusOK)
+	if n := projectWorkers(t, e, owner, depot); n != 1 {
+		t.Fatalf("expected the worker on the target, got %d workers", n)
+	}
+}
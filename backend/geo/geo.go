@@ -0,0 +1,182 @@
+// Package geo implements the geometry behind the location queries of
+// projects: great-circle distances and bounding boxes on a spherical earth.
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EarthRadiusKm is the mean radius of the earth
+const EarthRadiusKm = 6371.0088
+
+// MaxDistanceKm is half the circumference of the earth, the largest possible
+// distance between two points
+const MaxDistanceKm = math.Pi * EarthRadiusKm
+
+// Point is a position in decimal degrees
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Validate checks that the point lies within the coordinate ranges
+func (p Point) Validate() error {
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("latitude %v is out of range [-90, 90]", p.Lat)
+	}
+	if math.IsNaN(p.Lng) || p.Lng < -180 || p.Lng > 180 {
+		return fmt.Errorf("longitude %v is out of range [-180, 180]", p.Lng)
+	}
+	return nil
+}
+
+// DistanceKm returns the great-circle distance between two points using the
+// haversine formula
+func DistanceKm(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat := lat2 - lat1
+	dLng := radians(b.Lng - a.Lng)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BoundingBox is an area between two parallels and two meridians. A box with
+// MinLng > MaxLng crosses the antimeridian.
+type BoundingBox struct {
+	MinLng float64 `json:"min_lng"`
+	MinLat float64 `json:"min_lat"`
+	MaxLng float64 `json:"max_lng"`
+	MaxLat float64 `json:"max_lat"`
+}
+
+// Validate checks the coordinate ranges of the box
+func (b BoundingBox) Validate() error {
+	if err := (Point{Lat: b.MinLat, Lng: b.MinLng}).Validate(); err != nil {
+		return err
+	}
+	if err := (Point{Lat: b.MaxLat, Lng: b.MaxLng}).Validate(); err != nil {
+		return err
+	}
+	if b.MinLat > b.MaxLat {
+		return fmt.Errorf("minimum latitude %v is above maximum latitude %v", b.MinLat, b.MaxLat)
+	}
+	return nil
+}
+
+// CrossesAntimeridian reports whether the box wraps around longitude 180
+func (b BoundingBox) CrossesAntimeridian() bool {
+	return b.MinLng > b.MaxLng
+}
+
+// Contains reports whether the point lies inside the box, edges included
+func (b BoundingBox) Contains(p Point) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.CrossesAntimeridian() {
+		return p.Lng >= b.MinLng || p.Lng <= b.MaxLng
+	}
+	return p.Lng >= b.MinLng && p.Lng <= b.MaxLng
+}
+
+// Center returns the middle of the box
+func (b BoundingBox) Center() Point {
+	maxLng := b.MaxLng
+	if b.CrossesAntimeridian() {
+		maxLng += 360
+	}
+	lng := (b.MinLng + maxLng) / 2
+	if lng > 180 {
+		lng -= 360
+	}
+	return Point{Lat: (b.MinLat + b.MaxLat) / 2, Lng: lng}
+}
+
+// BoxAround returns the smallest bounding box holding every point within
+// radiusKm of center. It spans all longitudes when the circle reaches a pole.
+func BoxAround(center Point, radiusKm float64) BoundingBox {
+	angle := radiusKm / EarthRadiusKm
+	dLat := degrees(angle)
+	box := BoundingBox{
+		MinLat: math.Max(center.Lat-dLat, -90),
+		MaxLat: math.Min(center.Lat+dLat, 90),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+	if box.MinLat == -90 || box.MaxLat == 90 || angle >= math.Pi/2 {
+		return box
+	}
+
+	// Widest longitude offset of the circle, reached north of the center's parallel
+	dLng := degrees(math.Asin(math.Sin(angle) / math.Cos(radians(center.Lat))))
+	box.MinLng = center.Lng - dLng
+	box.MaxLng = center.Lng + dLng
+	if box.MinLng < -180 {
+		box.MinLng += 360
+	}
+	if box.MaxLng > 180 {
+		box.MaxLng -= 360
+	}
+	return box
+}
+
+// Intersect returns the part of b also inside other, assuming neither crosses
+// the antimeridian, and whether they overlap at all
+func (b BoundingBox) Intersect(other BoundingBox) (BoundingBox, bool) {
+	result := BoundingBox{
+		MinLng: math.Max(b.MinLng, other.MinLng),
+		MinLat: math.Max(b.MinLat, other.MinLat),
+		MaxLng: math.Min(b.MaxLng, other.MaxLng),
+		MaxLat: math.Min(b.MaxLat, other.MaxLat),
+	}
+	return result, result.MinLng <= result.MaxLng && result.MinLat <= result.MaxLat
+}
+
+// ParsePoint parses "lat,lng"
+func ParsePoint(raw string) (Point, error) {
+	values, err := parseFloats(raw, 2)
+	if err != nil {
+		return Point{}, err
+	}
+	point := Point{Lat: values[0], Lng: values[1]}
+	return point, point.Validate()
+}
+
+// ParseBoundingBox parses "minLng,minLat,maxLng,maxLat", the GeoJSON order
+func ParseBoundingBox(raw string) (BoundingBox, error) {
+	values, err := parseFloats(raw, 4)
+	if err != nil {
+		return BoundingBox{}, err
+	}
+	box := BoundingBox{MinLng: values[0], MinLat: values[1], MaxLng: values[2], MaxLat: values[3]}
+	return box, box.Validate()
+}
+
+// parseFloats parses a comma separated list of exactly n numbers
+func parseFloats(raw string, n int) ([]float64, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected %d comma separated numbers", n)
+	}
+	values := make([]float64, n)
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("%q is not a number", part)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
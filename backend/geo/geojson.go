@@ -0,0 +1,79 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// GeoJSON object and geometry types (RFC 7946)
+const (
+	TypeFeatureCollection = "FeatureCollection"
+	TypeFeature           = "Feature"
+	TypePoint             = "Point"
+)
+
+// FeatureCollection is a GeoJSON feature collection whose features carry
+// properties of type P
+type FeatureCollection[P any] struct {
+	Type     string       `json:"type"`
+	Features []Feature[P] `json:"features"`
+}
+
+// Feature is a GeoJSON feature with properties of type P
+type Feature[P any] struct {
+	Type       string    `json:"type"`
+	ID         any       `json:"id,omitempty"`
+	Geometry   *Geometry `json:"geometry"`
+	Properties P         `json:"properties"`
+}
+
+// Geometry is a GeoJSON geometry. Its coordinates are decoded according to
+// its type by the accessor methods.
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// NewFeatureCollection wraps features in a collection, never encoding a null
+// feature list
+func NewFeatureCollection[P any](features []Feature[P]) FeatureCollection[P] {
+	if features == nil {
+		features = []Feature[P]{}
+	}
+	return FeatureCollection[P]{Type: TypeFeatureCollection, Features: features}
+}
+
+// PointGeometry returns the GeoJSON geometry of a point, in [lng, lat] order
+func PointGeometry(p Point) *Geometry {
+	coordinates, _ := json.Marshal(position(p))
+	return &Geometry{Type: TypePoint, Coordinates: coordinates}
+}
+
+// Point decodes a Point geometry. An altitude, if present, is ignored.
+func (g *Geometry) Point() (Point, error) {
+	if g == nil {
+		return Point{}, fmt.Errorf("geometry is required")
+	}
+	if g.Type != TypePoint {
+		return Point{}, fmt.Errorf("geometry must be a Point, not %q", g.Type)
+	}
+	var coordinates []float64
+	if err := json.Unmarshal(g.Coordinates, &coordinates); err != nil {
+		return Point{}, fmt.Errorf("coordinates must be an array of numbers")
+	}
+	return parsePosition(coordinates)
+}
+
+// position returns the GeoJSON position of a point
+func position(p Point) []float64 {
+	return []float64{p.Lng, p.Lat}
+}
+
+// parsePosition reads a GeoJSON position
+func parsePosition(coordinates []float64) (Point, error) {
+	if len(coordinates) < 2 || len(coordinates) > 3 {
+		return Point{}, fmt.Errorf("a position must have 2 or 3 coordinates")
+	}
+	point := Point{Lat: coordinates[1], Lng: coordinates[0]}
+	return point, point.Validate()
+}
@@ -0,0 +1,61 @@
+package geo
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// KMLNamespace is the namespace of KML 2.2 documents
+const KMLNamespace = "http://www.opengis.net/kml/2.2"
+
+// Placemark is a named KML feature at a point
+type Placemark struct {
+	ID          string    `xml:"id,attr,omitempty"`
+	Name        string    `xml:"name"`
+	Description string    `xml:"description,omitempty"`
+	Data        []KMLData `xml:"ExtendedData>Data,omitempty"`
+	Point       KMLPoint  `xml:"Point"`
+}
+
+// KMLData is a named value of a placemark's extended data
+type KMLData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// KMLPoint holds the "lng,lat" coordinates of a placemark
+type KMLPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// NewKMLPoint formats a point as KML coordinates
+func NewKMLPoint(p Point) KMLPoint {
+	return KMLPoint{Coordinates: formatCoordinates(p)}
+}
+
+type kmlDocument struct {
+	XMLName    xml.Name    `xml:"kml"`
+	Namespace  string      `xml:"xmlns,attr"`
+	Name       string      `xml:"Document>name"`
+	Placemarks []Placemark `xml:"Document>Placemark"`
+}
+
+// WriteKML writes a KML document holding the placemarks
+func WriteKML(w io.Writer, name string, placemarks []Placemark) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(kmlDocument{Namespace: KMLNamespace, Name: name, Placemarks: placemarks}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// formatCoordinates formats a point as a KML "lng,lat" tuple
+func formatCoordinates(p Point) string {
+	return strconv.FormatFloat(p.Lng, 'f', -1, 64) + "," + strconv.FormatFloat(p.Lat, 'f', -1, 64)
+}
@@ -0,0 +1,235 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxRingPoints bounds the number of points of a polygon ring
+const MaxRingPoints = 1000
+
+// A ring is the outline of a polygon: at least three distinct points with the
+// first point repeated at the end, as in GeoJSON. Rings are meant for sites a
+// few kilometers wide; containment and edge crossings are computed on the
+// plane of longitudes and latitudes, which is accurate at that scale.
+
+// ValidateRing checks that a ring is closed, has valid coordinates and does
+// not cross itself
+func ValidateRing(ring []Point) error {
+	if len(ring) < 4 {
+		return fmt.Errorf("a ring needs at least 4 points, the first one repeated at the end")
+	}
+	if len(ring) > MaxRingPoints {
+		return fmt.Errorf("a ring has at most %d points", MaxRingPoints)
+	}
+	if ring[0] != ring[len(ring)-1] {
+		return fmt.Errorf("the ring is not closed: the last point must repeat the first")
+	}
+	for i, point := range ring {
+		if err := point.Validate(); err != nil {
+			return fmt.Errorf("point %d: %w", i, err)
+		}
+		if i == 0 {
+			continue
+		}
+		if point == ring[i-1] {
+			return fmt.Errorf("point %d repeats the previous point", i)
+		}
+		if math.Abs(point.Lng-ring[i-1].Lng) > 180 {
+			return fmt.Errorf("edge %d crosses the antimeridian", i-1)
+		}
+	}
+
+	// Every pair of edges may only meet at the point shared by neighbours
+	edges := len(ring) - 1
+	for i := 0; i < edges; i++ {
+		for j := i + 1; j < edges; j++ {
+			adjacent := j == i+1 || (i == 0 && j == edges-1)
+			if adjacent {
+				// Neighbours share a point but must not fold back onto each other
+				folded := collinearOverlap(ring[i], ring[i+1], ring[i+2])
+				if j != i+1 {
+					folded = collinearOverlap(ring[j], ring[0], ring[1])
+				}
+				if folded {
+					return fmt.Errorf("edges %d and %d overlap", i, j)
+				}
+				continue
+			}
+			if segmentsIntersect(ring[i], ring[i+1], ring[j], ring[j+1]) {
+				return fmt.Errorf("edges %d and %d cross", i, j)
+			}
+		}
+	}
+
+	if RingAreaKm2(ring) == 0 {
+		return fmt.Errorf("the ring has no area")
+	}
+	return nil
+}
+
+// RingContains reports whether a point lies inside a ring or on its outline
+func RingContains(ring []Point, p Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if onSegment(a, b, p) {
+			return true
+		}
+		// Count the edges crossed by a ray going east from the point
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) {
+			lng := a.Lng + (p.Lat-a.Lat)*(b.Lng-a.Lng)/(b.Lat-a.Lat)
+			if p.Lng < lng {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// RingAreaKm2 returns the area enclosed by a ring on the sphere, in square
+// kilometers
+func RingAreaKm2(ring []Point) float64 {
+	// Spherical excess, summed over the edges (Chamberlain & Duquette)
+	sum := 0.0
+	for i := 0; i+1 < len(ring); i++ {
+		a, b := ring[i], ring[i+1]
+		sum += radians(b.Lng-a.Lng) * (2 + math.Sin(radians(a.Lat)) + math.Sin(radians(b.Lat)))
+	}
+	return math.Abs(sum * EarthRadiusKm * EarthRadiusKm / 2)
+}
+
+// DistanceToRingKm returns the distance from a point to the area of a ring:
+// zero inside it, otherwise the distance to the nearest edge
+func DistanceToRingKm(p Point, ring []Point) float64 {
+	if RingContains(ring, p) {
+		return 0
+	}
+	nearest := math.Inf(1)
+	for i := 0; i+1 < len(ring); i++ {
+		nearest = math.Min(nearest, distanceToSegmentKm(p, ring[i], ring[i+1]))
+	}
+	return nearest
+}
+
+// RingIntersectsBox reports whether a ring and a bounding box share any point
+func RingIntersectsBox(ring []Point, box BoundingBox) bool {
+	for _, point := range ring {
+		if box.Contains(point) {
+			return true
+		}
+	}
+	if box.CrossesAntimeridian() {
+		// Rings do not cross the antimeridian, so they meet such a box at
+		// a vertex or not at all unless they enclose one of its halves
+		west := BoundingBox{MinLng: box.MinLng, MinLat: box.MinLat, MaxLng: 180, MaxLat: box.MaxLat}
+		east := BoundingBox{MinLng: -180, MinLat: box.MinLat, MaxLng: box.MaxLng, MaxLat: box.MaxLat}
+		return RingIntersectsBox(ring, west) || RingIntersectsBox(ring, east)
+	}
+
+	// The box may lie inside the ring, or their edges may cross
+	corners := []Point{
+		{Lat: box.MinLat, Lng: box.MinLng},
+		{Lat: box.MinLat, Lng: box.MaxLng},
+		{Lat: box.MaxLat, Lng: box.MaxLng},
+		{Lat: box.MaxLat, Lng: box.MinLng},
+	}
+	for _, corner := range corners {
+		if RingContains(ring, corner) {
+			return true
+		}
+	}
+	for i := 0; i+1 < len(ring); i++ {
+		for k := range corners {
+			if segmentsIntersect(ring[i], ring[i+1], corners[k], corners[(k+1)%len(corners)]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Expand grows a box by a distance on every side, up to the poles
+func (b BoundingBox) Expand(km float64) BoundingBox {
+	dLat := degrees(km / EarthRadiusKm)
+	expanded := BoundingBox{
+		MinLat: math.Max(b.MinLat-dLat, -90),
+		MaxLat: math.Min(b.MaxLat+dLat, 90),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+	if expanded.MinLat == -90 || expanded.MaxLat == 90 || (b.MinLng == -180 && b.MaxLng == 180) {
+		return expanded
+	}
+
+	// Longitude degrees are shortest at the latitude farthest from the equator
+	widest := math.Max(math.Abs(expanded.MinLat), math.Abs(expanded.MaxLat))
+	dLng := degrees(km / (EarthRadiusKm * math.Cos(radians(widest))))
+	width := b.MaxLng - b.MinLng
+	if b.CrossesAntimeridian() {
+		width += 360
+	}
+	if width+2*dLng >= 360 {
+		return expanded
+	}
+
+	expanded.MinLng = b.MinLng - dLng
+	expanded.MaxLng = b.MaxLng + dLng
+	if expanded.MinLng < -180 {
+		expanded.MinLng += 360
+	}
+	if expanded.MaxLng > 180 {
+		expanded.MaxLng -= 360
+	}
+	return expanded
+}
+
+// distanceToSegmentKm returns the distance from a point to a segment, on an
+// equirectangular projection centered on the point
+func distanceToSegmentKm(p, a, b Point) float64 {
+	scale := math.Cos(radians(p.Lat))
+	project := func(q Point) (float64, float64) {
+		return radians(q.Lng-p.Lng) * scale * EarthRadiusKm, radians(q.Lat-p.Lat) * EarthRadiusKm
+	}
+	ax, ay := project(a)
+	bx, by := project(b)
+
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/length))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// cross returns the z component of (b - a) x (c - a)
+func cross(a, b, c Point) float64 {
+	return (b.Lng-a.Lng)*(c.Lat-a.Lat) - (b.Lat-a.Lat)*(c.Lng-a.Lng)
+}
+
+// onSegment reports whether p lies on the segment from a to b
+func onSegment(a, b, p Point) bool {
+	return cross(a, b, p) == 0 &&
+		p.Lng >= math.Min(a.Lng, b.Lng) && p.Lng <= math.Max(a.Lng, b.Lng) &&
+		p.Lat >= math.Min(a.Lat, b.Lat) && p.Lat <= math.Max(a.Lat, b.Lat)
+}
+
+// segmentsIntersect reports whether the segments ab and cd share any point
+func segmentsIntersect(a, b, c, d Point) bool {
+	d1, d2 := cross(c, d, a), cross(c, d, b)
+	d3, d4 := cross(a, b, c), cross(a, b, d)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return onSegment(c, d, a) || onSegment(c, d, b) || onSegment(a, b, c) || onSegment(a, b, d)
+}
+
+// collinearOverlap reports whether the consecutive edges ab and bc fold back
+// onto each other
+func collinearOverlap(a, b, c Point) bool {
+	if cross(a, b, c) != 0 {
+		return false
+	}
+	// Collinear: they overlap unless b lies between a and c
+	return (b.Lng-a.Lng)*(c.Lng-b.Lng)+(b.Lat-a.Lat)*(c.Lat-b.Lat) < 0
+}
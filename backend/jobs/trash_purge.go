@@ -0,0 +1,48 @@
+// Package jobs runs the background maintenance tasks of the server.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+)
+
+// StartTrashPurge permanently deletes the workers and projects trashed for
+// longer than the retention period, once at startup and then at every purge
+// interval, until the context is cancelled. It does nothing when the
+// retention is zero.
+func StartTrashPurge(ctx context.Context, repo repository.TrashRepository, cfg config.TrashConfig) {
+	if cfg.Retention <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.PurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			purgeExpired(ctx, repo, cfg.Retention)
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// purgeExpired runs one purge, logging its outcome
+func purgeExpired(ctx context.Context, repo repository.TrashRepository, retention time.Duration) {
+	purged, err := repo.PurgeDeletedBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		log.Printf("Failed to purge expired trash: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d expired trash items", purged)
+	}
+}
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+)
+
+// purgeRecorder records the cutoffs of the purges it is asked for
+type purgeRecorder struct {
+	repository.TrashRepository
+	cutoffs chan time.Time
+}
+
+func (r *purgeRecorder) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.cutoffs <- cutoff
+	return 0, nil
+}
+
+func TestStartTrashPurge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := &purgeRecorder{cutoffs: make(chan time.Time, 10)}
+
+	started := time.Now()
+	StartTrashPurge(ctx, repo, config.TrashConfig{Retention: 30 * 24 * time.Hour, PurgeInterval: 10 * time.Millisecond})
+
+	for run := 0; run < 2; run++ {
+		select {
+		case cutoff := <-repo.cutoffs:
+			if want := started.Add(-30 * 24 * time.Hour); cutoff.Before(want) || cutoff.After(time.Now().Add(-30*24*time.Hour)) {
+				t.Fatalf("expected a cutoff 30 days back, got %s", cutoff)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected purge run %d", run+1)
+		}
+	}
+}
+
+func TestStartTrashPurgeKeepsItemsWithoutRetention(t *testing.T) {
+	repo := &purgeRecorder{cutoffs: make(chan time.Time, 1)}
+	StartTrashPurge(context.Background(), repo, config.TrashConfig{Retention: 0, PurgeInterval: time.Millisecond})
+
+	select {
+	case cutoff := <-repo.cutoffs:
+		t.Fatalf("expected no purge without a retention, got one before %s", cutoff)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
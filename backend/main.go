@@ -1,56 +1,89 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"log"
 	"net/http"
 	"os"
-	"strings"
+	"strconv"
 
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/auth"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/cache"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/controller"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/jobs"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/middleware"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/spreadsheet"
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
 )
 
 func main() {
+	// Handle the "config" subcommand
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
+
+	// Load and validate the configuration
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Initialize the database
-	config.InitDB()
+	db := config.InitDB(cfg.Database)
 	
 	// Initialize the cache system
-	cache.InitCache()
+	cache.InitCache(cfg.Cache)
 
-	// New Echo instance
-	e := echo.New()
+	// Token service used by the auth controller and middleware
+	tokens := auth.NewTokenService(cfg.Auth)
 
-	// Get allowed origins from environment variable or use default
-	allowedOrigins := []string{"http://localhost:5173", "http://127.0.0.1:5173"}
-	if origins := os.Getenv("ALLOWED_ORIGINS"); origins != "" {
-		allowedOrigins = strings.Split(origins, ",")
-	}
+	// New Echo instance, answering every error with the API error envelope
+	e := echo.New()
+	e.HTTPErrorHandler = apierror.Handler
 
 	// CORS middleware
 	e.Use(echoMiddleware.CORSWithConfig(echoMiddleware.CORSConfig{
-		AllowOrigins: allowedOrigins,
-		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
-		AllowHeaders: []string{"Content-Type", "Authorization", "Accept"},
+		AllowOrigins: cfg.Server.AllowedOrigins,
+		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
+		AllowHeaders: []string{"Content-Type", "Authorization", "Accept", "If-Match"},
+		ExposeHeaders: []string{"Link", "ETag"},
 		AllowCredentials: true,
 	}))
 
+	// Per-request deadline, propagated to the database queries
+	e.Use(middleware.RequestTimeout(cfg.Server))
+
 	// Repository instances
-	workerRepo := repository.NewWorkerRepository()
-	projectRepo := repository.NewProjectRepository()
-	userRepo := repository.NewUserRepository()
-	logRepo := repository.NewLogRepository() // Keep log repository for background logging
+	workerRepo := repository.NewWorkerRepository(db)
+	projectRepo := repository.NewProjectRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	logRepo := repository.NewLogRepository(db) // Keep log repository for background logging
+	searchRepo := repository.NewSearchRepository(db)
+	trashRepo := repository.NewTrashRepository(db)
+	historyRepo := repository.NewHistoryRepository(db)
+	timesheetRepo := repository.NewTimesheetRepository(db)
+
+	// Unit of work for operations spanning several repositories
+	uow := repository.NewUnitOfWork(db)
 
 	// Controller instances
-	workerCtrl := controller.NewWorkerController(workerRepo)
-	projectCtrl := controller.NewProjectController(projectRepo)
-	authCtrl := controller.NewAuthController(userRepo)
+	workerCtrl := controller.NewWorkerController(workerRepo, uow)
+	projectCtrl := controller.NewProjectController(projectRepo, uow)
+	authCtrl := controller.NewAuthController(userRepo, tokens)
 	adminCtrl := controller.NewAdminController(userRepo, logRepo)
+	searchCtrl := controller.NewSearchController(searchRepo)
+	trashCtrl := controller.NewTrashController(trashRepo, cfg.Trash.Retention)
+	historyCtrl := controller.NewHistoryController(historyRepo)
+	timesheetCtrl := controller.NewTimesheetController(timesheetRepo, userRepo, uow)
+
+	// Hard-delete what has been in the trash for longer than the retention
+	jobs.StartTrashPurge(context.Background(), trashRepo, cfg.Trash)
 
 	// Create activity logger middleware
 	activityLogger := middleware.NewActivityLogger(logRepo)
@@ -61,31 +94,78 @@ func main() {
 	authGroup.POST("/register", authCtrl.Register, activityLogger.LogUserAuth(model.LogTypeRegister))
 
 	// Worker routes (protected) with CRUD logging
-	workers := e.Group("/api/workers", auth.JWTMiddleware, activityLogger.LogCRUDOperation(model.EntityTypeWorker))
+	workers := e.Group("/api/workers", tokens.JWTMiddleware, activityLogger.LogCRUDOperation(model.EntityTypeWorker))
 	workers.GET("", workerCtrl.GetAllWorkers)
 	workers.GET("/:id", workerCtrl.GetWorker)
+	workers.GET("/:id/history", historyCtrl.GetWorkerHistory)
 	workers.POST("", workerCtrl.CreateWorker)
+	workers.POST("/bulk", workerCtrl.BulkWorkers)
+	workers.POST("/import", workerCtrl.ImportWorkers)
+	workers.GET("/export.csv", workerCtrl.ExportWorkers(spreadsheet.CSV))
+	workers.GET("/export.xlsx", workerCtrl.ExportWorkers(spreadsheet.XLSX))
 	workers.PUT("/:id", workerCtrl.UpdateWorker)
+	workers.PATCH("/:id", workerCtrl.PatchWorker)
 	workers.DELETE("/:id", workerCtrl.DeleteWorker)
+	workers.POST("/:id/transfer", workerCtrl.TransferWorker)
 
 	// Project routes (protected) with CRUD logging
-	projects := e.Group("/api/projects", auth.JWTMiddleware, activityLogger.LogCRUDOperation(model.EntityTypeProject))
+	projects := e.Group("/api/projects", tokens.JWTMiddleware, activityLogger.LogCRUDOperation(model.EntityTypeProject))
 	projects.GET("", projectCtrl.GetAllProjects)
+	projects.GET("/nearest", projectCtrl.GetNearestProjects)
+	projects.GET("/export.geojson", projectCtrl.ExportGeoJSON)
+	projects.GET("/export.kml", projectCtrl.ExportKML)
+	projects.GET("/export.csv", projectCtrl.ExportProjects(spreadsheet.CSV))
+	projects.GET("/export.xlsx", projectCtrl.ExportProjects(spreadsheet.XLSX))
+	projects.GET("/assignments/export.csv", projectCtrl.ExportAssignments(spreadsheet.CSV))
+	projects.GET("/assignments/export.xlsx", projectCtrl.ExportAssignments(spreadsheet.XLSX))
+	projects.POST("/import", projectCtrl.ImportGeoJSON)
+	projects.POST("/bulk", projectCtrl.BulkProjects)
 	projects.GET("/:id", projectCtrl.GetProject)
+	projects.GET("/:id/history", historyCtrl.GetProjectHistory)
+	projects.GET("/:id/report.pdf", projectCtrl.ProjectReport)
 	projects.POST("", projectCtrl.CreateProject)
 	projects.PUT("/:id", projectCtrl.UpdateProject)
+	projects.PATCH("/:id", projectCtrl.PatchProject)
 	projects.DELETE("/:id", projectCtrl.DeleteProject)
 
 	// Project-Worker relationship routes (protected) with CRUD logging
 	projects.POST("/:id/workers", projectCtrl.AssignWorkerToProject)
 	projects.GET("/:id/workers/available", projectCtrl.GetAvailableWorkers)
+	projects.PUT("/:id/workers/:workerId", projectCtrl.UpdateWorkerAssignment)
 	projects.DELETE("/:id/workers/:workerId", projectCtrl.UnassignWorkerFromProject)
 
+	// Timesheet routes (protected) with CRUD logging
+	timesheets := e.Group("/api/timesheets", tokens.JWTMiddleware, activityLogger.LogCRUDOperation(model.EntityTypeTimesheet))
+	timesheets.GET("", timesheetCtrl.GetTimesheets)
+	timesheets.GET("/summary", timesheetCtrl.GetTimesheetSummary)
+	timesheets.GET("/approvals", timesheetCtrl.GetApprovals)
+	timesheets.PUT("/week", timesheetCtrl.SaveTimesheetWeek)
+	timesheets.GET("/:id", timesheetCtrl.GetTimesheet)
+	timesheets.POST("", timesheetCtrl.CreateTimesheet)
+	timesheets.PUT("/:id", timesheetCtrl.UpdateTimesheet)
+	timesheets.DELETE("/:id", timesheetCtrl.DeleteTimesheet)
+	timesheets.POST("/:id/submit", timesheetCtrl.SubmitTimesheet)
+	timesheets.POST("/:id/approve", timesheetCtrl.ApproveTimesheet)
+	timesheets.POST("/:id/reject", timesheetCtrl.RejectTimesheet)
+
+	// Scheduling conflicts between worker assignments (protected)
+	e.GET("/api/conflicts", projectCtrl.GetConflicts, tokens.JWTMiddleware)
+
+	// Full-text search across workers and projects (protected)
+	e.GET("/api/search", searchCtrl.Search, tokens.JWTMiddleware)
+
+	// Trash routes (protected) with restore and purge logging
+	trash := e.Group("/api/trash", tokens.JWTMiddleware, activityLogger.LogTrashOperation())
+	trash.GET("", trashCtrl.GetTrash)
+	trash.POST("/:type/:id/restore", trashCtrl.RestoreItem)
+	trash.DELETE("/:type/:id", trashCtrl.PurgeItem)
+
 	// Admin routes (protected with admin role) with CRUD logging
-	admin := e.Group("/api/admin", auth.JWTMiddleware, auth.AdminOnly, activityLogger.LogCRUDOperation(model.EntityTypeUser))
+	admin := e.Group("/api/admin", tokens.JWTMiddleware, auth.AdminOnly, activityLogger.LogCRUDOperation(model.EntityTypeUser))
 	admin.GET("/users", adminCtrl.GetAllUsers)
 	admin.PUT("/users/:id/status", adminCtrl.UpdateUserStatus)
 	admin.PUT("/users/:id/role", adminCtrl.UpdateUserRole)
+	admin.PUT("/users/:id/manager", adminCtrl.UpdateUserManager)
 	admin.GET("/users/:id/activity", adminCtrl.GetUserActivity)
 
 	// Health check endpoint
@@ -96,12 +176,6 @@ func main() {
 		})
 	})
 
-	// Get port from environment or use default
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	e.Logger.Fatal(e.Start(":"+port))
+	e.Logger.Fatal(e.Start(":"+strconv.Itoa(cfg.Server.Port)))
 
 }
\ No newline at end of file
@@ -0,0 +1,46 @@
+// Package mergepatch implements JSON Merge Patch (RFC 7396): a patch is a
+// JSON document mirroring the target, where members set to null are removed,
+// objects are merged recursively and any other value replaces the target's.
+package mergepatch
+
+import (
+	"encoding/json"
+)
+
+// MediaType is the content type of merge patch documents
+const MediaType = "application/merge-patch+json"
+
+// Apply returns the target document with the patch applied
+func Apply(target, patch []byte) ([]byte, error) {
+	var targetValue, patchValue interface{}
+	if len(target) > 0 {
+		if err := json.Unmarshal(target, &targetValue); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(targetValue, patchValue))
+}
+
+// merge applies a decoded patch to a decoded target
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = merge(targetObject[name], value)
+	}
+	return targetObject
+}
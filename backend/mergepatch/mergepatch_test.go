@@ -0,0 +1,54 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// The examples of RFC 7396, appendix A
+func TestApply(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		got, err := Apply([]byte(tt.target), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("applying %s to %s: %v", tt.patch, tt.target, err)
+		}
+		var gotValue, wantValue interface{}
+		if err := json.Unmarshal(got, &gotValue); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(tt.want), &wantValue); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(gotValue, wantValue) {
+			t.Errorf("applying %s to %s: expected %s, got %s", tt.patch, tt.target, tt.want, got)
+		}
+	}
+}
+
+func TestApplyRejectsMalformedPatches(t *testing.T) {
+	if _, err := Apply([]byte(`{"a":"b"}`), []byte(`{"a":`)); err == nil {
+		t.Fatal("expected a malformed patch to fail")
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -11,13 +12,33 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// ChangedFieldsKey is the context key under which update handlers store the
+// JSON names of the fields they changed, for the activity log
+const ChangedFieldsKey = "changed_fields"
+
+// LogSummaryKey is the context key under which batch handlers store the
+// description of what the batch wrote, logged as one BULK entry in place of
+// the entry of the request; an empty summary is not logged
+const LogSummaryKey = "log_summary"
+
+// LogEntryKey is the context key under which handlers of operations other
+// than plain CRUD store the LogEntry describing them, logged in place of the
+// entry of the request
+const LogEntryKey = "log_entry"
+
+// LogEntry is the type and description of the activity log entry of a request
+type LogEntry struct {
+	Type        model.LogType
+	Description string
+}
+
 // ActivityLogger is a middleware that logs CRUD operations
 type ActivityLogger struct {
-	logRepo *repository.LogRepository
+	logRepo repository.LogRepository
 }
 
 // NewActivityLogger creates a new ActivityLogger middleware
-func NewActivityLogger(logRepo *repository.LogRepository) *ActivityLogger {
+func NewActivityLogger(logRepo repository.LogRepository) *ActivityLogger {
 	return &ActivityLogger{
 		logRepo: logRepo,
 	}
@@ -43,6 +64,11 @@ func (l *ActivityLogger) LogCRUDOperation(entityType model.EntityType) echo.Midd
 				return nil
 			}
 
+			// Dry runs change nothing
+			if dryRun, _ := strconv.ParseBool(c.QueryParam("dry_run")); dryRun {
+				return nil
+			}
+
 			// Get user information from context (set by JWTMiddleware)
 			userID, ok := c.Get("user_id").(uint)
 			if !ok {
@@ -76,12 +102,49 @@ func (l *ActivityLogger) LogCRUDOperation(entityType model.EntityType) echo.Midd
 				}
 			}
 
+			// A batch is logged once, as a whole
+			if summary, ok := c.Get(LogSummaryKey).(string); ok {
+				if summary == "" {
+					return nil
+				}
+				l.storeAsync(c, &model.ActivityLog{
+					UserID:      userID,
+					Username:    username,
+					LogType:     model.LogTypeBulk,
+					EntityType:  entityType,
+					Description: summary,
+				})
+				return nil
+			}
+
+			// Other operations describe themselves
+			if entry, ok := c.Get(LogEntryKey).(LogEntry); ok {
+				l.storeAsync(c, &model.ActivityLog{
+					UserID:      userID,
+					Username:    username,
+					LogType:     entry.Type,
+					EntityType:  entityType,
+					EntityID:    entityID,
+					Description: entry.Description,
+				})
+				return nil
+			}
+
 			// Create description based on the operation
 			description := fmt.Sprintf("%s %s", logType, entityType)
 			if entityID > 0 {
 				description = fmt.Sprintf("%s with ID: %d", description, entityID)
 			}
 
+			// Updates naming their changed fields are logged with them, and
+			// not at all when nothing changed
+			if fields, ok := c.Get(ChangedFieldsKey).([]string); ok {
+				if len(fields) == 0 {
+					return nil
+				}
+				description = fmt.Sprintf("%s (changed: %s)", description, strings.Join(fields, ", "))
+			}
+
 			// Create log entry
 			log := &model.ActivityLog{
 				UserID:      userID,
@@ -92,21 +155,72 @@ func (l *ActivityLogger) LogCRUDOperation(entityType model.EntityType) echo.Midd
 				Description: description,
 			}
 
-			// Store log asynchronously to avoid blocking the response
-			go func(log *model.ActivityLog) {
-				err := l.logRepo.CreateLog(log)
-				if err != nil {
-					// Just print to console for now, could use a more sophisticated
-					// error handling mechanism in production
-					fmt.Printf("Failed to log activity: %v\n", err)
-				}
-			}(log)
+			l.storeAsync(c, log)
+
+			return nil
+		}
+	}
+}
+
+// LogTrashOperation logs the items restored from or purged out of the trash
+func (l *ActivityLogger) LogTrashOperation() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// Process the request
+			err := next(c)
+			if err != nil {
+				return err
+			}
+
+			var logType model.LogType
+			switch c.Request().Method {
+			case http.MethodPost:
+				logType = model.LogTypeRestore
+			case http.MethodDelete:
+				logType = model.LogTypePurge
+			default:
+				return nil
+			}
+
+			userID, ok := c.Get("user_id").(uint)
+			if !ok {
+				return nil
+			}
+			username, _ := c.Get("username").(string)
+
+			// The handler succeeded, so the path holds a valid type and ID
+			entityType := model.EntityType(strings.ToUpper(c.Param("type")))
+			id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+			l.storeAsync(c, &model.ActivityLog{
+				UserID:      userID,
+				Username:    username,
+				LogType:     logType,
+				EntityType:  entityType,
+				EntityID:    uint(id),
+				Description: fmt.Sprintf("%s %s with ID: %d", logType, entityType, id),
+			})
 
 			return nil
 		}
 	}
 }
 
+// storeAsync stores a log entry without blocking the response. The request
+// context is cancelled once the response is sent, so detach from its
+// cancellation.
+func (l *ActivityLogger) storeAsync(c echo.Context, log *model.ActivityLog) {
+	logCtx := context.WithoutCancel(c.Request().Context())
+	go func(log *model.ActivityLog) {
+		err := l.logRepo.CreateLog(logCtx, log)
+		if err != nil {
+			// Just print to console for now, could use a more sophisticated
+			// error handling mechanism in production
+			fmt.Printf("Failed to log activity: %v\n", err)
+		}
+	}(log)
+}
+
 // LogUserAuth logs user authentication events (login, logout, register)
 func (l *ActivityLogger) LogUserAuth(logType model.LogType) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -141,9 +255,10 @@ func (l *ActivityLogger) LogUserAuth(logType model.LogType) echo.MiddlewareFunc
 				Description: fmt.Sprintf("User %s: %s", logType, username),
 			}
 			
-			// Store log asynchronously
+			// Store log asynchronously, detached from the request cancellation
+			logCtx := context.WithoutCancel(c.Request().Context())
 			go func(log *model.ActivityLog) {
-				err := l.logRepo.CreateLog(log)
+				err := l.logRepo.CreateLog(logCtx, log)
 				if err != nil {
 					fmt.Printf("Failed to log auth activity: %v\n", err)
 				}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
+	"github.com/labstack/echo/v4"
+)
+
+// StatusClientClosedRequest is the non-standard status (introduced by nginx)
+// reported when the client disconnects before the response is written
+const StatusClientClosedRequest = 499
+
+// RequestTimeout bounds the request context with a deadline. Routes listed in
+// the server configuration get their own deadline, every other route gets the
+// default request timeout. Repositories run their queries with the request
+// context, so a timed-out or abandoned request also stops its queries.
+func RequestTimeout(cfg config.ServerConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			timeout := cfg.RequestTimeout
+			if routeTimeout, ok := cfg.RouteTimeouts[c.Request().Method+" "+c.Path()]; ok {
+				timeout = routeTimeout
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			err := next(c)
+
+			// Report cancellation unless the handler already responded
+			if status, ok := ContextErrorStatus(ctx, err); ok && !c.Response().Committed {
+				return echo.NewHTTPError(status, http.StatusText(status))
+			}
+			return err
+		}
+	}
+}
+
+// ContextErrorStatus reports whether err (or the request context itself) was
+// caused by a cancelled or expired context, and the HTTP status to answer with:
+// 499 when the client went away, 504 when the deadline passed
+func ContextErrorStatus(ctx context.Context, err error) (int, bool) {
+	// Some drivers don't wrap the context error, so look at the context as well
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, true
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest, true
+	default:
+		return 0, false
+	}
+}
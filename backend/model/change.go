@@ -0,0 +1,42 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Assignment operation types, recorded in the history of both sides
+const (
+	LogTypeAssign   LogType = "ASSIGN"
+	LogTypeUnassign LogType = "UNASSIGN"
+)
+
+// EntityChange is an entry of the history of a worker or project: one write
+// and the fields it changed
+type EntityChange struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	EntityType EntityType `json:"entity_type" gorm:"size:20;index:idx_entity_changes_entity,priority:1"`
+	EntityID   uint       `json:"entity_id" gorm:"index:idx_entity_changes_entity,priority:2"`
+	Action     LogType    `json:"action" gorm:"size:20"`
+	// UserID is the owner of the entity, isolating histories between users
+	UserID uint `json:"user_id" gorm:"index"`
+	// ChangedByID and ChangedBy identify the user who made the change
+	ChangedByID uint          `json:"changed_by_id"`
+	ChangedBy   string        `json:"changed_by" gorm:"size:50"`
+	Changes     []FieldChange `json:"changes" gorm:"type:text;serializer:json"`
+	CreatedAt   time.Time     `json:"created_at" gorm:"index"`
+}
+
+// FieldChange is the value of a field before and after a change, in JSON
+// form; a missing value is null
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// Actor is the user on whose behalf a request writes
+type Actor struct {
+	ID       uint
+	Username string
+}
@@ -15,6 +15,21 @@ const (
 	LogTypeRead   LogType = "READ"
 	LogTypeUpdate LogType = "UPDATE"
 	LogTypeDelete LogType = "DELETE"
+
+	// Trash operation types
+	LogTypeRestore LogType = "RESTORE"
+	LogTypePurge   LogType = "PURGE"
+
+	// Batch operation types
+	LogTypeBulk LogType = "BULK"
+
+	// Worker operation types
+	LogTypeTransfer LogType = "TRANSFER"
+
+	// Timesheet approval types
+	LogTypeSubmit  LogType = "SUBMIT"
+	LogTypeApprove LogType = "APPROVE"
+	LogTypeReject  LogType = "REJECT"
 	
 	// Auth operation types
 	LogTypeLogin    LogType = "LOGIN"
@@ -26,9 +41,10 @@ const (
 type EntityType string
 
 const (
-	EntityTypeWorker  EntityType = "WORKER"
-	EntityTypeProject EntityType = "PROJECT"
-	EntityTypeUser    EntityType = "USER"
+	EntityTypeWorker    EntityType = "WORKER"
+	EntityTypeProject   EntityType = "PROJECT"
+	EntityTypeUser      EntityType = "USER"
+	EntityTypeTimesheet EntityType = "TIMESHEET"
 )
 
 // ActivityLog represents a system activity log entry
@@ -3,22 +3,95 @@ package model
 import (
 	"time"
 
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/geo"
 	"gorm.io/gorm"
 )
 
+// Statuses of projects
+const (
+	ProjectStatusActive    = "active"
+	ProjectStatusCancelled = "cancelled"
+)
+
 // Project represents a construction project with associated workers
 type Project struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" validate:"required,min=2,max=100"`
+	Name        string         `json:"name" gorm:"index" validate:"required,min=2,max=100"`
 	Description string         `json:"description" validate:"required,min=10,max=500"`
-	Status      string         `json:"status" validate:"required,oneof=active completed on_hold cancelled"`
-	StartDate   time.Time      `json:"start_date" validate:"required"`
-	EndDate     *time.Time     `json:"end_date"`
-	Latitude    float64        `json:"latitude" validate:"required,latitude"`
-	Longitude   float64        `json:"longitude" validate:"required,longitude"`
+	Status      string         `json:"status" gorm:"index" validate:"required,oneof=active completed on_hold cancelled"`
+	StartDate   time.Time      `json:"start_date" gorm:"index" validate:"required"`
+	EndDate     *time.Time     `json:"end_date" gorm:"index"`
+	Latitude    float64        `json:"latitude" gorm:"index:idx_projects_location,priority:1" validate:"required,latitude"`
+	Longitude   float64        `json:"longitude" gorm:"index:idx_projects_location,priority:2" validate:"required,longitude"`
+	Boundaries  []SiteBoundary `json:"boundaries" gorm:"type:text;serializer:json" validate:"max=20,dive"`
+	Locations   []SiteLocation `json:"locations" gorm:"type:text;serializer:json" validate:"max=50,dive"`
 	UserID      uint           `json:"user_id" gorm:"index" validate:"required"`
-	Workers     []Worker       `json:"workers" gorm:"many2many:worker_projects;joinForeignKey:ProjectID;joinReferences:WorkerID"`
+	// Workers are the workers with an assignment to the project that has not
+	// ended, loaded by the repositories
+	Workers     []Worker       `json:"workers" gorm:"-"`
+	// Version is incremented on every write, for optimistic concurrency control
+	Version     uint           `json:"version" gorm:"not null;default:1"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
-} 
\ No newline at end of file
+}
+
+// Point returns the main location of the project
+func (p Project) Point() geo.Point {
+	return geo.Point{Lat: p.Latitude, Lng: p.Longitude}
+}
+
+// Overlaps reports whether the project runs on some day of the period from
+// start to end, where a nil end leaves the period open
+func (p Project) Overlaps(start time.Time, end *time.Time) bool {
+	return (end == nil || !p.StartDate.After(*end)) && (p.EndDate == nil || !p.EndDate.Before(start))
+}
+
+// DistanceKm returns the distance from a point to the nearest part of the
+// site: its main location, its other locations or the area of its boundaries
+func (p Project) DistanceKm(from geo.Point) float64 {
+	distance := geo.DistanceKm(from, p.Point())
+	for _, location := range p.Locations {
+		distance = min(distance, geo.DistanceKm(from, location.Point()))
+	}
+	for _, boundary := range p.Boundaries {
+		distance = min(distance, geo.DistanceToRingKm(from, boundary.Points))
+	}
+	return distance
+}
+
+// InBox reports whether any part of the site lies inside a bounding box
+func (p Project) InBox(box geo.BoundingBox) bool {
+	if box.Contains(p.Point()) {
+		return true
+	}
+	for _, location := range p.Locations {
+		if box.Contains(location.Point()) {
+			return true
+		}
+	}
+	for _, boundary := range p.Boundaries {
+		if geo.RingIntersectsBox(boundary.Points, box) {
+			return true
+		}
+	}
+	return false
+}
+
+// OutsideExtent returns the JSON name of the first field holding a point
+// farther than MaxSiteExtentKm from the main location, or "" if there is none
+func (p Project) OutsideExtent() string {
+	for _, location := range p.Locations {
+		if geo.DistanceKm(p.Point(), location.Point()) > MaxSiteExtentKm {
+			return "locations"
+		}
+	}
+	for _, boundary := range p.Boundaries {
+		for _, point := range boundary.Points {
+			if geo.DistanceKm(p.Point(), point) > MaxSiteExtentKm {
+				return "boundaries"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/geo"
+)
+
+// MaxSiteExtentKm is how far the boundaries and locations of a project may
+// reach from its main location. Location queries rely on it to find projects
+// through the index on the main location.
+const MaxSiteExtentKm = 10.0
+
+// SiteBoundary is a polygon outlining a project site, or one part of it
+type SiteBoundary struct {
+	Name string `json:"name" validate:"max=100"`
+	// Points is a closed ring: the first point is repeated at the end
+	Points []geo.Point `json:"points" validate:"polygon"`
+}
+
+// MarshalJSON adds the area of the boundary to its JSON form
+func (b SiteBoundary) MarshalJSON() ([]byte, error) {
+	type boundary SiteBoundary
+	return json.Marshal(struct {
+		boundary
+		AreaKm2 float64 `json:"area_km2"`
+	}{boundary(b), geo.RingAreaKm2(b.Points)})
+}
+
+// SiteLocation is a named point of a project site besides its main location,
+// such as a gate or a depot
+type SiteLocation struct {
+	Name      string  `json:"name" validate:"required,min=2,max=100"`
+	Kind      string  `json:"kind" validate:"required,oneof=gate depot office parking other"`
+	Latitude  float64 `json:"latitude" validate:"latitude"`
+	Longitude float64 `json:"longitude" validate:"longitude"`
+}
+
+// Point returns the position of the location
+func (l SiteLocation) Point() geo.Point {
+	return geo.Point{Lat: l.Latitude, Lng: l.Longitude}
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Statuses of timesheet entries. Entries start as drafts, are submitted for
+// approval and then approved or rejected; rejected entries may be submitted
+// again.
+const (
+	TimesheetDraft     = "draft"
+	TimesheetSubmitted = "submitted"
+	TimesheetApproved  = "approved"
+	TimesheetRejected  = "rejected"
+)
+
+// Actions moving timesheet entries between statuses
+const (
+	TimesheetSubmit  = "submit"
+	TimesheetApprove = "approve"
+	TimesheetReject  = "reject"
+)
+
+// TimesheetTransition is an action on timesheet entries: the statuses it
+// applies to, the status it leads to, the roles allowed to take it and the
+// type of the activity log entry recording it
+type TimesheetTransition struct {
+	From    []string
+	To      string
+	Roles   []string
+	LogType LogType
+}
+
+// TimesheetTransitions lists the transitions of timesheet entries by action
+var TimesheetTransitions = map[string]TimesheetTransition{
+	TimesheetSubmit: {
+		From: []string{TimesheetDraft, TimesheetRejected}, To: TimesheetSubmitted,
+		Roles: []string{RoleForeman, RoleManager, RoleAdmin}, LogType: LogTypeSubmit,
+	},
+	TimesheetApprove: {
+		From: []string{TimesheetSubmitted}, To: TimesheetApproved,
+		Roles: []string{RoleManager, RoleAdmin}, LogType: LogTypeApprove,
+	},
+	TimesheetReject: {
+		From: []string{TimesheetSubmitted}, To: TimesheetRejected,
+		Roles: []string{RoleManager, RoleAdmin}, LogType: LogTypeReject,
+	},
+}
+
+// Allows reports whether a role may take the transition
+func (t TimesheetTransition) Allows(role string) bool {
+	return slices.Contains(t.Roles, role)
+}
+
+// AppliesTo reports whether the transition may move an entry out of a status
+func (t TimesheetTransition) AppliesTo(status string) bool {
+	return slices.Contains(t.From, status)
+}
+
+// Timesheet is the time a worker spent on a project on one day. A worker has
+// at most one entry per project and day.
+type Timesheet struct {
+	ID        uint `json:"id" gorm:"primaryKey"`
+	WorkerID  uint `json:"worker_id" gorm:"not null;uniqueIndex:idx_timesheets_day,priority:1" validate:"required"`
+	ProjectID uint `json:"project_id" gorm:"not null;index;uniqueIndex:idx_timesheets_day,priority:2" validate:"required"`
+	// Date is the day worked, at midnight UTC
+	Date time.Time `json:"date" gorm:"not null;index;uniqueIndex:idx_timesheets_day,priority:3" validate:"required"`
+	// Hours are the regular hours worked and OvertimeHours those on top of
+	// them; together they fit in a day
+	Hours         float64 `json:"hours" validate:"min=0,max=24"`
+	OvertimeHours float64 `json:"overtime_hours" validate:"min=0,max=24"`
+	// BreakMinutes is the break time taken, not counted in the hours
+	BreakMinutes int    `json:"break_minutes" validate:"min=0,max=1440"`
+	Notes        string `json:"notes" validate:"max=500"`
+	UserID       uint   `json:"user_id" gorm:"index;not null"` // Used to enforce user isolation
+	// Status is set by the transitions only, never by the body of a request
+	Status      string     `json:"status" gorm:"size:20;not null;default:draft;index"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+	// ReviewedBy is the user who approved or rejected the entry, with the
+	// comment given on rejection
+	ReviewedBy    *uint      `json:"reviewed_by"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+	ReviewComment string     `json:"review_comment" gorm:"size:500"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TotalHours returns the hours worked, overtime included
+func (t Timesheet) TotalHours() float64 {
+	return t.Hours + t.OvertimeHours
+}
+
+// Editable reports whether the entry may be changed or deleted. Submitted
+// entries wait for their review and approved ones are final.
+func (t Timesheet) Editable() bool {
+	return t.Status == TimesheetDraft || t.Status == TimesheetRejected
+}
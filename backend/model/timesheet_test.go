@@ -0,0 +1,50 @@
+package model
+
+import "testing"
+
+func TestTimesheetTransitions(t *testing.T) {
+	tests := []struct {
+		action string
+		from   string
+		to     string
+		roles  []string
+	}{
+		{TimesheetSubmit, TimesheetDraft, TimesheetSubmitted, []string{RoleForeman, RoleManager, RoleAdmin}},
+		{TimesheetSubmit, TimesheetRejected, TimesheetSubmitted, []string{RoleForeman, RoleManager, RoleAdmin}},
+		{TimesheetApprove, TimesheetSubmitted, TimesheetApproved, []string{RoleManager, RoleAdmin}},
+		{TimesheetReject, TimesheetSubmitted, TimesheetRejected, []string{RoleManager, RoleAdmin}},
+	}
+	statuses := []string{TimesheetDraft, TimesheetSubmitted, TimesheetApproved, TimesheetRejected}
+	roles := []string{RoleUser, RoleForeman, RoleManager, RoleAdmin}
+
+	for _, tt := range tests {
+		transition := TimesheetTransitions[tt.action]
+		if transition.To != tt.to {
+			t.Errorf("expected %s to lead to %s, got %s", tt.action, tt.to, transition.To)
+		}
+		if !transition.AppliesTo(tt.from) {
+			t.Errorf("expected %s to apply to %s entries", tt.action, tt.from)
+		}
+		for _, role := range roles {
+			want := false
+			for _, allowed := range tt.roles {
+				want = want || allowed == role
+			}
+			if transition.Allows(role) != want {
+				t.Errorf("expected %s to allow the %s role: %v", tt.action, role, want)
+			}
+		}
+	}
+
+	// Approved entries are final
+	for action, transition := range TimesheetTransitions {
+		if transition.AppliesTo(TimesheetApproved) {
+			t.Errorf("expected %s not to apply to approved entries", action)
+		}
+	}
+	for _, status := range statuses {
+		if TimesheetTransitions[TimesheetApprove].AppliesTo(status) != (status == TimesheetSubmitted) {
+			t.Errorf("expected approve to apply to submitted entries only, not %s", status)
+		}
+	}
+}
@@ -6,13 +6,28 @@ import (
 	"gorm.io/gorm"
 )
 
+// Roles of users. Foremen submit timesheets, managers approve those of the
+// users reporting to them and admins do both for everyone.
+const (
+	RoleUser    = "user"
+	RoleForeman = "foreman"
+	RoleManager = "manager"
+	RoleAdmin   = "admin"
+)
+
+// ValidRole reports whether a role is one of the known roles
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleForeman || role == RoleManager || role == RoleAdmin
+}
+
 // User represents a system user with authentication and role information
 type User struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
 	Username     string         `json:"username" gorm:"uniqueIndex;size:50" validate:"required,min=3,max=50"`
 	Email        string         `json:"email" gorm:"uniqueIndex;size:100" validate:"required,email"`
 	PasswordHash string         `json:"-" gorm:"size:255" validate:"required"` // Not exposed in JSON
-	Role         string         `json:"role" gorm:"default:user" validate:"required,oneof=user admin"`
+	Role         string         `json:"role" gorm:"default:user;index" validate:"required,oneof=user foreman manager admin"`
+	ManagerID    *uint          `json:"manager_id" gorm:"index"` // Manager approving the user's timesheets
 	Active       bool           `json:"active" gorm:"default:true"`
 	LastLogin    *time.Time     `json:"last_login"`
 	CreatedAt    time.Time      `json:"created_at"`
@@ -9,12 +9,19 @@ import (
 // Worker represents a construction worker with associated projects
 type Worker struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" validate:"required,min=2,max=50"`
+	Name      string         `json:"name" gorm:"index" validate:"required,min=2,max=50"`
 	Age       int            `json:"age" validate:"required,min=18,max=100"`
-	Position  string         `json:"position" validate:"required,min=2,max=50"`
-	Salary    int            `json:"salary" validate:"required,min=0"`
+	Position  string         `json:"position" gorm:"index" validate:"required,min=2,max=50"`
+	Salary    int            `json:"salary" gorm:"index" validate:"required,min=0"`
 	UserID    uint           `json:"user_id" gorm:"index" validate:"required"`
-	Projects  []Project      `json:"projects" gorm:"many2many:worker_projects;joinForeignKey:WorkerID;joinReferences:ProjectID"`
+	// Projects are the projects the worker has an assignment to that has
+	// not ended, loaded by the repositories
+	Projects  []Project      `json:"projects" gorm:"-"`
+	// Assignment holds the details of the worker's assignment when the
+	// worker is loaded as one of the workers of a project
+	Assignment *WorkerProject `json:"assignment,omitempty" gorm:"-"`
+	// Version is incremented on every write, for optimistic concurrency control
+	Version   uint           `json:"version" gorm:"not null;default:1"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt  `json:"deleted_at" gorm:"index"`
@@ -1,14 +1,99 @@
 package model
 
+import "time"
+
+// FullAllocation is the allocation of a worker spending all of their time on one project
+const FullAllocation = 100
+
+// AssignmentDetails describe how a worker is assigned to a project
+type AssignmentDetails struct {
+	// Role is the worker's role on the project, e.g. foreman
+	Role string `json:"role" gorm:"size:50" validate:"max=50"`
+	// StartDate and EndDate bound the assignment; when missing, it runs from
+	// the start or until the end of the project
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	// AllocationPercent is the share of the worker's time spent on the project
+	AllocationPercent int `json:"allocation_percent" gorm:"not null;default:100" validate:"min=1,max=100"`
+	// DayRate overrides the daily rate derived from the worker's salary
+	DayRate *int   `json:"day_rate" validate:"omitempty,min=0"`
+	Notes   string `json:"notes" validate:"max=500"`
+}
+
 // WorkerProject represents the many-to-many relationship between workers and projects
-// with an additional user_id field to enforce data isolation between users
+// with an additional user_id field to enforce data isolation between users.
+// A worker may be assigned to a project several times, e.g. when moved away
+// and back, so assignments have an ID of their own; the ones that ended are
+// kept as the history of the worker on the project.
 type WorkerProject struct {
-	WorkerID  uint `gorm:"primaryKey"`
-	ProjectID uint `gorm:"primaryKey"`
-	UserID    uint `gorm:"index;not null"` // Used to enforce user isolation
+	ID        uint `json:"id" gorm:"primaryKey"`
+	WorkerID  uint `json:"worker_id" gorm:"index;not null"`
+	ProjectID uint `json:"project_id" gorm:"index;not null"`
+	UserID    uint `json:"-" gorm:"index;not null"` // Used to enforce user isolation
+	AssignmentDetails
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Worker and Project only carry the foreign keys of the table
+	Worker  Worker  `json:"-"`
+	Project Project `json:"-"`
 }
 
 // TableName overrides the default table name
 func (WorkerProject) TableName() string {
 	return "worker_projects"
-} 
\ No newline at end of file
+}
+
+// NewAssignment creates the assignment of a worker to a project with the
+// default details: the whole project at full allocation
+func NewAssignment(workerID, projectID, userID uint) WorkerProject {
+	return WorkerProject{
+		WorkerID:          workerID,
+		ProjectID:         projectID,
+		UserID:            userID,
+		AssignmentDetails: AssignmentDetails{AllocationPercent: FullAllocation},
+	}
+}
+
+// Period returns the days the assignment covers on a project: its own dates,
+// or those of the project where it has none
+func (a AssignmentDetails) Period(project Project) (start time.Time, end *time.Time) {
+	start, end = project.StartDate, project.EndDate
+	if a.StartDate != nil {
+		start = *a.StartDate
+	}
+	if a.EndDate != nil {
+		end = a.EndDate
+	}
+	return start, end
+}
+
+// Ended reports whether the assignment ended before the day of now
+func (a AssignmentDetails) Ended(now time.Time) bool {
+	return a.EndDate != nil && dateOnly(*a.EndDate) < dateOnly(now)
+}
+
+// Started reports whether the assignment started before the day of now
+func (a AssignmentDetails) Started(project Project, now time.Time) bool {
+	start, _ := a.Period(project)
+	return dateOnly(start) < dateOnly(now)
+}
+
+// Overlaps reports whether two assignments to a project share a day
+func (a AssignmentDetails) Overlaps(other AssignmentDetails, project Project) bool {
+	start, end := a.Period(project)
+	otherStart, otherEnd := other.Period(project)
+	return (end == nil || dateOnly(otherStart) <= dateOnly(*end)) &&
+		(otherEnd == nil || dateOnly(start) <= dateOnly(*otherEnd))
+}
+
+// dateOnly formats the day of a date, in UTC
+func dateOnly(date time.Time) string {
+	return date.UTC().Format(time.DateOnly)
+}
+
+// Covers reports whether the assignment covers the day of the date on a project
+func (a AssignmentDetails) Covers(project Project, date time.Time) bool {
+	start, end := a.Period(project)
+	day := date.UTC().Format(time.DateOnly)
+	return day >= start.UTC().Format(time.DateOnly) && (end == nil || day <= end.UTC().Format(time.DateOnly))
+}
@@ -0,0 +1,143 @@
+// Package pagination parses the pagination parameters shared by the list
+// endpoints and writes their responses, including RFC 8288 Link headers.
+package pagination
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/apierror"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// DefaultPageSize is the page size of list endpoints without their own default
+	DefaultPageSize = 10
+	// MaxPageSize is the largest page a client may request
+	MaxPageSize = 100
+)
+
+// Params are the pagination parameters of a list request
+type Params struct {
+	Page     int
+	PageSize int
+	// Cursor is the raw keyset cursor. CursorMode is set when the cursor
+	// parameter is present, an empty cursor requesting the first page.
+	Cursor     string
+	CursorMode bool
+}
+
+// Parse reads the "page", "page_size" and "cursor" query parameters. The
+// camel-case "pageSize" is accepted for older clients. Values that are not
+// positive integers, or page sizes above MaxPageSize, are rejected.
+func Parse(c echo.Context, defaultPageSize int) (Params, error) {
+	params := Params{Page: 1, PageSize: defaultPageSize}
+
+	if raw := c.QueryParam("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return params, apierror.InvalidParameter("page", "must be a positive integer")
+		}
+		params.Page = page
+	}
+
+	raw := c.QueryParam("page_size")
+	if raw == "" {
+		raw = c.QueryParam("pageSize")
+	}
+	if raw != "" {
+		pageSize, err := strconv.Atoi(raw)
+		if err != nil || pageSize < 1 || pageSize > MaxPageSize {
+			return params, apierror.InvalidParameter("page_size", fmt.Sprintf("must be an integer between 1 and %d", MaxPageSize))
+		}
+		params.PageSize = pageSize
+	}
+
+	if c.QueryParams().Has("cursor") {
+		params.Cursor = c.QueryParam("cursor")
+		params.CursorMode = true
+	}
+
+	return params, nil
+}
+
+// Respond writes a page of a list under the given key, with the Link header
+// pointing at the neighbouring pages
+func Respond(c echo.Context, key string, data interface{}, params Params, info repository.PageInfo) error {
+	SetLinkHeader(c, params, info)
+	return c.JSON(http.StatusOK, Body(key, data, params, info))
+}
+
+// Body builds the response body of a page. Offset pages report the total and
+// the page number, cursor pages the cursors of their neighbours.
+func Body(key string, data interface{}, params Params, info repository.PageInfo) map[string]interface{} {
+	if params.CursorMode {
+		return map[string]interface{}{
+			key:           data,
+			"next_cursor": info.NextCursor,
+			"prev_cursor": info.PrevCursor,
+			"pageSize":    params.PageSize,
+		}
+	}
+	return map[string]interface{}{
+		key:        data,
+		"total":    info.Total,
+		"page":     params.Page,
+		"pageSize": params.PageSize,
+	}
+}
+
+// SetLinkHeader sets the Link header of a page
+func SetLinkHeader(c echo.Context, params Params, info repository.PageInfo) {
+	if links := Links(c, params, info); len(links) > 0 {
+		c.Response().Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// Links returns the RFC 8288 links to the first, previous, next and last
+// pages. Cursor pages have no last link, as it would require a count.
+func Links(c echo.Context, params Params, info repository.PageInfo) []string {
+	var links []string
+
+	// link adds a link to the current URL with one parameter replaced
+	link := func(rel, param, value string) {
+		u := *c.Request().URL
+		q := u.Query()
+		q.Del("pageSize")
+		q.Set("page_size", strconv.Itoa(params.PageSize))
+		q.Set(param, value)
+		if param == "cursor" {
+			q.Del("page")
+		}
+		u.RawQuery = q.Encode()
+		links = append(links, fmt.Sprintf("<%s>; rel=%q", u.RequestURI(), rel))
+	}
+
+	if params.CursorMode {
+		link("first", "cursor", "")
+		if info.PrevCursor != "" {
+			link("prev", "cursor", info.PrevCursor)
+		}
+		if info.NextCursor != "" {
+			link("next", "cursor", info.NextCursor)
+		}
+		return links
+	}
+
+	lastPage := int((info.Total + int64(params.PageSize) - 1) / int64(params.PageSize))
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	link("first", "page", "1")
+	if params.Page > 1 {
+		link("prev", "page", strconv.Itoa(min(params.Page-1, lastPage)))
+	}
+	if params.Page < lastPage {
+		link("next", "page", strconv.Itoa(params.Page+1))
+	}
+	link("last", "page", strconv.Itoa(lastPage))
+	return links
+}
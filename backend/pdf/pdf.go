@@ -0,0 +1,172 @@
+// Package pdf writes simple PDF documents: pages of text in the standard
+// Helvetica fonts, lines and filled rectangles, enough for printable
+// reports. Coordinates are in points from the bottom left corner of a page.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Size of an A4 page in points
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Font is one of the standard fonts every PDF reader has
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+// resource names and base fonts of the standard fonts
+var fonts = []struct{ resource, base string }{
+	Helvetica:     {"F1", "Helvetica"},
+	HelveticaBold: {"F2", "Helvetica-Bold"},
+}
+
+// Document is a PDF document built page by page
+type Document struct {
+	title string
+	pages []*Page
+}
+
+// Page is a page of a document
+type Page struct {
+	content bytes.Buffer
+}
+
+// New creates an empty document with a title
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+// AddPage adds an A4 page to the document and returns it
+func (d *Document) AddPage() *Page {
+	page := &Page{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the pages of the document
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// Text writes text with its baseline starting at x, y
+func (p *Page) Text(x, y float64, font Font, size float64, text string) {
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td ", fonts[font].resource, number(size), number(x), number(y))
+	writeString(&p.content, encode(text))
+	p.content.WriteString(" Tj ET\n")
+}
+
+// Line draws a straight line
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", number(width), number(x1), number(y1), number(x2), number(y2))
+}
+
+// FillRect fills a rectangle with a shade of gray, 0 being black and 1 white
+func (p *Page) FillRect(x, y, width, height, gray float64) {
+	fmt.Fprintf(&p.content, "q %s g %s %s %s %s re f Q\n", number(gray), number(x), number(y), number(width), number(height))
+}
+
+// SetGray sets the shade of gray of the text written next, 0 being black
+func (p *Page) SetGray(gray float64) {
+	fmt.Fprintf(&p.content, "%s g\n", number(gray))
+}
+
+// WriteTo writes the document
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	out := &countingWriter{w: w}
+	var offsets []int64
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, out.n)
+		fmt.Fprintf(out, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			out.Write([]byte("stream\n"))
+			out.Write(stream)
+			out.Write([]byte("\nendstream\n"))
+		}
+		out.Write([]byte("endobj\n"))
+	}
+
+	// Objects 1 to 5 are the catalog, the page tree, the fonts and the
+	// document information; each page is followed by its content stream
+	const firstPage = 6
+	out.Write([]byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"))
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	var kids bytes.Buffer
+	for i := range d.pages {
+		fmt.Fprintf(&kids, "%d 0 R ", firstPage+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(d.pages)), nil)
+	for _, font := range fonts {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", font.base), nil)
+	}
+	var title bytes.Buffer
+	writeString(&title, encode(d.title))
+	object(fmt.Sprintf("<< /Title %s /CreationDate (D:%s) >>", title.String(), time.Now().UTC().Format("20060102150405Z")), nil)
+
+	resources := fmt.Sprintf("<< /Font << /%s 3 0 R /%s 4 0 R >> >>", fonts[Helvetica].resource, fonts[HelveticaBold].resource)
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			number(A4Width), number(A4Height), resources, firstPage+2*i+1), nil)
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(page.content.Bytes())
+		zw.Close()
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", compressed.Len()), compressed.Bytes())
+	}
+
+	xref := out.n
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.n, out.err
+}
+
+// number formats a coordinate or size, rounded to a thousandth of a point
+func number(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+// writeString writes encoded text as a PDF literal string
+func writeString(buf *bytes.Buffer, text []byte) {
+	buf.WriteByte('(')
+	for _, b := range text {
+		if b == '(' || b == ')' || b == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(b)
+	}
+	buf.WriteByte(')')
+}
+
+// countingWriter counts the bytes written, for the cross-reference table,
+// and keeps the first error
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,92 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	doc := New("Report (draft)")
+	first := doc.AddPage()
+	first.Text(50, 800, HelveticaBold, 18, "Șantier Nord")
+	first.Line(50, 790, 545, 790, 0.5)
+	doc.AddPage().FillRect(50, 50, 100, 20, 0.9)
+
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if n != int64(len(data)) {
+		t.Fatalf("expected %d bytes written, got %d", len(data), n)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("expected a PDF file, got %q", data)
+	}
+	if !bytes.Contains(data, []byte("/Count 2")) || !bytes.Contains(data, []byte(`/Title (Report \(draft\))`)) {
+		t.Fatalf("expected 2 pages and the escaped title, got %q", data)
+	}
+
+	// Every entry of the cross-reference table points at its object
+	xref := bytes.LastIndex(data, []byte("\nxref\n")) + 1
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if startxref == nil || string(startxref[1]) != strconv.Itoa(xref) {
+		t.Fatalf("expected startxref %d, got %q", xref, startxref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	if len(entries) != 9 {
+		t.Fatalf("expected 9 objects, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := strconv.Itoa(i+1) + " 0 obj\n"; !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Fatalf("expected object %d at offset %d, got %q", i+1, offset, data[offset:offset+10])
+		}
+	}
+
+	// The first content stream holds the text in the font's encoding
+	stream := data[bytes.Index(data, []byte("stream\n"))+len("stream\n"):]
+	zr, err := zlib.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "/F2 18 Tf") || !strings.Contains(string(content), "(Santier Nord) Tj") {
+		t.Fatalf("expected the title text in the bold font, got %q", content)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := map[string]string{
+		"Ana Pop":     "Ana Pop",
+		"Ștefănescu":  "Stefanescu",
+		"Łódź":        "L\xf3dz",
+		"Café € 10":   "Caf\xe9 \x80 10",
+		"line\tbreak": "line break",
+		"日本":          "??",
+	}
+	for text, want := range tests {
+		if got := string(encode(text)); got != want {
+			t.Errorf("expected %q to encode as %q, got %q", text, want, got)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	// The space is 278 and a capital W 944 thousandths of the font size
+	if width := TextWidth(" W", Helvetica, 10); width != 12.22 {
+		t.Fatalf("expected 12.22, got %v", width)
+	}
+	if TextWidth("Ab", HelveticaBold, 10) <= TextWidth("Ab", Helvetica, 10) {
+		t.Fatal("expected the bold font to be wider")
+	}
+}
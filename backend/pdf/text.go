@@ -0,0 +1,82 @@
+package pdf
+
+import "strings"
+
+// Widths of the printable ASCII characters, from space to tilde, in
+// thousandths of the font size, from the metrics of the standard fonts
+var widths = [][95]int{
+	Helvetica: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	HelveticaBold: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// wideWidth is used for the characters outside ASCII, most of which are
+// accented letters as wide as a lowercase letter
+var wideWidth = []int{Helvetica: 556, HelveticaBold: 611}
+
+// winAnsi maps the characters of the Windows-1252 range 0x80-0x9F
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// folded replaces letters the standard fonts cannot show with their base
+// letter, so Romanian and other Central European names stay readable
+var folded = strings.NewReplacer(
+	"ă", "a", "Ă", "A", "ș", "s", "ş", "s", "Ș", "S", "Ş", "S", "ț", "t", "ţ", "t", "Ț", "T", "Ţ", "T",
+	"ą", "a", "Ą", "A", "ć", "c", "Ć", "C", "č", "c", "Č", "C", "ď", "d", "Ď", "D", "ę", "e", "Ę", "E",
+	"ě", "e", "Ě", "E", "ł", "l", "Ł", "L", "ń", "n", "Ń", "N", "ň", "n", "Ň", "N", "ő", "o", "Ő", "O",
+	"ř", "r", "Ř", "R", "ś", "s", "Ś", "S", "ť", "t", "Ť", "T", "ů", "u", "Ů", "U", "ű", "u", "Ű", "U",
+	"ź", "z", "Ź", "Z", "ż", "z", "Ż", "Z",
+)
+
+// encode converts text to the WinAnsi encoding of the standard fonts.
+// Characters it cannot show become a question mark, control characters
+// a space.
+func encode(text string) []byte {
+	text = folded.Replace(text)
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r < 0x20:
+			out = append(out, ' ')
+		case r < 0x7F, r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		default:
+			if b, ok := winAnsi[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// TextWidth returns the width of text written in a font and size, in points
+func TextWidth(text string, font Font, size float64) float64 {
+	total := 0
+	for _, b := range encode(text) {
+		if b >= 0x20 && b < 0x7F {
+			total += widths[font][b-0x20]
+		} else {
+			total += wideWidth[font]
+		}
+	}
+	return float64(total) * size / 1000
+}
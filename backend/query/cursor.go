@@ -0,0 +1,224 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Cursor marks a position in a list sorted by a Spec. It is handed to clients
+// as an opaque string and holds the sort key values, followed by the id, of
+// the row it points at.
+type Cursor struct {
+	// Sort is the signature of the sort keys the cursor was created for
+	Sort string `json:"s"`
+	// Values are the raw sort key values, the id last
+	Values []string `json:"v,omitempty"`
+	// Backward is set on cursors that page towards the start of the list
+	Backward bool `json:"b,omitempty"`
+
+	// parsed holds Values converted to the types of their fields
+	parsed []interface{}
+}
+
+// IsFirstPage reports whether the cursor points at the start of the list
+func (c *Cursor) IsFirstPage() bool {
+	return len(c.Values) == 0
+}
+
+// Encode returns the opaque string form of the cursor
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// keys returns the sort keys followed by the id as a final tie-breaker, which
+// sorts in the direction of the last key
+func (s *Spec) keys() []SortField {
+	var keys []SortField
+	if s != nil {
+		keys = append(keys, s.Sort...)
+	}
+	for _, key := range keys {
+		if key.Column == "id" {
+			return keys
+		}
+	}
+
+	desc := len(keys) > 0 && keys[len(keys)-1].Desc
+	return append(keys, SortField{Field: "id", Column: "id", Type: Int, Desc: desc})
+}
+
+// signature identifies the sort keys, so that a cursor is not reused with a
+// different sort
+func (s *Spec) signature() string {
+	parts := make([]string, 0)
+	for _, key := range s.keys() {
+		if key.Desc {
+			parts = append(parts, "-"+key.Field)
+		} else {
+			parts = append(parts, key.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseCursor decodes a cursor string produced by this spec. An empty string
+// yields a cursor pointing at the first page.
+func (s *Spec) ParseCursor(raw string) (*Cursor, error) {
+	keys := s.keys()
+	for _, key := range keys {
+		if key.Nullable {
+			return nil, &Error{Param: "sort", Field: key.Field, Message: "nullable fields cannot be used with cursor pagination"}
+		}
+	}
+
+	if raw == "" {
+		return &Cursor{Sort: s.signature()}, nil
+	}
+
+	invalid := &Error{Param: "cursor", Message: "malformed or expired cursor"}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, invalid
+	}
+	cursor := &Cursor{}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, invalid
+	}
+	if cursor.Sort != s.signature() {
+		return nil, &Error{Param: "cursor", Message: "cursor was created for a different sort order"}
+	}
+	if len(cursor.Values) != len(keys) {
+		return nil, invalid
+	}
+
+	for i, key := range keys {
+		value, err := parseValue(key.Type, cursor.Values[i])
+		if err != nil {
+			return nil, invalid
+		}
+		cursor.parsed = append(cursor.parsed, value)
+	}
+	return cursor, nil
+}
+
+// CursorAt creates a cursor pointing at a record
+func (s *Spec) CursorAt(row Row, backward bool) string {
+	cursor := &Cursor{Sort: s.signature(), Backward: backward}
+	for _, key := range s.keys() {
+		value, _ := row(key.Column)
+		cursor.Values = append(cursor.Values, formatValue(value))
+	}
+	return cursor.Encode()
+}
+
+// formatValue converts a column value to the raw form parsed by parseValue
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case *time.Time:
+		if v != nil {
+			return v.Format(time.RFC3339Nano)
+		}
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ApplyCursor restricts a GORM query to the rows after the cursor (before it
+// for backward cursors) and orders it by the sort keys, reversed for backward
+// cursors. Callers must reverse the rows of a backward page.
+func (s *Spec) ApplyCursor(db *gorm.DB, cursor *Cursor) *gorm.DB {
+	keys := s.keys()
+
+	if !cursor.IsFirstPage() {
+		// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... with ">" flipped for
+		// descending keys and again for backward cursors
+		alternatives := make([]clause.Expression, 0, len(keys))
+		for i, key := range keys {
+			conditions := make([]clause.Expression, 0, i+1)
+			for j := 0; j < i; j++ {
+				conditions = append(conditions, clause.Eq{Column: clause.Column{Name: keys[j].Column}, Value: cursor.parsed[j]})
+			}
+
+			column := clause.Column{Name: key.Column}
+			if key.Desc != cursor.Backward {
+				conditions = append(conditions, clause.Lt{Column: column, Value: cursor.parsed[i]})
+			} else {
+				conditions = append(conditions, clause.Gt{Column: column, Value: cursor.parsed[i]})
+			}
+			alternatives = append(alternatives, clause.And(conditions...))
+		}
+		db = db.Where(clause.Or(alternatives...))
+	}
+
+	for _, key := range keys {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: key.Column}, Desc: key.Desc != cursor.Backward})
+	}
+	return db
+}
+
+// After reports whether an in-memory record comes after the cursor in the
+// direction of the cursor
+func (s *Spec) After(row Row, cursor *Cursor) bool {
+	if cursor.IsFirstPage() {
+		return true
+	}
+
+	for i, key := range s.keys() {
+		value, _ := row(key.Column)
+		result := Compare(value, cursor.parsed[i])
+		if key.Desc != cursor.Backward {
+			result = -result
+		}
+		if result != 0 {
+			return result > 0
+		}
+	}
+	return false
+}
+
+// CursorPage computes the cursors around a fetched page. rows holds the page
+// in list order, fetched with one extra row to detect whether more rows
+// follow in the direction of the cursor; the extra row is trimmed.
+func CursorPage[T any](s *Spec, cursor *Cursor, rows []T, pageSize int, row func(T) Row) (page []T, next, prev string) {
+	hasMore := len(rows) > pageSize
+	if hasMore {
+		if cursor.Backward {
+			rows = rows[1:]
+		} else {
+			rows = rows[:pageSize]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, "", ""
+	}
+
+	first, last := row(rows[0]), row(rows[len(rows)-1])
+	if cursor.Backward {
+		// Moving back: there is always a next page, the one we came from
+		next = s.CursorAt(last, false)
+		if hasMore {
+			prev = s.CursorAt(first, true)
+		}
+	} else {
+		if hasMore {
+			next = s.CursorAt(last, false)
+		}
+		if !cursor.IsFirstPage() {
+			prev = s.CursorAt(first, true)
+		}
+	}
+	return rows, next, prev
+}
@@ -0,0 +1,138 @@
+package query
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"testing"
+)
+
+type pagedRow struct {
+	id     int
+	salary int
+	name   string
+}
+
+func (r pagedRow) row(column string) (interface{}, bool) {
+	switch column {
+	case "id":
+		return r.id, true
+	case "salary":
+		return r.salary, true
+	case "name":
+		return r.name, true
+	}
+	return nil, false
+}
+
+// page reads the page of the rows at the cursor the way the in-memory
+// repositories do, returning the IDs on it and the cursors around it
+func page(t *testing.T, spec *Spec, rows []pagedRow, raw string, pageSize int) (ids []int, next, prev string) {
+	t.Helper()
+	cursor, err := spec.ParseCursor(raw)
+	if err != nil {
+		t.Fatalf("parsing cursor %q: %v", raw, err)
+	}
+	sorted := slices.Clone(rows)
+	sort.SliceStable(sorted, func(i, j int) bool { return spec.Less(sorted[i].row, sorted[j].row) })
+
+	after := make([]pagedRow, 0)
+	for _, r := range sorted {
+		if spec.After(r.row, cursor) {
+			after = append(after, r)
+		}
+	}
+	if len(after) > pageSize+1 {
+		if cursor.Backward {
+			after = after[len(after)-pageSize-1:]
+		} else {
+			after = after[:pageSize+1]
+		}
+	}
+
+	found, next, prev := CursorPage(spec, cursor, after, pageSize, func(r pagedRow) Row { return r.row })
+	for _, r := range found {
+		ids = append(ids, r.id)
+	}
+	return ids, next, prev
+}
+
+func TestCursorPagesVisitEveryRowOnce(t *testing.T) {
+	schema := Schema{
+		"salary": {Column: "salary", Type: Int, Sortable: true},
+		"name":   {Column: "name", Type: String, Sortable: true},
+	}
+	rows := []pagedRow{
+		{id: 1, salary: 3000, name: "Ana"}, {id: 2, salary: 2500, name: "Ion"},
+		{id: 3, salary: 3000, name: "Eva"}, {id: 4, salary: 4000, name: "Dan"},
+		{id: 5, salary: 3000, name: "Ana"}, {id: 6, salary: 2500, name: "Bob"},
+		{id: 7, salary: 3500, name: "Cat"},
+	}
+	spec, err := Parse("", "-salary,name", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{4, 7, 1, 5, 3, 6, 2}
+
+	var forward []int
+	var pages []string
+	raw := ""
+	for {
+		pages = append(pages, raw)
+		ids, next, _ := page(t, spec, rows, raw, 3)
+		forward = append(forward, ids...)
+		if next == "" {
+			break
+		}
+		raw = next
+	}
+	if !slices.Equal(forward, want) {
+		t.Fatalf("expected the rows %v going forward, got %v", want, forward)
+	}
+
+	// Going back from the last page visits the same pages in reverse
+	_, _, prev := page(t, spec, rows, raw, 3)
+	var backward [][]int
+	for prev != "" {
+		var ids []int
+		ids, _, prev = page(t, spec, rows, prev, 3)
+		backward = append(backward, ids)
+	}
+	if len(backward) != len(pages)-1 || !slices.Equal(backward[len(backward)-1], want[:3]) {
+		t.Fatalf("expected to get back to the first page %v, got %v", want[:3], backward)
+	}
+}
+
+func TestParseCursorErrors(t *testing.T) {
+	schema := Schema{
+		"salary":   {Column: "salary", Type: Int, Sortable: true},
+		"name":     {Column: "name", Type: String, Sortable: true},
+		"end_date": {Column: "end_date", Type: Time, Nullable: true, Sortable: true},
+	}
+	bySalary, _ := Parse("", "salary", schema)
+	byName, _ := Parse("", "name", schema)
+	byEnd, _ := Parse("", "end_date", schema)
+	cursor := bySalary.CursorAt(pagedRow{id: 1, salary: 3000}.row, false)
+
+	if _, err := bySalary.ParseCursor(cursor); err != nil {
+		t.Fatalf("expected the cursor to parse, got %v", err)
+	}
+	tests := []struct {
+		name  string
+		spec  *Spec
+		raw   string
+		param string
+	}{
+		{name: "other sort", spec: byName, raw: cursor, param: "cursor"},
+		{name: "not base64", spec: bySalary, raw: "%%%", param: "cursor"},
+		{name: "not json", spec: bySalary, raw: "bm90IGpzb24", param: "cursor"},
+		{name: "nullable key", spec: byEnd, raw: "", param: "sort"},
+	}
+	for _, tt := range tests {
+		_, err := tt.spec.ParseCursor(tt.raw)
+		var queryErr *Error
+		if !errors.As(err, &queryErr) || queryErr.Param != tt.param {
+			t.Errorf("%s: expected a %s error, got %v", tt.name, tt.param, err)
+		}
+	}
+}
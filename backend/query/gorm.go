@@ -0,0 +1,61 @@
+package query
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ApplyFilters adds the filter conditions to a GORM query as parameterized clauses
+func (s *Spec) ApplyFilters(db *gorm.DB) *gorm.DB {
+	if s == nil {
+		return db
+	}
+
+	for _, condition := range s.Conditions {
+		column := clause.Column{Name: condition.Column}
+		value := condition.Values[0]
+
+		switch condition.Op {
+		case OpEq:
+			db = db.Where(clause.Eq{Column: column, Value: value})
+		case OpNe:
+			db = db.Where(clause.Neq{Column: column, Value: value})
+		case OpGt:
+			db = db.Where(clause.Gt{Column: column, Value: value})
+		case OpGte:
+			db = db.Where(clause.Gte{Column: column, Value: value})
+		case OpLt:
+			db = db.Where(clause.Lt{Column: column, Value: value})
+		case OpLte:
+			db = db.Where(clause.Lte{Column: column, Value: value})
+		case OpIn:
+			db = db.Where(clause.IN{Column: column, Values: condition.Values})
+		case OpNotIn:
+			db = db.Where(clause.Not(clause.IN{Column: column, Values: condition.Values}))
+		case OpLike:
+			db = db.Where(clause.Expr{SQL: "LOWER(?) LIKE ? ESCAPE '\\'", Vars: []interface{}{column, LikePattern(value.(string))}})
+		}
+	}
+	return db
+}
+
+// likeEscaper escapes the wildcards of LIKE, and the escape character itself
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// LikePattern returns the pattern matching the lowercased text containing a
+// term, taken literally, for "LOWER(column) LIKE ? ESCAPE '\'"
+func LikePattern(term string) string {
+	return "%" + likeEscaper.Replace(strings.ToLower(term)) + "%"
+}
+
+// ApplySort adds the sort keys to a GORM query. The primary key is appended
+// as a final tie-breaker so that pages are stable; without sort keys the
+// query is sorted by it alone.
+func (s *Spec) ApplySort(db *gorm.DB) *gorm.DB {
+	for _, key := range s.keys() {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: key.Column}, Desc: key.Desc})
+	}
+	return db
+}
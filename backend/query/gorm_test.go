@@ -0,0 +1,83 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+type sortedRow struct {
+	ID     uint
+	Name   string
+	Salary int
+}
+
+// dryRun opens a database that builds statements without running them
+func dryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// sortSQL returns the ORDER BY clause ApplySort gives a query
+func sortSQL(t *testing.T, spec *Spec) string {
+	t.Helper()
+	var rows []sortedRow
+	sql := spec.ApplySort(dryRun(t).Model(&sortedRow{})).Find(&rows).Statement.SQL.String()
+	if i := strings.Index(sql, "ORDER BY"); i >= 0 {
+		return sql[i:]
+	}
+	return ""
+}
+
+func TestApplySortAppendsTheID(t *testing.T) {
+	schema := Schema{
+		"name":   {Column: "name", Type: String, Sortable: true},
+		"salary": {Column: "salary", Type: Int, Sortable: true},
+	}
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{sort: "", want: "ORDER BY `id`"},
+		{sort: "name", want: "ORDER BY `name`,`id`"},
+		{sort: "-salary,name", want: "ORDER BY `salary` DESC,`name`,`id`"},
+		{sort: "-salary", want: "ORDER BY `salary` DESC,`id` DESC"},
+	}
+	for _, tt := range tests {
+		spec, err := Parse("", tt.sort, schema)
+		if err != nil {
+			t.Fatalf("parsing %q: %v", tt.sort, err)
+		}
+		if got := sortSQL(t, spec); got != tt.want {
+			t.Errorf("sort %q: expected %q, got %q", tt.sort, tt.want, got)
+		}
+	}
+	if got := sortSQL(t, nil); got != "ORDER BY `id`" {
+		t.Errorf("nil spec: expected ORDER BY `id`, got %q", got)
+	}
+}
+
+func TestApplyFiltersIsParameterized(t *testing.T) {
+	spec, err := Parse("name:like:50%_off;position:in:welder,mason;salary>=3000", "", workerSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []sortedRow
+	statement := spec.ApplyFilters(dryRun(t).Model(&sortedRow{})).Find(&rows).Statement
+
+	want := "SELECT * FROM `sorted_rows` WHERE LOWER(`name`) LIKE ? ESCAPE '\\' AND `position` IN (?,?) AND `salary` >= ?"
+	if got := statement.SQL.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	wantVars := []interface{}{`%50\%\_off%`, "welder", "mason", 3000}
+	if !reflect.DeepEqual(statement.Vars, wantVars) {
+		t.Errorf("expected arguments %q, got %q", wantVars, statement.Vars)
+	}
+}
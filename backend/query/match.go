@@ -0,0 +1,143 @@
+package query
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Row returns the value of a column of an in-memory record, and whether the
+// column exists
+type Row func(column string) (interface{}, bool)
+
+// Matches evaluates the filter conditions against an in-memory record with
+// SQL semantics: a condition on a NULL value never matches
+func (s *Spec) Matches(row Row) bool {
+	if s == nil {
+		return true
+	}
+
+	for _, condition := range s.Conditions {
+		value, ok := row(condition.Column)
+		if !ok || isNull(value) {
+			return false
+		}
+		if !condition.matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Condition) matches(value interface{}) bool {
+	switch c.Op {
+	case OpEq:
+		return Compare(value, c.Values[0]) == 0
+	case OpNe:
+		return Compare(value, c.Values[0]) != 0
+	case OpGt:
+		return Compare(value, c.Values[0]) > 0
+	case OpGte:
+		return Compare(value, c.Values[0]) >= 0
+	case OpLt:
+		return Compare(value, c.Values[0]) < 0
+	case OpLte:
+		return Compare(value, c.Values[0]) <= 0
+	case OpIn, OpNotIn:
+		found := false
+		for _, candidate := range c.Values {
+			if Compare(value, candidate) == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (c.Op == OpIn)
+	case OpLike:
+		return strings.Contains(strings.ToLower(fmt.Sprint(value)), strings.ToLower(c.Values[0].(string)))
+	}
+	return false
+}
+
+// Less orders two in-memory records by the sort keys, falling back to the
+// "id" column like ApplySort
+func (s *Spec) Less(a, b Row) bool {
+	for _, key := range s.keys() {
+		av, _ := a(key.Column)
+		bv, _ := b(key.Column)
+		result := compareNullable(av, bv)
+		if key.Desc {
+			result = -result
+		}
+		if result != 0 {
+			return result < 0
+		}
+	}
+	return false
+}
+
+// compareNullable compares two values, ordering NULL last like Postgres does
+// for ascending sorts
+func compareNullable(a, b interface{}) int {
+	switch {
+	case isNull(a) && isNull(b):
+		return 0
+	case isNull(a):
+		return 1
+	case isNull(b):
+		return -1
+	}
+	return Compare(a, b)
+}
+
+// Compare orders two column values. Numbers are compared numerically and
+// times chronologically; anything else is compared as text.
+func Compare(a, b interface{}) int {
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			return cmp.Compare(af, bf)
+		}
+	}
+	if at, ok := toTime(a); ok {
+		if bt, ok := toTime(b); ok {
+			return at.Compare(bt)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func isNull(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	if t, ok := value.(*time.Time); ok && t == nil {
+		return true
+	}
+	return false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v != nil {
+			return *v, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,270 @@
+// Package query implements the filter and sort language of the list
+// endpoints, e.g. "filter=salary>=3000;position:in:welder,mason" and
+// "sort=-start_date,name". Expressions are validated against a per-entity
+// whitelist of fields and compiled to parameterized GORM clauses.
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType is the type used to parse the values compared against a field
+type FieldType int
+
+const (
+	String FieldType = iota
+	Int
+	Float
+	Time
+)
+
+// Field describes a field clients may filter or sort on
+type Field struct {
+	Column     string
+	Type       FieldType
+	Nullable   bool
+	Filterable bool
+	Sortable   bool
+}
+
+// Schema is the whitelist of fields of an entity, keyed by their API name
+type Schema map[string]Field
+
+// Operator is a comparison operator of a filter condition
+type Operator string
+
+const (
+	OpEq    Operator = "eq"
+	OpNe    Operator = "ne"
+	OpGt    Operator = "gt"
+	OpGte   Operator = "gte"
+	OpLt    Operator = "lt"
+	OpLte   Operator = "lte"
+	OpIn    Operator = "in"
+	OpNotIn Operator = "nin"
+	OpLike  Operator = "like"
+)
+
+// symbolOperators maps the symbolic operators to their names. Longer symbols
+// come first so that ">=" is not read as ">".
+var symbolOperators = []struct {
+	symbol string
+	op     Operator
+}{
+	{">=", OpGte},
+	{"<=", OpLte},
+	{"!=", OpNe},
+	{">", OpGt},
+	{"<", OpLt},
+	{"=", OpEq},
+}
+
+// Condition is a single validated filter condition
+type Condition struct {
+	Field  string
+	Column string
+	Op     Operator
+	Values []interface{}
+}
+
+// SortField is a single validated sort key
+type SortField struct {
+	Field    string
+	Column   string
+	Type     FieldType
+	Nullable bool
+	Desc     bool
+}
+
+// Spec is a parsed and validated filter and sort expression
+type Spec struct {
+	Conditions []Condition
+	Sort       []SortField
+}
+
+// Error reports an invalid filter or sort expression
+type Error struct {
+	Param   string
+	Field   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("invalid %s: field %q: %s", e.Param, e.Field, e.Message)
+	}
+	return fmt.Sprintf("invalid %s: %s", e.Param, e.Message)
+}
+
+// Parse parses the filter and sort expressions against the schema. Either
+// expression may be empty.
+//
+// A filter is a list of conditions separated by ";". A condition is either
+// "field<op>value" with op one of =, !=, >, >=, <, <=, or "field:op:value"
+// with op one of eq, ne, gt, gte, lt, lte, in, nin, like. The in and nin
+// operators take a comma separated list of values.
+//
+// A sort is a comma separated list of fields, each optionally prefixed with
+// "-" for descending order.
+func Parse(filter, sort string, schema Schema) (*Spec, error) {
+	spec := &Spec{}
+
+	for _, raw := range strings.Split(filter, ";") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		field, op, value, err := splitCondition(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := spec.AddCondition(schema, field, op, value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, raw := range strings.Split(sort, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		desc := strings.HasPrefix(raw, "-")
+		field := strings.TrimPrefix(strings.TrimPrefix(raw, "-"), "+")
+		if err := spec.AddSort(schema, field, desc); err != nil {
+			return nil, err
+		}
+	}
+
+	return spec, nil
+}
+
+// splitCondition splits a raw condition into field, operator and value
+func splitCondition(raw string) (string, Operator, string, error) {
+	// Named operator form: field:op:value
+	if field, rest, ok := strings.Cut(raw, ":"); ok && isIdentifier(field) {
+		name, value, ok := strings.Cut(rest, ":")
+		if !ok {
+			return "", "", "", &Error{Param: "filter", Field: field, Message: "expected field:operator:value"}
+		}
+		op := Operator(strings.ToLower(name))
+		switch op {
+		case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNotIn, OpLike:
+			return field, op, value, nil
+		}
+		return "", "", "", &Error{Param: "filter", Field: field, Message: fmt.Sprintf("unknown operator %q", name)}
+	}
+
+	// Symbolic form: field>=value
+	index := strings.IndexAny(raw, "=!<>")
+	if index <= 0 {
+		return "", "", "", &Error{Param: "filter", Message: fmt.Sprintf("malformed condition %q", raw)}
+	}
+	field := strings.TrimSpace(raw[:index])
+	rest := raw[index:]
+	for _, candidate := range symbolOperators {
+		if strings.HasPrefix(rest, candidate.symbol) {
+			return field, candidate.op, strings.TrimSpace(rest[len(candidate.symbol):]), nil
+		}
+	}
+	return "", "", "", &Error{Param: "filter", Field: field, Message: fmt.Sprintf("malformed condition %q", raw)}
+}
+
+// isIdentifier reports whether s looks like a field name
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddCondition validates a condition against the schema and adds it to the spec
+func (s *Spec) AddCondition(schema Schema, field string, op Operator, value string) error {
+	def, ok := schema[field]
+	if !ok || !def.Filterable {
+		return &Error{Param: "filter", Field: field, Message: "unknown or non-filterable field"}
+	}
+
+	raws := []string{value}
+	if op == OpIn || op == OpNotIn {
+		raws = strings.Split(value, ",")
+	}
+	if op == OpLike && def.Type != String {
+		return &Error{Param: "filter", Field: field, Message: "like is only supported on text fields"}
+	}
+
+	values := make([]interface{}, 0, len(raws))
+	for _, raw := range raws {
+		parsed, err := parseValue(def.Type, strings.TrimSpace(raw))
+		if err != nil {
+			return &Error{Param: "filter", Field: field, Message: err.Error()}
+		}
+		values = append(values, parsed)
+	}
+
+	s.Conditions = append(s.Conditions, Condition{
+		Field:  field,
+		Column: def.Column,
+		Op:     op,
+		Values: values,
+	})
+	return nil
+}
+
+// AddSort validates a sort key against the schema and adds it to the spec
+func (s *Spec) AddSort(schema Schema, field string, desc bool) error {
+	def, ok := schema[field]
+	if !ok || !def.Sortable {
+		return &Error{Param: "sort", Field: field, Message: "unknown or non-sortable field"}
+	}
+	for _, existing := range s.Sort {
+		if existing.Field == field {
+			return &Error{Param: "sort", Field: field, Message: "field listed more than once"}
+		}
+	}
+
+	s.Sort = append(s.Sort, SortField{
+		Field:    field,
+		Column:   def.Column,
+		Type:     def.Type,
+		Nullable: def.Nullable,
+		Desc:     desc,
+	})
+	return nil
+}
+
+// parseValue converts a raw value to the type of the field
+func parseValue(fieldType FieldType, raw string) (interface{}, error) {
+	switch fieldType {
+	case Int:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", raw)
+		}
+		return value, nil
+	case Float:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return value, nil
+	case Time:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if value, err := time.Parse(layout, raw); err == nil {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not a date (use YYYY-MM-DD or RFC 3339)", raw)
+	default:
+		return raw, nil
+	}
+}
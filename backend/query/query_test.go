@@ -0,0 +1,126 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// workerSchema is a whitelist like the one of the worker list
+var workerSchema = Schema{
+	"name":       {Column: "name", Type: String, Filterable: true, Sortable: true},
+	"position":   {Column: "position", Type: String, Filterable: true, Sortable: true},
+	"salary":     {Column: "salary", Type: Int, Filterable: true, Sortable: true},
+	"rating":     {Column: "rating", Type: Float, Filterable: true},
+	"start_date": {Column: "start_date", Type: Time, Nullable: true, Filterable: true, Sortable: true},
+	"notes":      {Column: "notes", Type: String},
+}
+
+func TestParse(t *testing.T) {
+	spec, err := Parse("salary>=3000; position:in:welder, mason ;name:like:an;start_date<2026-03-01;rating!=4.5",
+		"-start_date,+name", workerSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Condition{
+		{Field: "salary", Column: "salary", Op: OpGte, Values: []interface{}{3000}},
+		{Field: "position", Column: "position", Op: OpIn, Values: []interface{}{"welder", "mason"}},
+		{Field: "name", Column: "name", Op: OpLike, Values: []interface{}{"an"}},
+		{Field: "start_date", Column: "start_date", Op: OpLt, Values: []interface{}{time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}},
+		{Field: "rating", Column: "rating", Op: OpNe, Values: []interface{}{4.5}},
+	}
+	if !reflect.DeepEqual(spec.Conditions, want) {
+		t.Errorf("expected conditions %+v, got %+v", want, spec.Conditions)
+	}
+
+	wantSort := []SortField{
+		{Field: "start_date", Column: "start_date", Type: Time, Nullable: true, Desc: true},
+		{Field: "name", Column: "name", Type: String},
+	}
+	if !reflect.DeepEqual(spec.Sort, wantSort) {
+		t.Errorf("expected sort %+v, got %+v", wantSort, spec.Sort)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	spec, err := Parse(" ; ", "", workerSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Conditions) != 0 || len(spec.Sort) != 0 {
+		t.Fatalf("expected an empty spec, got %+v", spec)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		filter string
+		sort   string
+		param  string
+		field  string
+	}{
+		{filter: "password=secret", param: "filter", field: "password"},
+		{filter: "notes=late", param: "filter", field: "notes"},
+		{filter: "salary:between:1,2", param: "filter", field: "salary"},
+		{filter: "salary:3000", param: "filter", field: "salary"},
+		{filter: "salary>=lots", param: "filter", field: "salary"},
+		{filter: "salary:like:3", param: "filter", field: "salary"},
+		{filter: "start_date>yesterday", param: "filter", field: "start_date"},
+		{filter: "=3000", param: "filter"},
+		{filter: "salary", param: "filter"},
+		{sort: "rating", param: "sort", field: "rating"},
+		{sort: "name; DROP TABLE workers", param: "sort", field: "name; DROP TABLE workers"},
+		{sort: "name,-name", param: "sort", field: "name"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.filter, tt.sort, workerSchema)
+		var queryErr *Error
+		if !errors.As(err, &queryErr) {
+			t.Errorf("filter %q sort %q: expected a query error, got %v", tt.filter, tt.sort, err)
+			continue
+		}
+		if queryErr.Param != tt.param || queryErr.Field != tt.field {
+			t.Errorf("filter %q sort %q: expected %s field %q, got %s field %q",
+				tt.filter, tt.sort, tt.param, tt.field, queryErr.Param, queryErr.Field)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	start := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	row := func(values map[string]interface{}) Row {
+		return func(column string) (interface{}, bool) {
+			value, ok := values[column]
+			return value, ok
+		}
+	}
+	ana := row(map[string]interface{}{"name": "Ana Pop", "position": "Mason", "salary": 3500, "start_date": &start})
+	ion := row(map[string]interface{}{"name": "Ion Rus", "position": "Welder", "salary": 2500, "start_date": (*time.Time)(nil)})
+
+	tests := []struct {
+		filter string
+		ana    bool
+		ion    bool
+	}{
+		{filter: "", ana: true, ion: true},
+		{filter: "salary>=3000", ana: true, ion: false},
+		{filter: "position:nin:mason,Mason", ana: false, ion: true},
+		{filter: "name:like:POP", ana: true, ion: false},
+		{filter: "start_date<2026-03-01", ana: true, ion: false},
+		{filter: "start_date!=2026-03-01", ana: true, ion: false},
+	}
+	for _, tt := range tests {
+		spec, err := Parse(tt.filter, "", workerSchema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := spec.Matches(ana); got != tt.ana {
+			t.Errorf("filter %q: expected Ana to match %v", tt.filter, tt.ana)
+		}
+		if got := spec.Matches(ion); got != tt.ion {
+			t.Errorf("filter %q: expected Ion to match %v", tt.filter, tt.ion)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"gorm.io/gorm"
+)
+
+// AvailableWorkers selects the workers that can be assigned to a project
+type AvailableWorkers struct {
+	ProjectID uint
+	// ExcludeBusy also leaves out the workers assigned to another active
+	// project for some of the days of the project
+	ExcludeBusy bool
+}
+
+// Assignment is a worker assignment together with both of its sides
+type Assignment struct {
+	ProjectID     uint   `json:"project_id"`
+	ProjectName   string `json:"project_name"`
+	ProjectStatus string `json:"project_status"`
+	// ProjectStartDate and ProjectEndDate are the dates of the project
+	ProjectStartDate time.Time  `json:"project_start_date"`
+	ProjectEndDate   *time.Time `json:"project_end_date"`
+	WorkerID         uint       `json:"worker_id"`
+	WorkerName       string     `json:"worker_name"`
+	Position         string     `json:"position"`
+	Salary           int        `json:"salary"`
+	model.AssignmentDetails
+}
+
+// assignments selects the assignments of the user together with both of
+// their sides, leaving out trashed workers and projects
+func assignments(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Table("worker_projects").
+		Select(`projects.id AS project_id, projects.name AS project_name, projects.status AS project_status,
+			projects.start_date AS project_start_date, projects.end_date AS project_end_date,
+			workers.id AS worker_id, workers.name AS worker_name, workers.position, workers.salary,
+			worker_projects.role, worker_projects.start_date, worker_projects.end_date,
+			worker_projects.allocation_percent, worker_projects.day_rate, worker_projects.notes`).
+		Joins("JOIN projects ON projects.id = worker_projects.project_id AND projects.deleted_at IS NULL").
+		Joins("JOIN workers ON workers.id = worker_projects.worker_id AND workers.deleted_at IS NULL").
+		Where("worker_projects.user_id = ? AND projects.user_id = ? AND workers.user_id = ?", userID, userID, userID)
+}
+
+// EachAssignment calls fn with the assignments to the matching projects,
+// one row at a time
+func (r *projectRepository) EachAssignment(ctx context.Context, userID uint, opts ListOptions, fn func(Assignment) error) error {
+	projects := r.filtered(ctx, userID, opts).Select("id")
+	query := assignments(r.db.WithContext(ctx), userID).
+		Where("worker_projects.project_id IN (?)", projects).
+		Order("projects.name, projects.id, workers.name, workers.id, worker_projects.id")
+	return eachRow(query, fn)
+}
+
+// scheduledAssignments reads the assignments of the user's workers, or of
+// one worker when workerID is not 0
+func scheduledAssignments(db *gorm.DB, userID uint, workerID uint) ([]Assignment, error) {
+	query := assignments(db, userID)
+	if workerID != 0 {
+		query = query.Where("worker_projects.worker_id = ?", workerID)
+	}
+
+	var rows []Assignment
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// currentAssignments keeps the assignments that have not ended before today
+func currentAssignments(db *gorm.DB) *gorm.DB {
+	return db.Where("(worker_projects.end_date IS NULL OR worker_projects.end_date >= ?)", day(time.Now()))
+}
+
+// assignmentsOf reads the assignments of a worker to a project of the user,
+// oldest first, leaving them out when either side is trashed
+func assignmentsOf(db *gorm.DB, projectID, workerID, userID uint) ([]model.WorkerProject, error) {
+	var rows []model.WorkerProject
+	err := db.
+		Joins("JOIN projects ON projects.id = worker_projects.project_id AND projects.deleted_at IS NULL").
+		Joins("JOIN workers ON workers.id = worker_projects.worker_id AND workers.deleted_at IS NULL").
+		Where("worker_projects.project_id = ? AND worker_projects.worker_id = ? AND worker_projects.user_id = ?", projectID, workerID, userID).
+		Order("worker_projects.id").
+		Find(&rows).Error
+	return rows, err
+}
+
+// FitAssignment places an assignment among the other assignments of its
+// worker to its project. Without a start date of its own, it starts the day
+// after the last of them ends. It fails with gorm.ErrDuplicatedKey when it
+// shares a day with one of them, e.g. when the worker is still on the project.
+func FitAssignment(assignment *model.WorkerProject, project model.Project, others []model.WorkerProject) error {
+	if assignment.StartDate == nil {
+		var last *time.Time
+		for _, other := range others {
+			if other.ID == assignment.ID {
+				continue
+			}
+			// Without an end date of its own, the other assignment runs
+			// until the end of the project
+			if other.EndDate == nil {
+				return gorm.ErrDuplicatedKey
+			}
+			if last == nil || other.EndDate.After(*last) {
+				last = other.EndDate
+			}
+		}
+		if last != nil {
+			start := day(*last).AddDate(0, 0, 1)
+			if project.EndDate != nil && start.After(day(*project.EndDate)) {
+				return gorm.ErrDuplicatedKey
+			}
+			assignment.StartDate = &start
+		}
+	}
+
+	for _, other := range others {
+		if other.ID != assignment.ID && other.Overlaps(assignment.AssignmentDetails, project) {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	return nil
+}
+
+// assign stores a new assignment of a worker to a project after fitting it
+// among the others of the worker to the project, and records it in the
+// history of both
+func assign(ctx context.Context, tx *gorm.DB, worker model.Worker, project model.Project, assignment *model.WorkerProject) error {
+	others, err := assignmentsOf(tx, project.ID, worker.ID, assignment.UserID)
+	if err != nil {
+		return err
+	}
+	if err := FitAssignment(assignment, project, others); err != nil {
+		return err
+	}
+	if err := tx.Create(assignment).Error; err != nil {
+		return err
+	}
+	return recordChanges(tx, AssignmentChanges(ctx, worker, project, model.LogTypeAssign, assignment.UserID)...)
+}
+
+// EndAssignment takes a worker off a project as of the day of now: the
+// assignment ends the day before and is kept as history. It reports false
+// for an assignment that has not started yet, which has no days to keep and
+// is withdrawn instead.
+func EndAssignment(assignment *model.WorkerProject, project model.Project, now time.Time) bool {
+	if !assignment.Started(project, now) {
+		return false
+	}
+	end := day(now).AddDate(0, 0, -1)
+	if assignment.EndDate == nil || assignment.EndDate.After(end) {
+		assignment.EndDate = &end
+	}
+	return true
+}
+
+// endAssignment stores the end of an assignment, or withdraws it when it has
+// not started yet
+func endAssignment(tx *gorm.DB, assignment *model.WorkerProject, project model.Project) error {
+	if !EndAssignment(assignment, project, time.Now()) {
+		return tx.Delete(assignment).Error
+	}
+	return tx.Model(assignment).Update("end_date", assignment.EndDate).Error
+}
+
+// unassign ends the latest assignment of a worker to a project, the one
+// GetAssignment returns, and records it in the history of both. Nothing
+// happens when the worker has no assignment to the project or it already
+// ended.
+func unassign(ctx context.Context, tx *gorm.DB, worker model.Worker, project model.Project, userID uint) error {
+	var assignment model.WorkerProject
+	err := tx.Where("worker_id = ? AND project_id = ? AND user_id = ?", worker.ID, project.ID, userID).
+		Order("id DESC").First(&assignment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if assignment.Ended(time.Now()) {
+		return nil
+	}
+	if err := endAssignment(tx, &assignment, project); err != nil {
+		return err
+	}
+	return recordChanges(tx, AssignmentChanges(ctx, worker, project, model.LogTypeUnassign, userID)...)
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"gorm.io/gorm"
+)
+
+// assignedWorker creates a worker of the user assigned to a new project
+// starting on the date
+func assignedWorker(t *testing.T, db *gorm.DB, userID uint, start time.Time) (model.Worker, model.Project) {
+	t.Helper()
+	ctx := context.Background()
+	worker := model.Worker{Name: "Ana Pop", Age: 30, Position: "Mason", Salary: 3000, UserID: userID}
+	if err := NewWorkerRepository(db).Create(ctx, &worker); err != nil {
+		t.Fatal(err)
+	}
+	project := model.Project{Name: "North", Description: "A test building site", Status: "active",
+		StartDate: start, Latitude: 46.77, Longitude: 23.59, UserID: userID}
+	if err := NewProjectRepository(db).Create(ctx, &project); err != nil {
+		t.Fatal(err)
+	}
+	assignment := model.NewAssignment(worker.ID, project.ID, userID)
+	if err := NewProjectRepository(db).AddWorker(ctx, &assignment); err != nil {
+		t.Fatal(err)
+	}
+	return worker, project
+}
+
+// assignmentRows reads every assignment of the worker to the project
+func assignmentRows(t *testing.T, db *gorm.DB, workerID, projectID uint) []model.WorkerProject {
+	t.Helper()
+	var rows []model.WorkerProject
+	if err := db.Where("worker_id = ? AND project_id = ?", workerID, projectID).Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+// expectEndedYesterday fails the test unless the worker has exactly one
+// assignment to the project, ending yesterday
+func expectEndedYesterday(t *testing.T, db *gorm.DB, workerID, projectID uint) {
+	t.Helper()
+	rows := assignmentRows(t, db, workerID, projectID)
+	if len(rows) != 1 {
+		t.Fatalf("expected the assignment to be kept, got %d rows", len(rows))
+	}
+	yesterday := day(time.Now()).AddDate(0, 0, -1)
+	if rows[0].EndDate == nil || !day(*rows[0].EndDate).Equal(yesterday) {
+		t.Fatalf("expected the assignment to end on %s, got %v", yesterday.Format(time.DateOnly), rows[0].EndDate)
+	}
+}
+
+func TestRemoveWorkerKeepsTheAssignmentAsHistory(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	const userID = 1
+	worker, project := assignedWorker(t, db, userID, day(time.Now()).AddDate(0, -2, 0))
+	projects := NewProjectRepository(db)
+
+	if err := projects.RemoveWorker(ctx, project.ID, worker.ID, userID); err != nil {
+		t.Fatal(err)
+	}
+	expectEndedYesterday(t, db, worker.ID, project.ID)
+
+	current, err := projects.GetByID(ctx, project.ID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current.Workers) != 0 {
+		t.Fatalf("expected no current workers, got %d", len(current.Workers))
+	}
+	history, err := projects.GetWithHistory(ctx, project.ID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Workers) != 1 {
+		t.Fatalf("expected the ended assignment in the history, got %d workers", len(history.Workers))
+	}
+
+	// Removing the worker again leaves the ended assignment alone
+	if err := projects.RemoveWorker(ctx, project.ID, worker.ID, userID); err != nil {
+		t.Fatal(err)
+	}
+	expectEndedYesterday(t, db, worker.ID, project.ID)
+}
+
+func TestRemoveWorkerWithdrawsAnAssignmentNotStarted(t *testing.T) {
+	db := openTestDB(t)
+	const userID = 1
+	worker, project := assignedWorker(t, db, userID, day(time.Now()).AddDate(0, 0, 7))
+
+	if err := NewProjectRepository(db).RemoveWorker(context.Background(), project.ID, worker.ID, userID); err != nil {
+		t.Fatal(err)
+	}
+	if rows := assignmentRows(t, db, worker.ID, project.ID); len(rows) != 0 {
+		t.Fatalf("expected the assignment to be withdrawn, got %d rows", len(rows))
+	}
+}
+
+func TestProjectUpdateEndsTheAssignmentsOfDroppedWorkers(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	const userID = 1
+	worker, project := assignedWorker(t, db, userID, day(time.Now()).AddDate(0, -2, 0))
+	other := model.Worker{Name: "Ion Rus", Age: 40, Position: "Welder", Salary: 3500, UserID: userID}
+	if err := NewWorkerRepository(db).Create(ctx, &other); err != nil {
+		t.Fatal(err)
+	}
+
+	update := model.Project{ID: project.ID, Workers: []model.Worker{{ID: other.ID}}}
+	if err := NewProjectRepository(db).Update(ctx, &update, userID); err != nil {
+		t.Fatal(err)
+	}
+	expectEndedYesterday(t, db, worker.ID, project.ID)
+	if rows := assignmentRows(t, db, other.ID, project.ID); len(rows) != 1 || rows[0].EndDate != nil {
+		t.Fatalf("expected the new worker to get an open assignment, got %+v", rows)
+	}
+}
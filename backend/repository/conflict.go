@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+)
+
+// Kinds of assignment conflicts
+const (
+	// ConflictOverAllocation is a worker allocated above 100% on some days
+	ConflictOverAllocation = "over_allocation"
+	// ConflictBeforeProject is an assignment starting before its project
+	ConflictBeforeProject = "before_project"
+	// ConflictAfterProject is an assignment running past the end of its project
+	ConflictAfterProject = "after_project"
+)
+
+// Conflict is a problem with the assignments of a worker
+type Conflict struct {
+	Kind       string `json:"kind"`
+	WorkerID   uint   `json:"worker_id"`
+	WorkerName string `json:"worker_name"`
+	// ProjectIDs are the projects of the conflicting assignments
+	ProjectIDs []uint `json:"project_ids"`
+	// StartDate and EndDate bound the days in conflict; a nil end date
+	// leaves the period open
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	// AllocationPercent is the highest allocation of an over-allocation
+	AllocationPercent int    `json:"allocation_percent,omitempty"`
+	Message           string `json:"message"`
+}
+
+// Involves reports whether one of the conflicting assignments is on the project
+func (c Conflict) Involves(projectID uint) bool {
+	return slices.Contains(c.ProjectIDs, projectID)
+}
+
+// FindConflicts checks the assignments of workers against each other and
+// against the dates of their projects. Assignments to cancelled projects
+// are ignored. Conflicts are sorted by worker, then by date.
+func FindConflicts(assignments []Assignment) []Conflict {
+	byWorker := make(map[uint][]Assignment)
+	for _, assignment := range assignments {
+		if assignment.ProjectStatus != model.ProjectStatusCancelled {
+			byWorker[assignment.WorkerID] = append(byWorker[assignment.WorkerID], assignment)
+		}
+	}
+
+	conflicts := make([]Conflict, 0)
+	for _, assignments := range byWorker {
+		conflicts = append(conflicts, outsideProject(assignments)...)
+		conflicts = append(conflicts, overAllocations(assignments)...)
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].WorkerName != conflicts[j].WorkerName {
+			return conflicts[i].WorkerName < conflicts[j].WorkerName
+		}
+		if conflicts[i].WorkerID != conflicts[j].WorkerID {
+			return conflicts[i].WorkerID < conflicts[j].WorkerID
+		}
+		return conflicts[i].StartDate.Before(conflicts[j].StartDate)
+	})
+	return conflicts
+}
+
+// Days returns the first and last days of the assignment, as dates; a nil
+// last day leaves it open
+func (a Assignment) Days() (time.Time, *time.Time) {
+	start, end := a.AssignmentDetails.Period(model.Project{StartDate: a.ProjectStartDate, EndDate: a.ProjectEndDate})
+	start = day(start)
+	if end != nil {
+		last := day(*end)
+		end = &last
+	}
+	return start, end
+}
+
+// outsideProject finds the days the assignments of a worker run before the
+// start or past the end of their project
+func outsideProject(assignments []Assignment) []Conflict {
+	var conflicts []Conflict
+	for _, a := range assignments {
+		start, end := a.Days()
+		projectStart := day(a.ProjectStartDate)
+		var projectEnd *time.Time
+		if a.ProjectEndDate != nil {
+			last := day(*a.ProjectEndDate)
+			projectEnd = &last
+		}
+		conflict := Conflict{
+			WorkerID:   a.WorkerID,
+			WorkerName: a.WorkerName,
+			ProjectIDs: []uint{a.ProjectID},
+		}
+
+		if start.Before(projectStart) {
+			before := projectStart.AddDate(0, 0, -1)
+			if end != nil && end.Before(before) {
+				before = *end
+			}
+			conflict.Kind, conflict.StartDate, conflict.EndDate = ConflictBeforeProject, start, &before
+			conflict.Message = fmt.Sprintf("%s is assigned to %s %s, before the project starts on %s",
+				a.WorkerName, a.ProjectName, describePeriod(start, &before), projectStart.Format(time.DateOnly))
+			conflicts = append(conflicts, conflict)
+		}
+		if projectEnd != nil && (end == nil || end.After(*projectEnd)) {
+			after := projectEnd.AddDate(0, 0, 1)
+			if start.After(after) {
+				after = start
+			}
+			conflict.Kind, conflict.StartDate, conflict.EndDate = ConflictAfterProject, after, end
+			conflict.Message = fmt.Sprintf("%s is assigned to %s %s, after the project ends on %s",
+				a.WorkerName, a.ProjectName, describePeriod(after, end), projectEnd.Format(time.DateOnly))
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts
+}
+
+// overAllocations finds the periods in which the allocations of a worker add
+// up to more than 100%. Consecutive days with the same assignments make up
+// one period.
+func overAllocations(assignments []Assignment) []Conflict {
+	// The allocation can only change on the first day of an assignment and
+	// on the day after its last one
+	var changes []time.Time
+	for _, a := range assignments {
+		start, end := a.Days()
+		changes = append(changes, start)
+		if end != nil {
+			changes = append(changes, end.AddDate(0, 0, 1))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Before(changes[j]) })
+	changes = slices.CompactFunc(changes, func(a, b time.Time) bool { return a.Equal(b) })
+
+	var conflicts []Conflict
+	var current *Conflict
+	for i, from := range changes {
+		// The period runs until the day before the next change, or forever
+		var until *time.Time
+		if i+1 < len(changes) {
+			last := changes[i+1].AddDate(0, 0, -1)
+			until = &last
+		}
+
+		total := 0
+		var projects []uint
+		var names []string
+		for _, a := range assignments {
+			start, end := a.Days()
+			if start.After(from) || (end != nil && end.Before(from)) {
+				continue
+			}
+			total += a.AllocationPercent
+			projects = append(projects, a.ProjectID)
+			names = append(names, a.ProjectName)
+		}
+
+		if total <= model.FullAllocation {
+			current = nil
+			continue
+		}
+		if current != nil && slices.Equal(current.ProjectIDs, projects) {
+			current.EndDate = until
+			current.AllocationPercent = max(current.AllocationPercent, total)
+			continue
+		}
+		conflicts = append(conflicts, Conflict{
+			Kind:              ConflictOverAllocation,
+			WorkerID:          assignments[0].WorkerID,
+			WorkerName:        assignments[0].WorkerName,
+			ProjectIDs:        projects,
+			StartDate:         from,
+			EndDate:           until,
+			AllocationPercent: total,
+			Message:           strings.Join(names, ", "),
+		})
+		current = &conflicts[len(conflicts)-1]
+	}
+
+	// Describe the periods once they are complete
+	for i := range conflicts {
+		c := &conflicts[i]
+		c.Message = fmt.Sprintf("%s is allocated %d%% %s across %s",
+			c.WorkerName, c.AllocationPercent, describePeriod(c.StartDate, c.EndDate), c.Message)
+	}
+	return conflicts
+}
+
+// day returns the date of a time, at midnight UTC
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// describePeriod formats a period of days for conflict messages
+func describePeriod(start time.Time, end *time.Time) string {
+	if end == nil {
+		return "from " + start.Format(time.DateOnly) + " on"
+	}
+	return "from " + start.Format(time.DateOnly) + " to " + end.Format(time.DateOnly)
+}
+
+// GetConflicts returns the conflicts between the assignments of the user's workers
+func (r *projectRepository) GetConflicts(ctx context.Context, userID uint) ([]Conflict, error) {
+	assignments, err := scheduledAssignments(r.db.WithContext(ctx), userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	return FindConflicts(assignments), nil
+}
+
+// AssignmentConflicts returns the conflicts an assignment would cause
+func (r *projectRepository) AssignmentConflicts(ctx context.Context, assignment model.WorkerProject) ([]Conflict, error) {
+	db := r.db.WithContext(ctx)
+	var project model.Project
+	if err := db.Where("id = ? AND user_id = ?", assignment.ProjectID, assignment.UserID).First(&project).Error; err != nil {
+		return nil, err
+	}
+	var worker model.Worker
+	if err := db.Where("id = ? AND user_id = ?", assignment.WorkerID, assignment.UserID).First(&worker).Error; err != nil {
+		return nil, err
+	}
+
+	assignments, err := scheduledAssignments(db, assignment.UserID, assignment.WorkerID)
+	if err != nil {
+		return nil, err
+	}
+	return CandidateConflicts(assignments, project, worker, assignment), nil
+}
+
+// CandidateConflicts returns the conflicts the assignment of a worker to a
+// project would cause among the worker's current assignments, which it adds
+// to or replaces
+func CandidateConflicts(assignments []Assignment, project model.Project, worker model.Worker, candidate model.WorkerProject) []Conflict {
+	others := slices.DeleteFunc(slices.Clone(assignments), func(a Assignment) bool {
+		return a.ProjectID == candidate.ProjectID
+	})
+	others = append(others, Assignment{
+		ProjectID:         project.ID,
+		ProjectName:       project.Name,
+		ProjectStatus:     project.Status,
+		ProjectStartDate:  project.StartDate,
+		ProjectEndDate:    project.EndDate,
+		WorkerID:          worker.ID,
+		WorkerName:        worker.Name,
+		Position:          worker.Position,
+		Salary:            worker.Salary,
+		AssignmentDetails: candidate.AssignmentDetails,
+	})
+
+	conflicts := make([]Conflict, 0)
+	for _, conflict := range FindConflicts(others) {
+		if conflict.Involves(candidate.ProjectID) {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+)
+
+// date returns a day of 2026
+func date(month time.Month, d int) time.Time {
+	return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// datePtr returns a pointer to a day of 2026
+func datePtr(month time.Month, d int) *time.Time {
+	t := date(month, d)
+	return &t
+}
+
+// scheduled is an assignment of worker 1 to a project starting on March 1st
+func scheduled(projectID uint, allocation int, start, end *time.Time) Assignment {
+	return Assignment{
+		ProjectID:        projectID,
+		ProjectName:      "Project",
+		ProjectStatus:    "active",
+		ProjectStartDate: date(time.March, 1),
+		WorkerID:         1,
+		WorkerName:       "Ana Pop",
+		AssignmentDetails: model.AssignmentDetails{
+			StartDate:         start,
+			EndDate:           end,
+			AllocationPercent: allocation,
+		},
+	}
+}
+
+// expectPeriod fails the test unless the conflict covers the days
+func expectPeriod(t *testing.T, conflict Conflict, start time.Time, end *time.Time) {
+	t.Helper()
+	if !conflict.StartDate.Equal(start) || (end == nil) != (conflict.EndDate == nil) ||
+		(end != nil && !conflict.EndDate.Equal(*end)) {
+		t.Fatalf("expected a conflict from %s to %v, got %s to %v", start.Format(time.DateOnly), end,
+			conflict.StartDate.Format(time.DateOnly), conflict.EndDate)
+	}
+}
+
+func TestFindConflictsOverAllocation(t *testing.T) {
+	conflicts := FindConflicts([]Assignment{
+		scheduled(1, 100, nil, nil),
+		scheduled(2, 50, datePtr(time.March, 10), datePtr(time.March, 20)),
+		scheduled(3, 100, datePtr(time.April, 1), datePtr(time.April, 5)),
+	})
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %+v", conflicts)
+	}
+	if conflicts[0].Kind != ConflictOverAllocation || conflicts[0].AllocationPercent != 150 ||
+		!slices.Equal(conflicts[0].ProjectIDs, []uint{1, 2}) {
+		t.Fatalf("expected projects 1 and 2 at 150%%, got %+v", conflicts[0])
+	}
+	expectPeriod(t, conflicts[0], date(time.March, 10), datePtr(time.March, 20))
+	if conflicts[1].AllocationPercent != 200 || !slices.Equal(conflicts[1].ProjectIDs, []uint{1, 3}) {
+		t.Fatalf("expected projects 1 and 3 at 200%%, got %+v", conflicts[1])
+	}
+	expectPeriod(t, conflicts[1], date(time.April, 1), datePtr(time.April, 5))
+}
+
+func TestFindConflictsJoinsConsecutiveDays(t *testing.T) {
+	// The third assignment splits the period without changing the projects
+	// over-allocated, so the conflict stays one period
+	conflicts := FindConflicts([]Assignment{
+		scheduled(1, 60, nil, nil),
+		scheduled(2, 60, datePtr(time.March, 10), datePtr(time.March, 20)),
+		scheduled(2, 60, datePtr(time.March, 21), datePtr(time.March, 31)),
+	})
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %+v", conflicts)
+	}
+	expectPeriod(t, conflicts[0], date(time.March, 10), datePtr(time.March, 31))
+}
+
+func TestFindConflictsFullAllocationIsAllowed(t *testing.T) {
+	conflicts := FindConflicts([]Assignment{
+		scheduled(1, 50, nil, nil),
+		scheduled(2, 50, datePtr(time.March, 10), nil),
+		scheduled(3, 50, nil, datePtr(time.March, 9)),
+	})
+	if len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts at 100%%, got %+v", conflicts)
+	}
+}
+
+func TestFindConflictsOutsideProject(t *testing.T) {
+	early := scheduled(1, 100, datePtr(time.February, 20), datePtr(time.April, 10))
+	early.ProjectEndDate = datePtr(time.March, 31)
+
+	conflicts := FindConflicts([]Assignment{early})
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %+v", conflicts)
+	}
+	if conflicts[0].Kind != ConflictBeforeProject {
+		t.Fatalf("expected a conflict before the project, got %+v", conflicts[0])
+	}
+	expectPeriod(t, conflicts[0], date(time.February, 20), datePtr(time.February, 28))
+	if conflicts[1].Kind != ConflictAfterProject {
+		t.Fatalf("expected a conflict after the project, got %+v", conflicts[1])
+	}
+	expectPeriod(t, conflicts[1], date(time.April, 1), datePtr(time.April, 10))
+
+	// Without dates of its own, the assignment ends with its project
+	if conflicts := FindConflicts([]Assignment{scheduled(1, 100, nil, nil)}); len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %+v", conflicts)
+	}
+}
+
+func TestFindConflictsIgnoresCancelledProjects(t *testing.T) {
+	cancelled := scheduled(2, 100, datePtr(time.February, 1), nil)
+	cancelled.ProjectStatus = model.ProjectStatusCancelled
+
+	if conflicts := FindConflicts([]Assignment{scheduled(1, 100, nil, nil), cancelled}); len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %+v", conflicts)
+	}
+}
+
+func TestCandidateConflicts(t *testing.T) {
+	current := []Assignment{
+		scheduled(1, 100, nil, nil),
+		scheduled(2, 100, datePtr(time.May, 1), nil),
+		scheduled(3, 50, nil, nil),
+	}
+	project := model.Project{ID: 2, Name: "Depot", Status: "active", StartDate: date(time.March, 1)}
+	worker := model.Worker{ID: 1, Name: "Ana Pop"}
+
+	// The candidate replaces the current assignment to project 2; the
+	// conflict between projects 1 and 3 is not its doing
+	candidate := model.WorkerProject{WorkerID: 1, ProjectID: 2,
+		AssignmentDetails: model.AssignmentDetails{AllocationPercent: 100, StartDate: datePtr(time.June, 1)}}
+	conflicts := CandidateConflicts(current, project, worker, candidate)
+	if len(conflicts) != 1 || !conflicts[0].Involves(2) {
+		t.Fatalf("expected 1 conflict of project 2, got %+v", conflicts)
+	}
+	expectPeriod(t, conflicts[0], date(time.June, 1), nil)
+
+	// Beside project 3 alone, half of the worker's time is free
+	candidate.AllocationPercent = 50
+	if conflicts := CandidateConflicts(current[1:], project, worker, candidate); len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts at half allocation, got %+v", conflicts)
+	}
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"gorm.io/gorm"
+)
+
+// The history of an entity is written by the repositories themselves, in the
+// transaction of each write, so every path that changes a worker, a project
+// or their assignments is covered, units of work included.
+
+// WorkerHistoryFields are the worker fields recorded in its history
+var WorkerHistoryFields = []string{"name", "age", "position", "salary"}
+
+// ProjectHistoryFields are the project fields recorded in its history
+var ProjectHistoryFields = []string{
+	"name", "description", "status", "start_date", "end_date",
+	"latitude", "longitude", "boundaries", "locations",
+}
+
+// AssignmentHistoryFields are the assignment details recorded in the history
+// of its worker and project
+var AssignmentHistoryFields = []string{"role", "start_date", "end_date", "allocation_percent", "day_rate", "notes"}
+
+// HistoryRepository reads the change history of workers and projects
+type HistoryRepository interface {
+	// List returns the history of one of the user's workers or projects,
+	// trashed ones included, newest first. It returns gorm.ErrRecordNotFound
+	// when the user has no such entity.
+	List(ctx context.Context, userID uint, entityType model.EntityType, entityID uint, page, pageSize int) ([]model.EntityChange, int64, error)
+}
+
+type historyRepository struct {
+	db *gorm.DB
+}
+
+// NewHistoryRepository creates a new HistoryRepository instance
+func NewHistoryRepository(db *gorm.DB) HistoryRepository {
+	return &historyRepository{
+		db: db,
+	}
+}
+
+// List returns the history of an entity, newest first
+func (r *historyRepository) List(ctx context.Context, userID uint, entityType model.EntityType, entityID uint, page, pageSize int) ([]model.EntityChange, int64, error) {
+	table, ok := trashTableOf(entityType)
+	if !ok {
+		return nil, 0, gorm.ErrRecordNotFound
+	}
+	var owned int64
+	if err := r.db.WithContext(ctx).Unscoped().Model(table.model).
+		Where("id = ? AND user_id = ?", entityID, userID).Count(&owned).Error; err != nil {
+		return nil, 0, err
+	}
+	if owned == 0 {
+		return nil, 0, gorm.ErrRecordNotFound
+	}
+
+	query := r.db.WithContext(ctx).Model(&model.EntityChange{}).
+		Where("entity_type = ? AND entity_id = ? AND user_id = ?", entityType, entityID, userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	changes := make([]model.EntityChange, 0)
+	query = query.Order("created_at DESC, id DESC")
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	if err := query.Find(&changes).Error; err != nil {
+		return nil, 0, err
+	}
+	return changes, total, nil
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the user on whose behalf the
+// repositories write, recorded in the history of what they change
+func WithActor(ctx context.Context, actor model.Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// NewChange builds a history entry of an entity. The change is attributed to
+// the actor of the context, or else to the owner of the entity.
+func NewChange(ctx context.Context, entityType model.EntityType, entityID uint, action model.LogType, userID uint, changes []model.FieldChange) model.EntityChange {
+	actor, ok := ctx.Value(actorKey{}).(model.Actor)
+	if !ok {
+		actor = model.Actor{ID: userID}
+	}
+	if changes == nil {
+		changes = []model.FieldChange{}
+	}
+	return model.EntityChange{
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Action:      action,
+		UserID:      userID,
+		ChangedByID: actor.ID,
+		ChangedBy:   actor.Username,
+		Changes:     changes,
+	}
+}
+
+// DiffFields returns the given fields whose JSON values differ between two
+// versions of a record. A nil version has no values, so diffing from nil
+// lists the fields of a new record.
+func DiffFields(before, after interface{}, fields []string) ([]model.FieldChange, error) {
+	old, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]model.FieldChange, 0)
+	for _, field := range fields {
+		if !bytes.Equal(old[field], updated[field]) {
+			changes = append(changes, model.FieldChange{Field: field, Old: jsonOrNull(old[field]), New: jsonOrNull(updated[field])})
+		}
+	}
+	return changes, nil
+}
+
+// AssignmentChanges builds the history entries of both sides of an
+// assignment being made or removed
+func AssignmentChanges(ctx context.Context, worker model.Worker, project model.Project, action model.LogType, userID uint) []model.EntityChange {
+	workerRef, _ := json.Marshal(map[string]interface{}{"id": worker.ID, "name": worker.Name})
+	projectRef, _ := json.Marshal(map[string]interface{}{"id": project.ID, "name": project.Name})
+
+	workerChange := model.FieldChange{Field: "projects", Old: jsonOrNull(nil), New: projectRef}
+	projectChange := model.FieldChange{Field: "workers", Old: jsonOrNull(nil), New: workerRef}
+	if action == model.LogTypeUnassign {
+		workerChange.Old, workerChange.New = workerChange.New, workerChange.Old
+		projectChange.Old, projectChange.New = projectChange.New, projectChange.Old
+	}
+
+	return []model.EntityChange{
+		NewChange(ctx, model.EntityTypeWorker, worker.ID, action, userID, []model.FieldChange{workerChange}),
+		NewChange(ctx, model.EntityTypeProject, project.ID, action, userID, []model.FieldChange{projectChange}),
+	}
+}
+
+// AssignmentUpdateChanges builds the history entries of both sides of an
+// assignment whose details change. Each side names the other with the old
+// and new values of the changed details; there are none when nothing changed.
+func AssignmentUpdateChanges(ctx context.Context, worker model.Worker, project model.Project, before, after model.AssignmentDetails, userID uint) ([]model.EntityChange, error) {
+	changes, err := DiffFields(before, after, AssignmentHistoryFields)
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+
+	side := func(id uint, name string, value func(model.FieldChange) json.RawMessage) json.RawMessage {
+		ref := map[string]interface{}{"id": id, "name": name}
+		for _, change := range changes {
+			ref[change.Field] = value(change)
+		}
+		data, _ := json.Marshal(ref)
+		return data
+	}
+	oldValue := func(change model.FieldChange) json.RawMessage { return change.Old }
+	newValue := func(change model.FieldChange) json.RawMessage { return change.New }
+
+	workerChange := model.FieldChange{Field: "projects",
+		Old: side(project.ID, project.Name, oldValue), New: side(project.ID, project.Name, newValue)}
+	projectChange := model.FieldChange{Field: "workers",
+		Old: side(worker.ID, worker.Name, oldValue), New: side(worker.ID, worker.Name, newValue)}
+	return []model.EntityChange{
+		NewChange(ctx, model.EntityTypeWorker, worker.ID, model.LogTypeUpdate, userID, []model.FieldChange{workerChange}),
+		NewChange(ctx, model.EntityTypeProject, project.ID, model.LogTypeUpdate, userID, []model.FieldChange{projectChange}),
+	}, nil
+}
+
+// recordChanges stores history entries
+func recordChanges(tx *gorm.DB, changes ...model.EntityChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	return tx.Create(&changes).Error
+}
+
+// recordAssignment stores the history entries of an assignment being made or
+// removed, loading both sides, trashed or not, for their names
+func recordAssignment(ctx context.Context, tx *gorm.DB, workerID, projectID uint, action model.LogType, userID uint) error {
+	var worker model.Worker
+	if err := tx.Unscoped().Select("id, name").First(&worker, workerID).Error; err != nil {
+		return err
+	}
+	var project model.Project
+	if err := tx.Unscoped().Select("id, name").First(&project, projectID).Error; err != nil {
+		return err
+	}
+	return recordChanges(tx, AssignmentChanges(ctx, worker, project, action, userID)...)
+}
+
+// jsonFields returns the top-level members of the JSON form of a record
+func jsonFields(record interface{}) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if record == nil {
+		return fields, nil
+	}
+	document, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(document, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// jsonOrNull returns a JSON value, or null when it is missing
+func jsonOrNull(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+)
+
+func TestDiffFields(t *testing.T) {
+	before := model.Worker{Name: "Ana Pop", Age: 30, Position: "Mason", Salary: 3000}
+	after := before
+	after.Salary = 3500
+
+	changes, err := DiffFields(before, after, WorkerHistoryFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Field != "salary" ||
+		string(changes[0].Old) != "3000" || string(changes[0].New) != "3500" {
+		t.Fatalf("expected the salary to change from 3000 to 3500, got %+v", changes)
+	}
+
+	changes, err = DiffFields(nil, after, WorkerHistoryFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != len(WorkerHistoryFields) || string(changes[0].Old) != "null" {
+		t.Fatalf("expected every field of a new record to change from null, got %+v", changes)
+	}
+}
+
+func TestAssignmentUpdateChangesNeedAChange(t *testing.T) {
+	details := model.AssignmentDetails{Role: "Foreman", AllocationPercent: model.FullAllocation}
+	changes, err := AssignmentUpdateChanges(context.Background(), model.Worker{ID: 1}, model.Project{ID: 2}, details, details, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes != nil {
+		t.Fatalf("expected no history entries, got %+v", changes)
+	}
+}
+
+// latestChange returns the newest history entry of an entity of the user
+func latestChange(t *testing.T, history HistoryRepository, userID uint, entityType model.EntityType, entityID uint) (model.EntityChange, int64) {
+	t.Helper()
+	changes, total, err := history.List(context.Background(), userID, entityType, entityID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) == 0 {
+		t.Fatalf("expected a history entry of %s %d", entityType, entityID)
+	}
+	return changes[0], total
+}
+
+func TestUpdateAssignmentRecordsBothSides(t *testing.T) {
+	db := openTestDB(t)
+	const userID = 1
+	ctx := WithActor(context.Background(), model.Actor{ID: 9, Username: "manager"})
+	worker, project := assignedWorker(t, db, userID, day(time.Now()).AddDate(0, -2, 0))
+	history := NewHistoryRepository(db)
+	_, before := latestChange(t, history, userID, model.EntityTypeWorker, worker.ID)
+
+	assignment := model.NewAssignment(worker.ID, project.ID, userID)
+	assignment.Role, assignment.AllocationPercent = "Foreman", 50
+	if err := NewProjectRepository(db).UpdateAssignment(ctx, &assignment); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, side := range []struct {
+		entityType model.EntityType
+		entityID   uint
+		field      string
+		otherID    uint
+	}{
+		{model.EntityTypeWorker, worker.ID, "projects", project.ID},
+		{model.EntityTypeProject, project.ID, "workers", worker.ID},
+	} {
+		change, _ := latestChange(t, history, userID, side.entityType, side.entityID)
+		if change.Action != model.LogTypeUpdate || change.ChangedByID != 9 || change.ChangedBy != "manager" {
+			t.Fatalf("expected an update by the manager in the %s history, got %+v", side.entityType, change)
+		}
+		if len(change.Changes) != 1 || change.Changes[0].Field != side.field {
+			t.Fatalf("expected the %s of the %s to change, got %+v", side.field, side.entityType, change.Changes)
+		}
+		var old, updated map[string]interface{}
+		if err := json.Unmarshal(change.Changes[0].Old, &old); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(change.Changes[0].New, &updated); err != nil {
+			t.Fatal(err)
+		}
+		if updated["id"] != float64(side.otherID) || old["role"] != "" || updated["role"] != "Foreman" ||
+			old["allocation_percent"] != float64(100) || updated["allocation_percent"] != float64(50) {
+			t.Fatalf("expected the role and allocation to change, got %s to %s", change.Changes[0].Old, change.Changes[0].New)
+		}
+		if _, ok := updated["notes"]; ok {
+			t.Fatalf("expected only the changed details, got %s", change.Changes[0].New)
+		}
+	}
+
+	// Writing the same details again leaves no entry
+	if err := NewProjectRepository(db).UpdateAssignment(ctx, &assignment); err != nil {
+		t.Fatal(err)
+	}
+	if _, total := latestChange(t, history, userID, model.EntityTypeWorker, worker.ID); total != before+1 {
+		t.Fatalf("expected one entry for the update, got %d", total-before)
+	}
+}
+
+func TestWorkerPatchRecordsTheChangedFields(t *testing.T) {
+	db := openTestDB(t)
+	const userID = 1
+	ctx := context.Background()
+	workers := NewWorkerRepository(db)
+	worker := model.Worker{Name: "Ana Pop", Age: 30, Position: "Mason", Salary: 3000, UserID: userID}
+	if err := workers.Create(ctx, &worker); err != nil {
+		t.Fatal(err)
+	}
+
+	worker.Salary = 3500
+	if err := workers.Patch(ctx, &worker, []string{"salary"}, userID); err != nil {
+		t.Fatal(err)
+	}
+	history := NewHistoryRepository(db)
+	change, total := latestChange(t, history, userID, model.EntityTypeWorker, worker.ID)
+	if total != 2 || change.Action != model.LogTypeUpdate || change.ChangedByID != userID {
+		t.Fatalf("expected the update by the owner after the creation, got %d entries, newest %+v", total, change)
+	}
+	if len(change.Changes) != 1 || change.Changes[0].Field != "salary" {
+		t.Fatalf("expected only the salary to change, got %+v", change.Changes)
+	}
+	if _, _, err := history.List(ctx, 2, model.EntityTypeWorker, worker.ID, 1, 10); err == nil {
+		t.Fatal("expected the history to be hidden from other users")
+	}
+}
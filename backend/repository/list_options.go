@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/query"
+	"gorm.io/gorm"
+)
+
+// ListOptions controls the filtering, sorting and pagination of list queries
+type ListOptions struct {
+	// Search is a free-text term matched against the entity's text columns
+	Search string
+	// Query holds the validated filter conditions and sort keys
+	Query *query.Spec
+	// Cursor selects keyset pagination; Page is ignored when it is set
+	Cursor   *query.Cursor
+	Page     int
+	PageSize int
+}
+
+// PageInfo describes the page returned by a list query
+type PageInfo struct {
+	// Total is the number of matching rows, only counted in offset mode
+	Total int64
+	// NextCursor and PrevCursor point at the neighbouring pages in cursor
+	// mode; they are empty when there is no such page
+	NextCursor string
+	PrevCursor string
+}
+
+// WorkerFields is the whitelist of worker fields usable in filters and sorts
+var WorkerFields = query.Schema{
+	"id":         {Column: "id", Type: query.Int, Filterable: true, Sortable: true},
+	"name":       {Column: "name", Type: query.String, Filterable: true, Sortable: true},
+	"age":        {Column: "age", Type: query.Int, Filterable: true, Sortable: true},
+	"position":   {Column: "position", Type: query.String, Filterable: true, Sortable: true},
+	"salary":     {Column: "salary", Type: query.Int, Filterable: true, Sortable: true},
+	"created_at": {Column: "created_at", Type: query.Time, Filterable: true, Sortable: true},
+	"updated_at": {Column: "updated_at", Type: query.Time, Filterable: true, Sortable: true},
+}
+
+// ProjectFields is the whitelist of project fields usable in filters and sorts
+var ProjectFields = query.Schema{
+	"id":          {Column: "id", Type: query.Int, Filterable: true, Sortable: true},
+	"name":        {Column: "name", Type: query.String, Filterable: true, Sortable: true},
+	"description": {Column: "description", Type: query.String, Filterable: true},
+	"status":      {Column: "status", Type: query.String, Filterable: true, Sortable: true},
+	"start_date":  {Column: "start_date", Type: query.Time, Filterable: true, Sortable: true},
+	"end_date":    {Column: "end_date", Type: query.Time, Nullable: true, Filterable: true, Sortable: true},
+	"latitude":    {Column: "latitude", Type: query.Float, Filterable: true, Sortable: true},
+	"longitude":   {Column: "longitude", Type: query.Float, Filterable: true, Sortable: true},
+	"created_at":  {Column: "created_at", Type: query.Time, Filterable: true, Sortable: true},
+	"updated_at":  {Column: "updated_at", Type: query.Time, Filterable: true, Sortable: true},
+}
+
+// LogFields is the whitelist of activity log fields usable in filters and sorts
+var LogFields = query.Schema{
+	"id":          {Column: "id", Type: query.Int, Filterable: true, Sortable: true},
+	"user_id":     {Column: "user_id", Type: query.Int, Filterable: true, Sortable: true},
+	"log_type":    {Column: "log_type", Type: query.String, Filterable: true, Sortable: true},
+	"entity_type": {Column: "entity_type", Type: query.String, Filterable: true, Sortable: true},
+	"entity_id":   {Column: "entity_id", Type: query.Int, Filterable: true, Sortable: true},
+	"created_at":  {Column: "created_at", Type: query.Time, Filterable: true, Sortable: true},
+}
+
+// WorkerRow exposes the columns of a worker to the query package
+func WorkerRow(worker model.Worker) query.Row {
+	return func(column string) (interface{}, bool) {
+		switch column {
+		case "id":
+			return worker.ID, true
+		case "name":
+			return worker.Name, true
+		case "age":
+			return worker.Age, true
+		case "position":
+			return worker.Position, true
+		case "salary":
+			return worker.Salary, true
+		case "user_id":
+			return worker.UserID, true
+		case "created_at":
+			return worker.CreatedAt, true
+		case "updated_at":
+			return worker.UpdatedAt, true
+		}
+		return nil, false
+	}
+}
+
+// ProjectRow exposes the columns of a project to the query package
+func ProjectRow(project model.Project) query.Row {
+	return func(column string) (interface{}, bool) {
+		switch column {
+		case "id":
+			return project.ID, true
+		case "name":
+			return project.Name, true
+		case "description":
+			return project.Description, true
+		case "status":
+			return project.Status, true
+		case "start_date":
+			return project.StartDate, true
+		case "end_date":
+			return project.EndDate, true
+		case "latitude":
+			return project.Latitude, true
+		case "longitude":
+			return project.Longitude, true
+		case "user_id":
+			return project.UserID, true
+		case "created_at":
+			return project.CreatedAt, true
+		case "updated_at":
+			return project.UpdatedAt, true
+		}
+		return nil, false
+	}
+}
+
+// LogRow exposes the columns of an activity log to the query package
+func LogRow(log model.ActivityLog) query.Row {
+	return func(column string) (interface{}, bool) {
+		switch column {
+		case "id":
+			return log.ID, true
+		case "user_id":
+			return log.UserID, true
+		case "log_type":
+			return string(log.LogType), true
+		case "entity_type":
+			return string(log.EntityType), true
+		case "entity_id":
+			return log.EntityID, true
+		case "created_at":
+			return log.CreatedAt, true
+		}
+		return nil, false
+	}
+}
+
+// eachRow runs a list query and calls fn with its rows one at a time, as
+// they are read from the database
+func eachRow[T any](db *gorm.DB, fn func(T) error) error {
+	rows, err := db.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row T
+		if err := db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// searchColumns keeps the rows where one of the columns contains the search
+// term, ignoring case on every database. Wildcards in the term are taken
+// literally, as by the like filter operator.
+func searchColumns(db *gorm.DB, term string, columns ...string) *gorm.DB {
+	condition, args := likeCondition(term, columns...)
+	return db.Where(condition, args...)
+}
+
+// likeCondition builds the condition of searchColumns with its arguments
+func likeCondition(term string, columns ...string) (string, []interface{}) {
+	pattern := query.LikePattern(term)
+	conditions := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		conditions[i] = "LOWER(" + column + ") LIKE ? ESCAPE '\\'"
+		args[i] = pattern
+	}
+	return strings.Join(conditions, " OR "), args
+}
+
+// sortAll sorts a list query read as a whole: by the sort of the options,
+// or else by ID
+func sortAll(db *gorm.DB, opts ListOptions) *gorm.DB {
+	return opts.Query.ApplySort(db)
+}
+
+// findPage runs a filtered list query in cursor mode, or in offset mode with
+// a total count. The preload function attaches associations to the final
+// query only, so that they are not part of the count.
+func findPage[T any](db *gorm.DB, opts ListOptions, preload func(*gorm.DB) *gorm.DB, row func(T) query.Row) ([]T, PageInfo, error) {
+	var info PageInfo
+	var rows []T
+
+	if opts.Cursor != nil {
+		// Fetch one extra row to find out whether another page follows
+		db = opts.Query.ApplyCursor(db, opts.Cursor).Limit(opts.PageSize + 1)
+		if err := preload(db).Find(&rows).Error; err != nil {
+			return nil, info, err
+		}
+
+		// Backward pages are fetched in reverse order
+		if opts.Cursor.Backward {
+			slices.Reverse(rows)
+		}
+		rows, info.NextCursor, info.PrevCursor = query.CursorPage(opts.Query, opts.Cursor, rows, opts.PageSize, row)
+		return rows, info, nil
+	}
+
+	// Count total records (before pagination)
+	if err := db.Count(&info.Total).Error; err != nil {
+		return nil, info, err
+	}
+
+	// Apply sorting
+	db = opts.Query.ApplySort(db)
+
+	// Apply pagination
+	if opts.Page > 0 && opts.PageSize > 0 {
+		offset := (opts.Page - 1) * opts.PageSize
+		db = db.Offset(offset).Limit(opts.PageSize)
+	}
+
+	if err := preload(db).Find(&rows).Error; err != nil {
+		return nil, info, err
+	}
+	return rows, info, nil
+}
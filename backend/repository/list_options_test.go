@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/query"
+)
+
+func TestSearchTakesWildcardsLiterally(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	const userID = 1
+	workers := NewWorkerRepository(db)
+	for _, position := range []string{"Mason 100%", "Mason 1000", "Site_lead", "Site lead"} {
+		worker := &model.Worker{Name: "Ana Pop", Age: 30, Position: position, Salary: 3000, UserID: userID}
+		if err := workers.Create(ctx, worker); err != nil {
+			t.Fatal(err)
+		}
+	}
+	users := NewUserRepository(db)
+	for _, username := range []string{"ana_pop", "anaxpop"} {
+		user := &model.User{Username: username, Email: username + "@example.com", Role: model.RoleUser, Active: true}
+		if err := users.CreateUser(ctx, user, "password1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		search string
+		want   []string
+	}{
+		{search: "100%", want: []string{"Mason 100%"}},
+		{search: "e_l", want: []string{"Site_lead"}},
+		{search: "%", want: []string{"Mason 100%"}},
+		{search: "MASON", want: []string{"Mason 100%", "Mason 1000"}},
+	}
+	for _, tt := range tests {
+		found, _, err := workers.GetAll(ctx, userID, ListOptions{Search: tt.search})
+		if err != nil {
+			t.Fatal(err)
+		}
+		positions := make([]string, len(found))
+		for i, worker := range found {
+			positions[i] = worker.Position
+		}
+		if len(positions) != len(tt.want) || (len(positions) > 0 && positions[0] != tt.want[0]) {
+			t.Errorf("searching workers for %q: expected %v, got %v", tt.search, tt.want, positions)
+		}
+	}
+
+	found, total, err := users.GetAllUsers(ctx, 1, 10, "a_p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || found[0].Username != "ana_pop" {
+		t.Fatalf("searching users for %q: expected ana_pop, got %+v", "a_p", found)
+	}
+}
+
+func TestWorkerCursorPagesMatchTheOffsetList(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	const userID = 1
+	workers := NewWorkerRepository(db)
+	for i, salary := range []int{3000, 2500, 3000, 4000, 3000, 2500, 3500} {
+		worker := &model.Worker{Name: "Worker " + string(rune('A'+i)), Age: 30, Position: "Mason", Salary: salary, UserID: userID}
+		if err := workers.Create(ctx, worker); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spec, err := query.Parse("", "-salary", WorkerFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, _, err := workers.GetAll(ctx, userID, ListOptions{Query: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []uint
+	for _, worker := range all {
+		want = append(want, worker.ID)
+	}
+
+	var got []uint
+	raw := ""
+	for pages := 0; pages < len(want); pages++ {
+		cursor, err := spec.ParseCursor(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, info, err := workers.GetAll(ctx, userID, ListOptions{Query: spec, Cursor: cursor, PageSize: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, worker := range page {
+			got = append(got, worker.ID)
+		}
+		if info.NextCursor == "" {
+			break
+		}
+		raw = info.NextCursor
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected the cursor pages to list %v, got %v", want, got)
+	}
+
+	// The previous cursor of the last page leads back to the page before it
+	cursor, err := spec.ParseCursor(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, info, err := workers.GetAll(ctx, userID, ListOptions{Query: spec, Cursor: cursor, PageSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := spec.ParseCursor(info.PrevCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _, err := workers.GetAll(ctx, userID, ListOptions{Query: spec, Cursor: back, PageSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var previous []uint
+	for _, worker := range page {
+		previous = append(previous, worker.ID)
+	}
+	if !slices.Equal(previous, want[3:6]) {
+		t.Fatalf("expected the previous page to list %v, got %v", want[3:6], previous)
+	}
+}
@@ -1,35 +1,57 @@
 package repository
 
 import (
-	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
+	"context"
+	"time"
+
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
 	"gorm.io/gorm"
 )
 
 // LogRepository handles database operations for activity logs
-type LogRepository struct {
+type LogRepository interface {
+	CreateLog(ctx context.Context, log *model.ActivityLog) error
+	GetLogsByUser(ctx context.Context, userID uint, page, pageSize int) ([]model.ActivityLog, int64, error)
+	GetLogsByEntityType(ctx context.Context, entityType model.EntityType, page, pageSize int) ([]model.ActivityLog, int64, error)
+	GetLogsByLogType(ctx context.Context, logType model.LogType, page, pageSize int) ([]model.ActivityLog, int64, error)
+	GetRecentLogs(ctx context.Context, page, pageSize int) ([]model.ActivityLog, int64, error)
+	GetLogsByDateRange(ctx context.Context, startDate, endDate string, page, pageSize int) ([]model.ActivityLog, int64, error)
+	ListLogs(ctx context.Context, filter LogFilter, opts ListOptions) ([]model.ActivityLog, PageInfo, error)
+}
+
+// LogFilter selects the logs returned by ListLogs; zero fields match all logs
+type LogFilter struct {
+	UserID     uint
+	EntityType model.EntityType
+	LogType    model.LogType
+	// From and To bound the creation time, both inclusive
+	From *time.Time
+	To   *time.Time
+}
+
+type logRepository struct {
 	db *gorm.DB
 }
 
 // NewLogRepository creates a new LogRepository instance
-func NewLogRepository() *LogRepository {
-	return &LogRepository{
-		db: config.DB,
+func NewLogRepository(db *gorm.DB) LogRepository {
+	return &logRepository{
+		db: db,
 	}
 }
 
 // CreateLog creates a new activity log entry
-func (r *LogRepository) CreateLog(log *model.ActivityLog) error {
-	return r.db.Create(log).Error
+func (r *logRepository) CreateLog(ctx context.Context, log *model.ActivityLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
 }
 
 // GetLogsByUser retrieves all logs for a specific user
-func (r *LogRepository) GetLogsByUser(userID uint, page, pageSize int) ([]model.ActivityLog, int64, error) {
+func (r *logRepository) GetLogsByUser(ctx context.Context, userID uint, page, pageSize int) ([]model.ActivityLog, int64, error) {
 	var logs []model.ActivityLog
 	var total int64
 
 	// Count total records
-	if err := r.db.Model(&model.ActivityLog{}).
+	if err := r.db.WithContext(ctx).Model(&model.ActivityLog{}).
 		Where("user_id = ?", userID).
 		Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -39,7 +61,7 @@ func (r *LogRepository) GetLogsByUser(userID uint, page, pageSize int) ([]model.
 	offset := (page - 1) * pageSize
 
 	// Get paginated records
-	if err := r.db.
+	if err := r.db.WithContext(ctx).
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
 		Offset(offset).
@@ -52,12 +74,12 @@ func (r *LogRepository) GetLogsByUser(userID uint, page, pageSize int) ([]model.
 }
 
 // GetLogsByEntityType retrieves logs filtered by entity type
-func (r *LogRepository) GetLogsByEntityType(entityType model.EntityType, page, pageSize int) ([]model.ActivityLog, int64, error) {
+func (r *logRepository) GetLogsByEntityType(ctx context.Context, entityType model.EntityType, page, pageSize int) ([]model.ActivityLog, int64, error) {
 	var logs []model.ActivityLog
 	var total int64
 
 	// Count total records
-	if err := r.db.Model(&model.ActivityLog{}).
+	if err := r.db.WithContext(ctx).Model(&model.ActivityLog{}).
 		Where("entity_type = ?", entityType).
 		Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -67,7 +89,7 @@ func (r *LogRepository) GetLogsByEntityType(entityType model.EntityType, page, p
 	offset := (page - 1) * pageSize
 
 	// Get paginated records
-	if err := r.db.
+	if err := r.db.WithContext(ctx).
 		Where("entity_type = ?", entityType).
 		Order("created_at DESC").
 		Offset(offset).
@@ -80,12 +102,12 @@ func (r *LogRepository) GetLogsByEntityType(entityType model.EntityType, page, p
 }
 
 // GetLogsByLogType retrieves logs filtered by log type
-func (r *LogRepository) GetLogsByLogType(logType model.LogType, page, pageSize int) ([]model.ActivityLog, int64, error) {
+func (r *logRepository) GetLogsByLogType(ctx context.Context, logType model.LogType, page, pageSize int) ([]model.ActivityLog, int64, error) {
 	var logs []model.ActivityLog
 	var total int64
 
 	// Count total records
-	if err := r.db.Model(&model.ActivityLog{}).
+	if err := r.db.WithContext(ctx).Model(&model.ActivityLog{}).
 		Where("log_type = ?", logType).
 		Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -95,7 +117,7 @@ func (r *LogRepository) GetLogsByLogType(logType model.LogType, page, pageSize i
 	offset := (page - 1) * pageSize
 
 	// Get paginated records
-	if err := r.db.
+	if err := r.db.WithContext(ctx).
 		Where("log_type = ?", logType).
 		Order("created_at DESC").
 		Offset(offset).
@@ -108,12 +130,12 @@ func (r *LogRepository) GetLogsByLogType(logType model.LogType, page, pageSize i
 }
 
 // GetRecentLogs retrieves recent logs with pagination
-func (r *LogRepository) GetRecentLogs(page, pageSize int) ([]model.ActivityLog, int64, error) {
+func (r *logRepository) GetRecentLogs(ctx context.Context, page, pageSize int) ([]model.ActivityLog, int64, error) {
 	var logs []model.ActivityLog
 	var total int64
 
 	// Count total records
-	if err := r.db.Model(&model.ActivityLog{}).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&model.ActivityLog{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -121,7 +143,7 @@ func (r *LogRepository) GetRecentLogs(page, pageSize int) ([]model.ActivityLog,
 	offset := (page - 1) * pageSize
 
 	// Get paginated records
-	if err := r.db.
+	if err := r.db.WithContext(ctx).
 		Order("created_at DESC").
 		Offset(offset).
 		Limit(pageSize).
@@ -133,12 +155,12 @@ func (r *LogRepository) GetRecentLogs(page, pageSize int) ([]model.ActivityLog,
 }
 
 // GetLogsByDateRange retrieves logs within a specified date range
-func (r *LogRepository) GetLogsByDateRange(startDate, endDate string, page, pageSize int) ([]model.ActivityLog, int64, error) {
+func (r *logRepository) GetLogsByDateRange(ctx context.Context, startDate, endDate string, page, pageSize int) ([]model.ActivityLog, int64, error) {
 	var logs []model.ActivityLog
 	var total int64
 
 	// Count total records
-	if err := r.db.Model(&model.ActivityLog{}).
+	if err := r.db.WithContext(ctx).Model(&model.ActivityLog{}).
 		Where("created_at BETWEEN ? AND ?", startDate, endDate).
 		Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -148,7 +170,7 @@ func (r *LogRepository) GetLogsByDateRange(startDate, endDate string, page, page
 	offset := (page - 1) * pageSize
 
 	// Get paginated records
-	if err := r.db.
+	if err := r.db.WithContext(ctx).
 		Where("created_at BETWEEN ? AND ?", startDate, endDate).
 		Order("created_at DESC").
 		Offset(offset).
@@ -158,4 +180,30 @@ func (r *LogRepository) GetLogsByDateRange(startDate, endDate string, page, page
 	}
 
 	return logs, total, nil
-} 
\ No newline at end of file
+} 
+
+// ListLogs retrieves the logs matching the filter, in offset or cursor mode
+func (r *logRepository) ListLogs(ctx context.Context, filter LogFilter, opts ListOptions) ([]model.ActivityLog, PageInfo, error) {
+	query := r.db.WithContext(ctx).Model(&model.ActivityLog{})
+
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.LogType != "" {
+		query = query.Where("log_type = ?", filter.LogType)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	// Apply the whitelisted filters
+	query = opts.Query.ApplyFilters(query)
+
+	return findPage(query, opts, func(query *gorm.DB) *gorm.DB { return query }, LogRow)
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"gorm.io/gorm"
+)
+
+type historyRepository struct {
+	store *Store
+}
+
+// NewHistoryRepository creates an in-memory HistoryRepository
+func NewHistoryRepository(store *Store) repository.HistoryRepository {
+	return &historyRepository{
+		store: store,
+	}
+}
+
+// List returns the history of an entity, newest first
+func (r *historyRepository) List(ctx context.Context, userID uint, entityType model.EntityType, entityID uint, page, pageSize int) ([]model.EntityChange, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	owned := false
+	switch entityType {
+	case model.EntityTypeWorker:
+		worker, ok := r.store.workers[entityID]
+		owned = ok && worker.UserID == userID
+	case model.EntityTypeProject:
+		project, ok := r.store.projects[entityID]
+		owned = ok && project.UserID == userID
+	}
+	if !owned {
+		return nil, 0, gorm.ErrRecordNotFound
+	}
+
+	changes := make([]model.EntityChange, 0)
+	for _, change := range r.store.changes {
+		if change.EntityType == entityType && change.EntityID == entityID && change.UserID == userID {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID > changes[j].ID
+	})
+
+	total := int64(len(changes))
+	if page <= 0 || pageSize <= 0 {
+		return changes, total, nil
+	}
+	offset := (page - 1) * pageSize
+	if offset >= len(changes) {
+		return []model.EntityChange{}, total, nil
+	}
+	return changes[offset:min(offset+pageSize, len(changes))], total, nil
+}
+
+// record stores history entries. Callers must hold the write lock.
+func (s *Store) record(changes ...model.EntityChange) {
+	now := time.Now()
+	for _, change := range changes {
+		change.ID = s.nextID("entity_changes")
+		change.CreatedAt = now
+		s.changes[change.ID] = change
+	}
+}
+
+// recordWrite stores the history entry of a worker or project write; a nil
+// before records a creation, and updates changing no recorded field leave no
+// entry. Callers must hold the write lock.
+func (s *Store) recordWrite(ctx context.Context, entityType model.EntityType, id, userID uint, before, after interface{}, fields []string) error {
+	action := model.LogTypeUpdate
+	if before == nil {
+		action = model.LogTypeCreate
+	}
+	changes, err := repository.DiffFields(before, after, fields)
+	if err != nil {
+		return err
+	}
+	if action == model.LogTypeUpdate && len(changes) == 0 {
+		return nil
+	}
+	s.record(repository.NewChange(ctx, entityType, id, action, userID, changes))
+	return nil
+}
+
+// forgetHistory deletes the history of an entity. Callers must hold the write lock.
+func (s *Store) forgetHistory(entityType model.EntityType, id uint) {
+	for changeID, change := range s.changes {
+		if change.EntityType == entityType && change.EntityID == id {
+			delete(s.changes, changeID)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+)
+
+type logRepository struct {
+	store *Store
+}
+
+// NewLogRepository creates an in-memory LogRepository
+func NewLogRepository(store *Store) repository.LogRepository {
+	return &logRepository{
+		store: store,
+	}
+}
+
+// CreateLog creates a new activity log entry
+func (r *logRepository) CreateLog(ctx context.Context, log *model.ActivityLog) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	log.ID = r.store.nextID("activity_logs")
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	r.store.logs[log.ID] = *log
+	return nil
+}
+
+// findLogs returns a page of the logs matching the predicate, newest first
+func (r *logRepository) findLogs(match func(model.ActivityLog) bool, page, pageSize int) ([]model.ActivityLog, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	logs := make([]model.ActivityLog, 0)
+	for _, log := range r.store.logs {
+		if !log.DeletedAt.Valid && match(log) {
+			logs = append(logs, log)
+		}
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].CreatedAt.Equal(logs[j].CreatedAt) {
+			return logs[i].ID > logs[j].ID
+		}
+		return logs[i].CreatedAt.After(logs[j].CreatedAt)
+	})
+
+	total := int64(len(logs))
+	return paginate(logs, page, pageSize), total, nil
+}
+
+// GetLogsByUser retrieves all logs for a specific user
+func (r *logRepository) GetLogsByUser(ctx context.Context, userID uint, page, pageSize int) ([]model.ActivityLog, int64, error) {
+	return r.findLogs(func(log model.ActivityLog) bool { return log.UserID == userID }, page, pageSize)
+}
+
+// GetLogsByEntityType retrieves logs filtered by entity type
+func (r *logRepository) GetLogsByEntityType(ctx context.Context, entityType model.EntityType, page, pageSize int) ([]model.ActivityLog, int64, error) {
+	return r.findLogs(func(log model.ActivityLog) bool { return log.EntityType == entityType }, page, pageSize)
+}
+
+// GetLogsByLogType retrieves logs filtered by log type
+func (r *logRepository) GetLogsByLogType(ctx context.Context, logType model.LogType, page, pageSize int) ([]model.ActivityLog, int64, error) {
+	return r.findLogs(func(log model.ActivityLog) bool { return log.LogType == logType }, page, pageSize)
+}
+
+// GetRecentLogs retrieves recent logs with pagination
+func (r *logRepository) GetRecentLogs(ctx context.Context, page, pageSize int) ([]model.ActivityLog, int64, error) {
+	return r.findLogs(func(model.ActivityLog) bool { return true }, page, pageSize)
+}
+
+// GetLogsByDateRange retrieves logs within a specified date range (inclusive)
+func (r *logRepository) GetLogsByDateRange(ctx context.Context, startDate, endDate string, page, pageSize int) ([]model.ActivityLog, int64, error) {
+	start, err := parseDate(startDate)
+	if err != nil {
+		return nil, 0, err
+	}
+	end, err := parseDate(endDate)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r.findLogs(func(log model.ActivityLog) bool {
+		return !log.CreatedAt.Before(start) && !log.CreatedAt.After(end)
+	}, page, pageSize)
+}
+
+// ListLogs retrieves the logs matching the filter, in offset or cursor mode
+func (r *logRepository) ListLogs(ctx context.Context, filter repository.LogFilter, opts repository.ListOptions) ([]model.ActivityLog, repository.PageInfo, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	logs := make([]model.ActivityLog, 0)
+	for _, log := range r.store.logs {
+		if log.DeletedAt.Valid ||
+			(filter.UserID != 0 && log.UserID != filter.UserID) ||
+			(filter.EntityType != "" && log.EntityType != filter.EntityType) ||
+			(filter.LogType != "" && log.LogType != filter.LogType) ||
+			(filter.From != nil && log.CreatedAt.Before(*filter.From)) ||
+			(filter.To != nil && log.CreatedAt.After(*filter.To)) {
+			continue
+		}
+
+		// Apply the whitelisted filters
+		if opts.Query.Matches(repository.LogRow(log)) {
+			logs = append(logs, log)
+		}
+	}
+
+	logs, info := listPage(logs, opts, repository.LogRow)
+	return logs, info, nil
+}
+
+// parseDate accepts the date formats the database would accept for a timestamp literal
+func parseDate(value string) (time.Time, error) {
+	var lastErr error
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed, nil
+		}
+		lastErr = err
+	}
+	return time.Time{}, lastErr
+}
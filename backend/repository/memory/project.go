@@ -0,0 +1,462 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/geo"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"gorm.io/gorm"
+)
+
+type projectRepository struct {
+	store *Store
+}
+
+// NewProjectRepository creates an in-memory ProjectRepository
+func NewProjectRepository(store *Store) repository.ProjectRepository {
+	return &projectRepository{
+		store: store,
+	}
+}
+
+// Create creates a new project
+func (r *projectRepository) Create(ctx context.Context, project *model.Project) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	project.ID = r.store.nextID("projects")
+	project.Version = 1
+	project.CreatedAt = now
+	project.UpdatedAt = now
+
+	stored := *project
+	stored.Workers = nil
+	r.store.projects[project.ID] = stored
+	return r.store.recordWrite(ctx, model.EntityTypeProject, project.ID, project.UserID, nil, stored, repository.ProjectHistoryFields)
+}
+
+// GetByID retrieves a project by ID and user ID
+func (r *projectRepository) GetByID(ctx context.Context, id uint, userID uint) (*model.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	project, ok := r.store.projects[id]
+	if !ok || project.DeletedAt.Valid || project.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	project.Workers = r.store.projectWorkers(id, userID, false)
+	return &project, nil
+}
+
+// GetWithHistory retrieves a project like GetByID, listing its workers once
+// per assignment, the ended ones included
+func (r *projectRepository) GetWithHistory(ctx context.Context, id uint, userID uint) (*model.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	project, ok := r.store.projects[id]
+	if !ok || project.DeletedAt.Valid || project.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	project.Workers = r.store.projectWorkers(id, userID, true)
+	return &project, nil
+}
+
+// GetAll retrieves all projects with optional filtering and sorting for a specific user
+func (r *projectRepository) GetAll(ctx context.Context, userID uint, opts repository.ListOptions) ([]model.Project, repository.PageInfo, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	projects, info := listPage(r.filtered(userID, opts), opts, repository.ProjectRow)
+	for i := range projects {
+		projects[i].Workers = r.store.projectWorkers(projects[i].ID, userID, false)
+	}
+	return projects, info, nil
+}
+
+// GetNearby retrieves the user's projects inside an area, nearest to its origin first
+func (r *projectRepository) GetNearby(ctx context.Context, userID uint, area repository.GeoQuery, opts repository.ListOptions) ([]repository.ProjectDistance, repository.PageInfo, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	projects, info := repository.NearbyPage(r.filtered(userID, opts), area, opts)
+	for i := range projects {
+		projects[i].Workers = r.store.projectWorkers(projects[i].ID, userID, false)
+	}
+	return projects, info, nil
+}
+
+// Each calls fn with every matching project, in list order
+func (r *projectRepository) Each(ctx context.Context, userID uint, opts repository.ListOptions, area *repository.GeoQuery, fn func(repository.ProjectDistance) error) error {
+	r.store.mu.RLock()
+	opts.Page, opts.PageSize, opts.Cursor = 0, 0, nil
+	projects, _ := listPage(r.filtered(userID, opts), opts, repository.ProjectRow)
+	r.store.mu.RUnlock()
+
+	for _, project := range projects {
+		item := repository.ProjectDistance{Project: project}
+		if area != nil {
+			distance, ok := area.Matches(project)
+			if !ok {
+				continue
+			}
+			item.DistanceKm = distance
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EachAssignment calls fn with the assignments to the matching projects
+func (r *projectRepository) EachAssignment(ctx context.Context, userID uint, opts repository.ListOptions, fn func(repository.Assignment) error) error {
+	r.store.mu.RLock()
+	projects := r.filtered(userID, opts)
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Name != projects[j].Name {
+			return projects[i].Name < projects[j].Name
+		}
+		return projects[i].ID < projects[j].ID
+	})
+	assignments := make([]repository.Assignment, 0)
+	for _, project := range projects {
+		workers := r.store.projectWorkers(project.ID, userID, true)
+		sort.SliceStable(workers, func(i, j int) bool { return workers[i].Name < workers[j].Name })
+		for _, worker := range workers {
+			assignments = append(assignments, repository.Assignment{
+				ProjectID: project.ID, ProjectName: project.Name, ProjectStatus: project.Status,
+				ProjectStartDate: project.StartDate, ProjectEndDate: project.EndDate,
+				WorkerID: worker.ID, WorkerName: worker.Name, Position: worker.Position, Salary: worker.Salary,
+				AssignmentDetails: worker.Assignment.AssignmentDetails,
+			})
+		}
+	}
+	r.store.mu.RUnlock()
+
+	for _, assignment := range assignments {
+		if err := fn(assignment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetNearest retrieves the user's limit projects nearest to the origin
+func (r *projectRepository) GetNearest(ctx context.Context, userID uint, origin geo.Point, limit int, opts repository.ListOptions) ([]repository.ProjectDistance, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	// Every project lies within the largest radius, so one pass suffices
+	projects, _ := repository.NearestProjects(r.filtered(userID, opts), origin, geo.MaxDistanceKm, limit, opts)
+	for i := range projects {
+		projects[i].Workers = r.store.projectWorkers(projects[i].ID, userID, false)
+	}
+	return projects, nil
+}
+
+// filtered returns the user's projects matching the search and filters of
+// the options. Callers must hold the read lock.
+func (r *projectRepository) filtered(userID uint, opts repository.ListOptions) []model.Project {
+	projects := make([]model.Project, 0)
+	for _, project := range r.store.projects {
+		if project.DeletedAt.Valid || project.UserID != userID {
+			continue
+		}
+
+		// Apply search
+		if opts.Search != "" && !like(project.Name, opts.Search) && !like(project.Description, opts.Search) {
+			continue
+		}
+
+		// Apply the whitelisted filters
+		if opts.Query.Matches(repository.ProjectRow(project)) {
+			projects = append(projects, project)
+		}
+	}
+	return projects
+}
+
+// GetAvailableWorkers returns a page of the matching workers that are not
+// assigned to the project, nor with ExcludeBusy to an overlapping active project
+func (r *projectRepository) GetAvailableWorkers(ctx context.Context, userID uint, available repository.AvailableWorkers, opts repository.ListOptions) ([]model.Worker, repository.PageInfo, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	project, ok := r.store.projects[available.ProjectID]
+	if !ok || project.DeletedAt.Valid || project.UserID != userID {
+		return nil, repository.PageInfo{}, gorm.ErrRecordNotFound
+	}
+
+	workers := make([]model.Worker, 0)
+	for _, worker := range (&workerRepository{store: r.store}).filtered(userID, opts) {
+		if r.unavailable(worker.ID, project, available.ExcludeBusy, userID) {
+			continue
+		}
+		workers = append(workers, worker)
+	}
+
+	workers, info := listPage(workers, opts, repository.WorkerRow)
+	for i := range workers {
+		workers[i].Projects = r.store.workerProjects(workers[i].ID, userID)
+	}
+	return workers, info, nil
+}
+
+// unavailable reports whether a worker has an assignment to the project that
+// has not ended or, when busy ones are excluded, one to another active
+// project overlapping it
+func (r *projectRepository) unavailable(workerID uint, project model.Project, excludeBusy bool, userID uint) bool {
+	now := time.Now()
+	for _, assignment := range r.store.assignments {
+		if assignment.WorkerID != workerID || assignment.UserID != userID {
+			continue
+		}
+		if assignment.ProjectID == project.ID {
+			if !assignment.Ended(now) {
+				return true
+			}
+			continue
+		}
+		other, ok := r.store.projects[assignment.ProjectID]
+		if !excludeBusy || !ok || other.DeletedAt.Valid || other.Status != model.ProjectStatusActive {
+			continue
+		}
+		start, end := assignment.Period(other)
+		if project.Overlaps(start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// Update updates the non-zero attributes of a project and, when workers are
+// given, replaces its worker assignments
+func (r *projectRepository) Update(ctx context.Context, project *model.Project, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// First check if this project belongs to the user
+	existing, ok := r.store.projects[project.ID]
+	if !ok || existing.DeletedAt.Valid || existing.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+
+	version, err := repository.NextVersion(existing.Version, project.Version)
+	if err != nil {
+		return err
+	}
+	before := existing
+	project.Version = version
+	existing.Version = version
+
+	// Like gorm's Updates, only non-zero fields are written
+	if project.Name != "" {
+		existing.Name = project.Name
+	}
+	if project.Description != "" {
+		existing.Description = project.Description
+	}
+	if project.Status != "" {
+		existing.Status = project.Status
+	}
+	if !project.StartDate.IsZero() {
+		existing.StartDate = project.StartDate
+	}
+	if project.EndDate != nil {
+		existing.EndDate = project.EndDate
+	}
+	if project.Latitude != 0 {
+		existing.Latitude = project.Latitude
+	}
+	if project.Longitude != 0 {
+		existing.Longitude = project.Longitude
+	}
+	if project.Boundaries != nil {
+		existing.Boundaries = project.Boundaries
+	}
+	if project.Locations != nil {
+		existing.Locations = project.Locations
+	}
+	if project.UserID != 0 {
+		existing.UserID = project.UserID
+	}
+	existing.UpdatedAt = time.Now()
+	project.UpdatedAt = existing.UpdatedAt
+	r.store.projects[project.ID] = existing
+	if err := r.store.recordWrite(ctx, model.EntityTypeProject, project.ID, userID, before, existing, repository.ProjectHistoryFields); err != nil {
+		return err
+	}
+
+	// Replace the worker assignments with the correct user_id
+	if len(project.Workers) > 0 {
+		// The workers staying on the project keep their assignment details
+		now := time.Now()
+		removed := make(map[uint]bool)
+		for _, assignment := range r.store.assignments {
+			if assignment.ProjectID == project.ID && !assignment.Ended(now) {
+				removed[assignment.WorkerID] = true
+			}
+		}
+		for _, worker := range project.Workers {
+			if removed[worker.ID] {
+				delete(removed, worker.ID)
+				continue
+			}
+			assignment := model.NewAssignment(worker.ID, project.ID, userID)
+			if err := r.store.assign(ctx, &assignment); err != nil {
+				return err
+			}
+		}
+		for workerID := range removed {
+			for _, assignment := range r.store.assignments {
+				if assignment.ProjectID == project.ID && assignment.WorkerID == workerID && !assignment.Ended(now) {
+					r.store.endAssignment(assignment, existing)
+				}
+			}
+			r.store.record(repository.AssignmentChanges(ctx, r.store.workers[workerID], existing, model.LogTypeUnassign, userID)...)
+		}
+	}
+	return nil
+}
+
+// Patch writes the given columns of a project, zero values included, with
+// the version check of Update
+func (r *projectRepository) Patch(ctx context.Context, project *model.Project, fields []string, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// First check if this project belongs to the user
+	existing, ok := r.store.projects[project.ID]
+	if !ok || existing.DeletedAt.Valid || existing.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+
+	version, err := repository.NextVersion(existing.Version, project.Version)
+	if err != nil {
+		return err
+	}
+	before := existing
+	for _, field := range fields {
+		switch field {
+		case "name":
+			existing.Name = project.Name
+		case "description":
+			existing.Description = project.Description
+		case "status":
+			existing.Status = project.Status
+		case "start_date":
+			existing.StartDate = project.StartDate
+		case "end_date":
+			existing.EndDate = project.EndDate
+		case "latitude":
+			existing.Latitude = project.Latitude
+		case "longitude":
+			existing.Longitude = project.Longitude
+		case "boundaries":
+			existing.Boundaries = project.Boundaries
+		case "locations":
+			existing.Locations = project.Locations
+		default:
+			return fmt.Errorf("unknown project column %q", field)
+		}
+	}
+	existing.Version = version
+	existing.UpdatedAt = time.Now()
+	project.Version = version
+	project.UpdatedAt = existing.UpdatedAt
+	r.store.projects[project.ID] = existing
+	return r.store.recordWrite(ctx, model.EntityTypeProject, project.ID, userID, before, existing, repository.ProjectHistoryFields)
+}
+
+// Delete soft-deletes a project
+func (r *projectRepository) Delete(ctx context.Context, id uint, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	project, ok := r.store.projects[id]
+	if !ok || project.DeletedAt.Valid || project.UserID != userID {
+		return nil
+	}
+	project.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.store.projects[id] = project
+	r.store.record(repository.NewChange(ctx, model.EntityTypeProject, id, model.LogTypeDelete, userID, nil))
+	return nil
+}
+
+// AddWorker adds a worker to a project (ensuring both belong to the user)
+func (r *projectRepository) AddWorker(ctx context.Context, assignment *model.WorkerProject) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.assign(ctx, assignment)
+}
+
+// GetAssignment retrieves the assignment of a worker to a project of the user
+func (r *projectRepository) GetAssignment(ctx context.Context, projectID, workerID, userID uint) (*model.WorkerProject, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.assignment(projectID, workerID, userID)
+}
+
+// UpdateAssignment replaces the details of the latest assignment of a worker
+// to a project
+func (r *projectRepository) UpdateAssignment(ctx context.Context, assignment *model.WorkerProject) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	current, err := r.store.assignment(assignment.ProjectID, assignment.WorkerID, assignment.UserID)
+	if err != nil {
+		return err
+	}
+	assignment.ID = current.ID
+	project := r.store.projects[assignment.ProjectID]
+	if err := repository.FitAssignment(assignment, project, r.store.assignmentsOf(assignment.ProjectID, assignment.WorkerID, assignment.UserID)); err != nil {
+		return err
+	}
+	changes, err := repository.AssignmentUpdateChanges(ctx, r.store.workers[assignment.WorkerID], project,
+		current.AssignmentDetails, assignment.AssignmentDetails, assignment.UserID)
+	if err != nil {
+		return err
+	}
+	assignment.CreatedAt = current.CreatedAt
+	assignment.UpdatedAt = time.Now()
+	r.store.assignments[assignment.ID] = *assignment
+	r.store.record(changes...)
+	return nil
+}
+
+// GetConflicts returns the conflicts between the assignments of the user's workers
+func (r *projectRepository) GetConflicts(ctx context.Context, userID uint) ([]repository.Conflict, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return repository.FindConflicts(r.store.scheduled(userID, 0)), nil
+}
+
+// AssignmentConflicts returns the conflicts an assignment would cause
+func (r *projectRepository) AssignmentConflicts(ctx context.Context, assignment model.WorkerProject) ([]repository.Conflict, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if err := r.store.checkOwnership(assignment.WorkerID, assignment.ProjectID, assignment.UserID); err != nil {
+		return nil, err
+	}
+	project := r.store.projects[assignment.ProjectID]
+	worker := r.store.workers[assignment.WorkerID]
+	return repository.CandidateConflicts(r.store.scheduled(assignment.UserID, assignment.WorkerID), project, worker, assignment), nil
+}
+
+// RemoveWorker removes a worker from a project (ensuring both belong to the user)
+func (r *projectRepository) RemoveWorker(ctx context.Context, projectID, workerID, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.unassign(ctx, workerID, projectID, userID)
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/search"
+)
+
+type searchRepository struct {
+	store *Store
+}
+
+// NewSearchRepository creates an in-memory SearchRepository. It ranks like
+// the GORM repository does on databases without full-text search.
+func NewSearchRepository(store *Store) repository.SearchRepository {
+	return &searchRepository{
+		store: store,
+	}
+}
+
+// Search returns the user's workers and projects matching every term of the query
+func (r *searchRepository) Search(ctx context.Context, userID uint, query string, types []model.EntityType, limit int) ([]repository.SearchHit, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	terms := search.Terms(query)
+	hits := make([]repository.SearchHit, 0)
+	if len(terms) == 0 {
+		return hits, nil
+	}
+
+	if searchesType(types, model.EntityTypeWorker) {
+		for _, worker := range r.store.workers {
+			if worker.DeletedAt.Valid || worker.UserID != userID {
+				continue
+			}
+			rank, ok := search.Score(terms, search.Field{Text: worker.Name, Weight: search.WeightTitle}, search.Field{Text: worker.Position, Weight: search.WeightBody})
+			if !ok {
+				continue
+			}
+			hits = append(hits, repository.SearchHit{
+				Type:     model.EntityTypeWorker,
+				ID:       worker.ID,
+				Title:    worker.Name,
+				Subtitle: worker.Position,
+				Rank:     rank,
+				Highlights: search.HighlightFields(terms, map[string]string{
+					"name":     worker.Name,
+					"position": worker.Position,
+				}),
+			})
+		}
+	}
+
+	if searchesType(types, model.EntityTypeProject) {
+		for _, project := range r.store.projects {
+			if project.DeletedAt.Valid || project.UserID != userID {
+				continue
+			}
+			rank, ok := search.Score(terms, search.Field{Text: project.Name, Weight: search.WeightTitle}, search.Field{Text: project.Description, Weight: search.WeightBody})
+			if !ok {
+				continue
+			}
+			hits = append(hits, repository.SearchHit{
+				Type:     model.EntityTypeProject,
+				ID:       project.ID,
+				Title:    project.Name,
+				Subtitle: project.Status,
+				Rank:     rank,
+				Highlights: search.HighlightFields(terms, map[string]string{
+					"name":        project.Name,
+					"description": project.Description,
+				}),
+			})
+		}
+	}
+
+	return repository.SortHits(hits, limit), nil
+}
+
+// searchesType reports whether an entity type is searched; no types means all
+func searchesType(types []model.EntityType, entityType model.EntityType) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == entityType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,334 @@
+// Package memory provides in-memory implementations of the repository
+// interfaces. They follow the same per-user isolation rules as the GORM
+// repositories, so controllers can be exercised without a database.
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/query"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"gorm.io/gorm"
+)
+
+// Store holds the data shared by the in-memory repositories
+type Store struct {
+	mu          sync.RWMutex
+	workers     map[uint]model.Worker
+	projects    map[uint]model.Project
+	users       map[uint]model.User
+	logs        map[uint]model.ActivityLog
+	assignments map[uint]model.WorkerProject
+	timesheets  map[uint]model.Timesheet
+	changes     map[uint]model.EntityChange
+	lastID      map[string]uint
+}
+
+// NewStore creates an empty Store
+func NewStore() *Store {
+	return &Store{
+		workers:     make(map[uint]model.Worker),
+		projects:    make(map[uint]model.Project),
+		users:       make(map[uint]model.User),
+		logs:        make(map[uint]model.ActivityLog),
+		assignments: make(map[uint]model.WorkerProject),
+		timesheets:  make(map[uint]model.Timesheet),
+		changes:     make(map[uint]model.EntityChange),
+		lastID:      make(map[string]uint),
+	}
+}
+
+// nextID returns the next auto-increment ID of a table. Callers must hold the lock.
+func (s *Store) nextID(table string) uint {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// snapshot copies the store contents so they can be restored later
+func (s *Store) snapshot() *Store {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	copied := NewStore()
+	for id, worker := range s.workers {
+		copied.workers[id] = worker
+	}
+	for id, project := range s.projects {
+		copied.projects[id] = project
+	}
+	for id, user := range s.users {
+		copied.users[id] = user
+	}
+	for id, log := range s.logs {
+		copied.logs[id] = log
+	}
+	for id, assignment := range s.assignments {
+		copied.assignments[id] = assignment
+	}
+	for id, entry := range s.timesheets {
+		copied.timesheets[id] = entry
+	}
+	for id, change := range s.changes {
+		copied.changes[id] = change
+	}
+	for table, id := range s.lastID {
+		copied.lastID[table] = id
+	}
+	return copied
+}
+
+// restore replaces the store contents with a snapshot
+func (s *Store) restore(snapshot *Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.workers = snapshot.workers
+	s.projects = snapshot.projects
+	s.users = snapshot.users
+	s.logs = snapshot.logs
+	s.assignments = snapshot.assignments
+	s.timesheets = snapshot.timesheets
+	s.changes = snapshot.changes
+	s.lastID = snapshot.lastID
+}
+
+// workerProjects returns the projects a worker has an assignment to that has
+// not ended, with the same conditions as the GORM repositories: both the
+// project and the join row must belong to the user. Callers must hold the lock.
+func (s *Store) workerProjects(workerID, userID uint) []model.Project {
+	now := time.Now()
+	listed := make(map[uint]bool)
+	projects := make([]model.Project, 0)
+	for _, assignment := range s.assignments {
+		if assignment.WorkerID != workerID || assignment.UserID != userID || assignment.Ended(now) || listed[assignment.ProjectID] {
+			continue
+		}
+		project, ok := s.projects[assignment.ProjectID]
+		if !ok || project.DeletedAt.Valid || project.UserID != userID {
+			continue
+		}
+		listed[project.ID] = true
+		projects = append(projects, project)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	return projects
+}
+
+// projectWorkers returns the workers of a project with their assignment, with
+// the same conditions as the GORM repositories: both the worker and the join
+// row must belong to the user. A worker is listed once, with the first of
+// their assignments that have not ended; withEnded lists a worker once per
+// assignment instead, ended ones included. Callers must hold the lock.
+func (s *Store) projectWorkers(projectID, userID uint, withEnded bool) []model.Worker {
+	now := time.Now()
+	project := s.projects[projectID]
+	workers := make([]model.Worker, 0)
+	for _, assignment := range s.sortedAssignments() {
+		if assignment.ProjectID != projectID || assignment.UserID != userID || (!withEnded && assignment.Ended(now)) {
+			continue
+		}
+		worker, ok := s.workers[assignment.WorkerID]
+		if !ok || worker.DeletedAt.Valid || worker.UserID != userID {
+			continue
+		}
+		worker.Assignment = &assignment
+		if !withEnded {
+			listed := slices.IndexFunc(workers, func(w model.Worker) bool { return w.ID == worker.ID })
+			if listed >= 0 {
+				start, _ := assignment.Period(project)
+				listedStart, _ := workers[listed].Assignment.Period(project)
+				if start.Before(listedStart) {
+					workers[listed] = worker
+				}
+				continue
+			}
+		}
+		workers = append(workers, worker)
+	}
+	sort.SliceStable(workers, func(i, j int) bool { return workers[i].ID < workers[j].ID })
+	return workers
+}
+
+// sortedAssignments returns every assignment, oldest first. Callers must hold
+// the lock.
+func (s *Store) sortedAssignments() []model.WorkerProject {
+	assignments := make([]model.WorkerProject, 0, len(s.assignments))
+	for _, assignment := range s.assignments {
+		assignments = append(assignments, assignment)
+	}
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].ID < assignments[j].ID })
+	return assignments
+}
+
+// paginate applies the same offset/limit rules as the GORM repositories
+func paginate[T any](items []T, page, pageSize int) []T {
+	if page <= 0 || pageSize <= 0 {
+		return items
+	}
+	offset := (page - 1) * pageSize
+	if offset >= len(items) {
+		return []T{}
+	}
+	end := offset + pageSize
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
+
+// listPage sorts the matching items and returns the page selected by the
+// options, in offset or cursor mode like the GORM repositories
+func listPage[T any](items []T, opts repository.ListOptions, row func(T) query.Row) ([]T, repository.PageInfo) {
+	var info repository.PageInfo
+
+	// Apply sorting
+	sort.SliceStable(items, func(i, j int) bool {
+		return opts.Query.Less(row(items[i]), row(items[j]))
+	})
+
+	if opts.Cursor == nil {
+		info.Total = int64(len(items))
+		return paginate(items, opts.Page, opts.PageSize), info
+	}
+
+	// Keep the rows past the cursor plus one extra row in the direction of
+	// the cursor, still in list order
+	rows := make([]T, 0)
+	for _, item := range items {
+		if opts.Query.After(row(item), opts.Cursor) {
+			rows = append(rows, item)
+		}
+	}
+	if len(rows) > opts.PageSize+1 {
+		if opts.Cursor.Backward {
+			rows = rows[len(rows)-opts.PageSize-1:]
+		} else {
+			rows = rows[:opts.PageSize+1]
+		}
+	}
+
+	rows, info.NextCursor, info.PrevCursor = query.CursorPage(opts.Query, opts.Cursor, rows, opts.PageSize, row)
+	return rows, info
+}
+
+// like mimics "LOWER(column) LIKE '%term%'" with a lowercased term
+func like(value, term string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(term))
+}
+
+// assign creates a join row after verifying that both the worker and the
+// project belong to the user of the assignment, and fitting it among the
+// other assignments of the worker to the project. Callers must hold the write lock.
+func (s *Store) assign(ctx context.Context, assignment *model.WorkerProject) error {
+	if err := s.checkOwnership(assignment.WorkerID, assignment.ProjectID, assignment.UserID); err != nil {
+		return err
+	}
+
+	project := s.projects[assignment.ProjectID]
+	if err := repository.FitAssignment(assignment, project, s.assignmentsOf(assignment.ProjectID, assignment.WorkerID, assignment.UserID)); err != nil {
+		return err
+	}
+	assignment.ID = s.nextID("worker_projects")
+	assignment.CreatedAt = time.Now()
+	assignment.UpdatedAt = assignment.CreatedAt
+	s.assignments[assignment.ID] = *assignment
+	s.record(repository.AssignmentChanges(ctx, s.workers[assignment.WorkerID], project, model.LogTypeAssign, assignment.UserID)...)
+	return nil
+}
+
+// unassign ends the latest assignment of a worker to a project after
+// verifying that both belong to the user. Callers must hold the write lock.
+func (s *Store) unassign(ctx context.Context, workerID, projectID, userID uint) error {
+	if err := s.checkOwnership(workerID, projectID, userID); err != nil {
+		return err
+	}
+
+	assignments := s.assignmentsOf(projectID, workerID, userID)
+	if len(assignments) == 0 || assignments[len(assignments)-1].Ended(time.Now()) {
+		return nil
+	}
+	s.endAssignment(assignments[len(assignments)-1], s.projects[projectID])
+	s.record(repository.AssignmentChanges(ctx, s.workers[workerID], s.projects[projectID], model.LogTypeUnassign, userID)...)
+	return nil
+}
+
+// endAssignment ends an assignment as of today, or withdraws it when it has
+// not started yet. Callers must hold the write lock.
+func (s *Store) endAssignment(assignment model.WorkerProject, project model.Project) {
+	if !repository.EndAssignment(&assignment, project, time.Now()) {
+		delete(s.assignments, assignment.ID)
+		return
+	}
+	assignment.UpdatedAt = time.Now()
+	s.assignments[assignment.ID] = assignment
+}
+
+// checkOwnership verifies that a worker and a project exist and belong to the user
+func (s *Store) checkOwnership(workerID, projectID, userID uint) error {
+	worker, ok := s.workers[workerID]
+	if !ok || worker.DeletedAt.Valid || worker.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	project, ok := s.projects[projectID]
+	if !ok || project.DeletedAt.Valid || project.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// assignmentsOf returns the assignments of a worker to a project of the
+// user, oldest first. Callers must hold the lock.
+func (s *Store) assignmentsOf(projectID, workerID, userID uint) []model.WorkerProject {
+	assignments := make([]model.WorkerProject, 0)
+	for _, assignment := range s.sortedAssignments() {
+		if assignment.ProjectID == projectID && assignment.WorkerID == workerID && assignment.UserID == userID {
+			assignments = append(assignments, assignment)
+		}
+	}
+	return assignments
+}
+
+// assignment returns the latest assignment of a worker to a project, both
+// still belonging to the user. Callers must hold the lock.
+func (s *Store) assignment(projectID, workerID, userID uint) (*model.WorkerProject, error) {
+	if err := s.checkOwnership(workerID, projectID, userID); err != nil {
+		return nil, err
+	}
+	assignments := s.assignmentsOf(projectID, workerID, userID)
+	if len(assignments) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &assignments[len(assignments)-1], nil
+}
+
+// scheduled returns the assignments of the user's workers, or of one worker
+// when workerID is not 0, leaving out trashed workers and projects
+func (s *Store) scheduled(userID, workerID uint) []repository.Assignment {
+	assignments := make([]repository.Assignment, 0)
+	for _, assignment := range s.assignments {
+		if assignment.UserID != userID || (workerID != 0 && assignment.WorkerID != workerID) {
+			continue
+		}
+		project, ok := s.projects[assignment.ProjectID]
+		if !ok || project.DeletedAt.Valid || project.UserID != userID {
+			continue
+		}
+		worker, ok := s.workers[assignment.WorkerID]
+		if !ok || worker.DeletedAt.Valid || worker.UserID != userID {
+			continue
+		}
+		assignments = append(assignments, repository.Assignment{
+			ProjectID: project.ID, ProjectName: project.Name, ProjectStatus: project.Status,
+			ProjectStartDate: project.StartDate, ProjectEndDate: project.EndDate,
+			WorkerID: worker.ID, WorkerName: worker.Name, Position: worker.Position, Salary: worker.Salary,
+			AssignmentDetails: assignment.AssignmentDetails,
+		})
+	}
+	return assignments
+}
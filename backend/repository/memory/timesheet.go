@@ -0,0 +1,325 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"gorm.io/gorm"
+)
+
+type timesheetRepository struct {
+	store *Store
+}
+
+// NewTimesheetRepository creates an in-memory TimesheetRepository
+func NewTimesheetRepository(store *Store) repository.TimesheetRepository {
+	return &timesheetRepository{
+		store: store,
+	}
+}
+
+// checkAssigned verifies that one of the assignments of the worker to the
+// project covers the date of the entry, both belonging to its user. Callers
+// must hold the lock.
+func (r *timesheetRepository) checkAssigned(entry *model.Timesheet) error {
+	if err := r.store.checkOwnership(entry.WorkerID, entry.ProjectID, entry.UserID); err != nil {
+		return err
+	}
+	for _, assignment := range r.store.assignmentsOf(entry.ProjectID, entry.WorkerID, entry.UserID) {
+		if assignment.Covers(r.store.projects[entry.ProjectID], entry.Date) {
+			return nil
+		}
+	}
+	return repository.ErrNotAssigned
+}
+
+// checkUnlocked verifies that the worker has no approved entry for the
+// project in the week of an entry. Callers must hold the lock.
+func (r *timesheetRepository) checkUnlocked(entry *model.Timesheet) error {
+	start := weekStart(entry.Date)
+	end := start.AddDate(0, 0, 6)
+	for _, existing := range r.store.timesheets {
+		if existing.UserID == entry.UserID && existing.WorkerID == entry.WorkerID && existing.ProjectID == entry.ProjectID &&
+			existing.Status == model.TimesheetApproved && !existing.Date.Before(start) && !existing.Date.After(end) {
+			return repository.ErrPeriodLocked
+		}
+	}
+	return nil
+}
+
+// weekStart returns the Monday starting the week of a date
+func weekStart(date time.Time) time.Time {
+	return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+}
+
+// keepReview carries the status and review fields of the stored entry over
+// to the entry replacing it
+func keepReview(entry *model.Timesheet, current model.Timesheet) {
+	entry.Status = current.Status
+	entry.SubmittedAt = current.SubmittedAt
+	entry.ReviewedBy = current.ReviewedBy
+	entry.ReviewedAt = current.ReviewedAt
+	entry.ReviewComment = current.ReviewComment
+	entry.CreatedAt = current.CreatedAt
+}
+
+// reportsTo reports whether the owner of an entry reports to a manager; any
+// user does when managerID is 0. Callers must hold the lock.
+func (r *timesheetRepository) reportsTo(entry model.Timesheet, managerID uint) bool {
+	user, ok := r.store.users[entry.UserID]
+	if !ok || user.DeletedAt.Valid {
+		return false
+	}
+	return managerID == 0 || (user.ManagerID != nil && *user.ManagerID == managerID)
+}
+
+// findDay returns the entry of the worker for the project and date of an
+// entry, other than the entry itself. Callers must hold the lock.
+func (r *timesheetRepository) findDay(entry *model.Timesheet) (model.Timesheet, bool) {
+	for id, existing := range r.store.timesheets {
+		if id != entry.ID && existing.UserID == entry.UserID && existing.WorkerID == entry.WorkerID &&
+			existing.ProjectID == entry.ProjectID && existing.Date.Equal(entry.Date) {
+			return existing, true
+		}
+	}
+	return model.Timesheet{}, false
+}
+
+// Create creates a new timesheet entry
+func (r *timesheetRepository) Create(ctx context.Context, entry *model.Timesheet) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.checkAssigned(entry); err != nil {
+		return err
+	}
+	if _, ok := r.findDay(entry); ok {
+		return gorm.ErrDuplicatedKey
+	}
+	if err := r.checkUnlocked(entry); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	keepReview(entry, model.Timesheet{Status: model.TimesheetDraft})
+	entry.ID = r.store.nextID("timesheets")
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	r.store.timesheets[entry.ID] = *entry
+	return nil
+}
+
+// GetByID retrieves a timesheet entry by ID and user ID
+func (r *timesheetRepository) GetByID(ctx context.Context, id uint, userID uint) (*model.Timesheet, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	entry, ok := r.store.timesheets[id]
+	if !ok || entry.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &entry, nil
+}
+
+// GetAll retrieves the user's timesheet entries with optional filtering and sorting
+func (r *timesheetRepository) GetAll(ctx context.Context, userID uint, opts repository.ListOptions) ([]model.Timesheet, repository.PageInfo, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	entries := make([]model.Timesheet, 0)
+	for _, entry := range r.store.timesheets {
+		if entry.UserID != userID {
+			continue
+		}
+		if opts.Search != "" && !like(entry.Notes, opts.Search) {
+			continue
+		}
+		if opts.Query.Matches(repository.TimesheetRow(entry)) {
+			entries = append(entries, entry)
+		}
+	}
+
+	entries, info := listPage(entries, opts, repository.TimesheetRow)
+	return entries, info, nil
+}
+
+// Update replaces the fields of a timesheet entry
+func (r *timesheetRepository) Update(ctx context.Context, entry *model.Timesheet) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	current, ok := r.store.timesheets[entry.ID]
+	if !ok || current.UserID != entry.UserID {
+		return gorm.ErrRecordNotFound
+	}
+	if !current.Editable() {
+		return repository.ErrLocked
+	}
+	if err := r.checkAssigned(entry); err != nil {
+		return err
+	}
+	if _, ok := r.findDay(entry); ok {
+		return gorm.ErrDuplicatedKey
+	}
+	// Neither the week the entry is in nor the one it moves to may be approved
+	if err := r.checkUnlocked(&current); err != nil {
+		return err
+	}
+	if err := r.checkUnlocked(entry); err != nil {
+		return err
+	}
+
+	keepReview(entry, current)
+	entry.UpdatedAt = time.Now()
+	r.store.timesheets[entry.ID] = *entry
+	return nil
+}
+
+// Save creates or replaces the entry of a worker for a project and date
+func (r *timesheetRepository) Save(ctx context.Context, entry *model.Timesheet) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.checkAssigned(entry); err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	entry.ID = 0
+	existing, ok := r.findDay(entry)
+	if ok && !existing.Editable() {
+		return false, repository.ErrLocked
+	}
+	if err := r.checkUnlocked(entry); err != nil {
+		return false, err
+	}
+	if ok {
+		entry.ID = existing.ID
+		keepReview(entry, existing)
+	} else {
+		keepReview(entry, model.Timesheet{Status: model.TimesheetDraft, CreatedAt: now})
+		entry.ID = r.store.nextID("timesheets")
+	}
+	entry.UpdatedAt = now
+	r.store.timesheets[entry.ID] = *entry
+	return !ok, nil
+}
+
+// Delete deletes a timesheet entry
+func (r *timesheetRepository) Delete(ctx context.Context, id uint, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entry, ok := r.store.timesheets[id]
+	if !ok || entry.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	if !entry.Editable() {
+		return repository.ErrLocked
+	}
+	if err := r.checkUnlocked(&entry); err != nil {
+		return err
+	}
+	delete(r.store.timesheets, id)
+	return nil
+}
+
+// Summarize adds up the hours of the matching entries, leaving out those of
+// trashed workers and projects
+func (r *timesheetRepository) Summarize(ctx context.Context, userID uint, summary repository.TimesheetSummaryQuery) ([]repository.TimesheetSummary, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	entries := make([]repository.TimesheetHours, 0)
+	for _, entry := range r.store.timesheets {
+		if entry.UserID != userID ||
+			(summary.WorkerID != 0 && entry.WorkerID != summary.WorkerID) ||
+			(summary.ProjectID != 0 && entry.ProjectID != summary.ProjectID) ||
+			(summary.Status != "" && entry.Status != summary.Status) ||
+			(summary.From != nil && entry.Date.Before(*summary.From)) ||
+			(summary.To != nil && entry.Date.After(*summary.To)) {
+			continue
+		}
+		worker, ok := r.store.workers[entry.WorkerID]
+		if !ok || worker.DeletedAt.Valid || worker.UserID != userID {
+			continue
+		}
+		project, ok := r.store.projects[entry.ProjectID]
+		if !ok || project.DeletedAt.Valid || project.UserID != userID {
+			continue
+		}
+		entries = append(entries, repository.TimesheetHours{
+			WorkerID: worker.ID, WorkerName: worker.Name, ProjectID: project.ID, ProjectName: project.Name,
+			Date: entry.Date, Hours: entry.Hours, OvertimeHours: entry.OvertimeHours, BreakMinutes: entry.BreakMinutes,
+		})
+	}
+	return repository.SummarizeTimesheets(entries, summary.GroupBy, summary.Period), nil
+}
+
+// ListApprovals lists the submitted entries awaiting review, leaving out
+// those of trashed workers and projects
+func (r *timesheetRepository) ListApprovals(ctx context.Context, managerID uint, opts repository.ListOptions) ([]repository.TimesheetApproval, repository.PageInfo, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	approvals := make([]repository.TimesheetApproval, 0)
+	for _, entry := range r.store.timesheets {
+		if entry.Status != model.TimesheetSubmitted || !r.reportsTo(entry, managerID) {
+			continue
+		}
+		worker, ok := r.store.workers[entry.WorkerID]
+		if !ok || worker.DeletedAt.Valid {
+			continue
+		}
+		project, ok := r.store.projects[entry.ProjectID]
+		if !ok || project.DeletedAt.Valid {
+			continue
+		}
+		if opts.Search != "" && !like(entry.Notes, opts.Search) {
+			continue
+		}
+		if !opts.Query.Matches(repository.TimesheetRow(entry)) {
+			continue
+		}
+		user := r.store.users[entry.UserID]
+		approvals = append(approvals, repository.TimesheetApproval{
+			Timesheet: entry, Username: user.Username, ManagerID: user.ManagerID,
+			WorkerName: worker.Name, ProjectName: project.Name,
+		})
+	}
+
+	approvals, info := listPage(approvals, opts, repository.TimesheetApprovalRow)
+	return approvals, info, nil
+}
+
+// GetForReview retrieves an entry of a user reporting to a manager
+func (r *timesheetRepository) GetForReview(ctx context.Context, id uint, managerID uint) (*model.Timesheet, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	entry, ok := r.store.timesheets[id]
+	if !ok || !r.reportsTo(entry, managerID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &entry, nil
+}
+
+// Transition writes the status and review fields of an entry still in status from
+func (r *timesheetRepository) Transition(ctx context.Context, entry *model.Timesheet, from string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	current, ok := r.store.timesheets[entry.ID]
+	if !ok || current.Status != from {
+		return repository.ErrVersionConflict
+	}
+	status := *entry
+	status.CreatedAt = current.CreatedAt
+	keepReview(&current, status)
+	current.UpdatedAt = time.Now()
+	r.store.timesheets[entry.ID] = current
+	*entry = current
+	return nil
+}
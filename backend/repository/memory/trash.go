@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"gorm.io/gorm"
+)
+
+type trashRepository struct {
+	store *Store
+}
+
+// NewTrashRepository creates an in-memory TrashRepository
+func NewTrashRepository(store *Store) repository.TrashRepository {
+	return &trashRepository{
+		store: store,
+	}
+}
+
+// List retrieves the user's trashed items of the given types (all when none
+// are given), most recently deleted first
+func (r *trashRepository) List(ctx context.Context, userID uint, types []model.EntityType, page, pageSize int) ([]repository.TrashItem, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	items := make([]repository.TrashItem, 0)
+	if searchesType(types, model.EntityTypeWorker) {
+		for _, worker := range r.store.workers {
+			if worker.DeletedAt.Valid && worker.UserID == userID {
+				items = append(items, r.store.trashItem(model.EntityTypeWorker, worker.ID, worker.Name, worker.DeletedAt.Time))
+			}
+		}
+	}
+	if searchesType(types, model.EntityTypeProject) {
+		for _, project := range r.store.projects {
+			if project.DeletedAt.Valid && project.UserID == userID {
+				items = append(items, r.store.trashItem(model.EntityTypeProject, project.ID, project.Name, project.DeletedAt.Time))
+			}
+		}
+	}
+
+	total := int64(len(items))
+	return repository.PageTrash(items, page, pageSize), total, nil
+}
+
+// Restore un-deletes an item of the user's trash
+func (r *trashRepository) Restore(ctx context.Context, userID uint, entityType model.EntityType, id uint) (*repository.TrashItem, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	switch entityType {
+	case model.EntityTypeWorker:
+		worker, ok := r.store.workers[id]
+		if !ok || !worker.DeletedAt.Valid || worker.UserID != userID {
+			return nil, gorm.ErrRecordNotFound
+		}
+		item := r.store.trashItem(entityType, id, worker.Name, worker.DeletedAt.Time)
+		worker.DeletedAt = gorm.DeletedAt{}
+		worker.Version++
+		r.store.workers[id] = worker
+		r.store.record(repository.NewChange(ctx, entityType, id, model.LogTypeRestore, userID, nil))
+		return &item, nil
+	case model.EntityTypeProject:
+		project, ok := r.store.projects[id]
+		if !ok || !project.DeletedAt.Valid || project.UserID != userID {
+			return nil, gorm.ErrRecordNotFound
+		}
+		item := r.store.trashItem(entityType, id, project.Name, project.DeletedAt.Time)
+		project.DeletedAt = gorm.DeletedAt{}
+		project.Version++
+		r.store.projects[id] = project
+		r.store.record(repository.NewChange(ctx, entityType, id, model.LogTypeRestore, userID, nil))
+		return &item, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// Purge permanently deletes an item of the user's trash with its assignments and timesheets
+func (r *trashRepository) Purge(ctx context.Context, userID uint, entityType model.EntityType, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	switch entityType {
+	case model.EntityTypeWorker:
+		worker, ok := r.store.workers[id]
+		if !ok || !worker.DeletedAt.Valid || worker.UserID != userID {
+			return gorm.ErrRecordNotFound
+		}
+		r.store.purgeWorker(id)
+		return nil
+	case model.EntityTypeProject:
+		project, ok := r.store.projects[id]
+		if !ok || !project.DeletedAt.Valid || project.UserID != userID {
+			return gorm.ErrRecordNotFound
+		}
+		r.store.purgeProject(id)
+		return nil
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// PurgeDeletedBefore permanently deletes every worker and project trashed
+// before the cutoff, for all users, and returns how many were deleted
+func (r *trashRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var purged int64
+	for id, worker := range r.store.workers {
+		if worker.DeletedAt.Valid && worker.DeletedAt.Time.Before(cutoff) {
+			r.store.purgeWorker(id)
+			purged++
+		}
+	}
+	for id, project := range r.store.projects {
+		if project.DeletedAt.Valid && project.DeletedAt.Time.Before(cutoff) {
+			r.store.purgeProject(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// trashItem describes a trashed record, counting its assignments to records
+// that are not trashed. Callers must hold the lock.
+func (s *Store) trashItem(entityType model.EntityType, id uint, name string, deletedAt time.Time) repository.TrashItem {
+	item := repository.TrashItem{Type: entityType, ID: id, Name: name, DeletedAt: deletedAt}
+	for _, assignment := range s.assignments {
+		switch {
+		case entityType == model.EntityTypeWorker && assignment.WorkerID == id:
+			if project, ok := s.projects[assignment.ProjectID]; ok && !project.DeletedAt.Valid {
+				item.Assignments++
+			}
+		case entityType == model.EntityTypeProject && assignment.ProjectID == id:
+			if worker, ok := s.workers[assignment.WorkerID]; ok && !worker.DeletedAt.Valid {
+				item.Assignments++
+			}
+		}
+	}
+	return item
+}
+
+// purgeWorker deletes a worker, its assignments, timesheets and history. Callers
+// must hold the write lock.
+func (s *Store) purgeWorker(id uint) {
+	delete(s.workers, id)
+	s.forgetHistory(model.EntityTypeWorker, id)
+	for assignmentID, assignment := range s.assignments {
+		if assignment.WorkerID == id {
+			delete(s.assignments, assignmentID)
+		}
+	}
+	for entryID, entry := range s.timesheets {
+		if entry.WorkerID == id {
+			delete(s.timesheets, entryID)
+		}
+	}
+}
+
+// purgeProject deletes a project, its assignments, timesheets and history. Callers
+// must hold the write lock.
+func (s *Store) purgeProject(id uint) {
+	delete(s.projects, id)
+	s.forgetHistory(model.EntityTypeProject, id)
+	for assignmentID, assignment := range s.assignments {
+		if assignment.ProjectID == id {
+			delete(s.assignments, assignmentID)
+		}
+	}
+	for entryID, entry := range s.timesheets {
+		if entry.ProjectID == id {
+			delete(s.timesheets, entryID)
+		}
+	}
+}
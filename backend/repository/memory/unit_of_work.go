@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+)
+
+// NewRepositories creates every in-memory repository on top of the store
+func NewRepositories(store *Store) *repository.Repositories {
+	return &repository.Repositories{
+		Workers:    NewWorkerRepository(store),
+		Projects:   NewProjectRepository(store),
+		Timesheets: NewTimesheetRepository(store),
+		Users:      NewUserRepository(store),
+		Logs:       NewLogRepository(store),
+		Nested:     NewUnitOfWork(store),
+	}
+}
+
+type unitOfWork struct {
+	store *Store
+}
+
+// NewUnitOfWork creates an in-memory UnitOfWork. Changes made by a failed
+// unit of work are rolled back by restoring a snapshot of the store; unlike a
+// database transaction, concurrent writes made meanwhile are rolled back too.
+func NewUnitOfWork(store *Store) repository.UnitOfWork {
+	return &unitOfWork{
+		store: store,
+	}
+}
+
+// Do runs fn and restores the store if it returns an error or panics
+func (u *unitOfWork) Do(ctx context.Context, fn func(repos *repository.Repositories) error) (err error) {
+	snapshot := u.store.snapshot()
+	defer func() {
+		if r := recover(); r != nil {
+			u.store.restore(snapshot)
+			panic(r)
+		}
+		if err != nil {
+			u.store.restore(snapshot)
+		}
+	}()
+
+	return fn(NewRepositories(u.store))
+}
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type userRepository struct {
+	store *Store
+}
+
+// NewUserRepository creates an in-memory UserRepository
+func NewUserRepository(store *Store) repository.UserRepository {
+	return &userRepository{
+		store: store,
+	}
+}
+
+// CreateUser creates a new user with hashed password
+func (r *userRepository) CreateUser(ctx context.Context, user *model.User, plainPassword string) error {
+	// Hash the password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(plainPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// Enforce the unique indexes on username and email
+	for _, existing := range r.store.users {
+		if existing.Username == user.Username || existing.Email == user.Email {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	// Apply the column defaults
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	user.Active = true
+
+	now := time.Now()
+	user.ID = r.store.nextID("users")
+	user.PasswordHash = string(hashedPassword)
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	r.store.users[user.ID] = *user
+	return nil
+}
+
+// findUser returns the first live user matching the predicate. Callers must hold the lock.
+func (r *userRepository) findUser(match func(model.User) bool) (*model.User, error) {
+	for _, user := range r.store.users {
+		if !user.DeletedAt.Valid && match(user) {
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// GetUserByID retrieves a user by ID
+func (r *userRepository) GetUserByID(ctx context.Context, id uint) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.findUser(func(user model.User) bool { return user.ID == id })
+}
+
+// GetUserByUsername retrieves a user by username
+func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.findUser(func(user model.User) bool { return user.Username == username })
+}
+
+// GetUserByEmail retrieves a user by email
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.findUser(func(user model.User) bool { return user.Email == email })
+}
+
+// ValidateCredentials validates user credentials and returns the user if valid
+func (r *userRepository) ValidateCredentials(ctx context.Context, username, password string) (*model.User, error) {
+	user, err := r.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid credentials")
+		}
+		return nil, err
+	}
+
+	// Check if user is active
+	if !user.Active {
+		return nil, errors.New("account is inactive")
+	}
+
+	// Compare password with hashed password
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, errors.New("invalid credentials")
+	}
+
+	return user, nil
+}
+
+// updateUser applies a change to a stored user; missing users are ignored like an UPDATE matching no rows
+func (r *userRepository) updateUser(userID uint, change func(user *model.User)) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[userID]
+	if !ok || user.DeletedAt.Valid {
+		return
+	}
+	change(&user)
+	user.UpdatedAt = time.Now()
+	r.store.users[userID] = user
+}
+
+// UpdateLastLogin updates the last login timestamp for a user
+func (r *userRepository) UpdateLastLogin(ctx context.Context, userID uint) error {
+	now := time.Now()
+	r.updateUser(userID, func(user *model.User) { user.LastLogin = &now })
+	return nil
+}
+
+// ChangePassword changes a user's password
+func (r *userRepository) ChangePassword(ctx context.Context, userID uint, newPassword string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	r.updateUser(userID, func(user *model.User) { user.PasswordHash = string(hashedPassword) })
+	return nil
+}
+
+// GetAllUsers retrieves all users with pagination and search
+func (r *userRepository) GetAllUsers(ctx context.Context, page, pageSize int, search string) ([]model.User, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := make([]model.User, 0)
+	for _, user := range r.store.users {
+		if user.DeletedAt.Valid {
+			continue
+		}
+		if search != "" && !like(user.Username, search) && !like(user.Email, search) {
+			continue
+		}
+
+		// Clear password hashes
+		user.PasswordHash = ""
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	total := int64(len(users))
+	return paginate(users, page, pageSize), total, nil
+}
+
+// UpdateUserStatus activates or deactivates a user
+func (r *userRepository) UpdateUserStatus(ctx context.Context, userID uint, active bool) error {
+	r.updateUser(userID, func(user *model.User) { user.Active = active })
+	return nil
+}
+
+// UpdateUserRole changes a user's role
+func (r *userRepository) UpdateUserRole(ctx context.Context, userID uint, role string) error {
+	// Validate role
+	if !model.ValidRole(role) {
+		return errors.New("invalid role")
+	}
+
+	r.updateUser(userID, func(user *model.User) { user.Role = role })
+	return nil
+}
+
+// UpdateUserManager sets or clears a user's manager
+func (r *userRepository) UpdateUserManager(ctx context.Context, userID uint, managerID *uint) error {
+	r.store.mu.RLock()
+	user, ok := r.store.users[userID]
+	r.store.mu.RUnlock()
+	if !ok || user.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+
+	r.updateUser(userID, func(user *model.User) { user.ManagerID = managerID })
+	return nil
+}
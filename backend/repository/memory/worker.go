@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/repository"
+	"gorm.io/gorm"
+)
+
+type workerRepository struct {
+	store *Store
+}
+
+// NewWorkerRepository creates an in-memory WorkerRepository
+func NewWorkerRepository(store *Store) repository.WorkerRepository {
+	return &workerRepository{
+		store: store,
+	}
+}
+
+// Create creates a new worker
+func (r *workerRepository) Create(ctx context.Context, worker *model.Worker) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	worker.ID = r.store.nextID("workers")
+	worker.Version = 1
+	worker.CreatedAt = now
+	worker.UpdatedAt = now
+
+	stored := *worker
+	stored.Projects = nil
+	r.store.workers[worker.ID] = stored
+	return r.store.recordWrite(ctx, model.EntityTypeWorker, worker.ID, worker.UserID, nil, stored, repository.WorkerHistoryFields)
+}
+
+// GetByID retrieves a worker by ID and user ID
+func (r *workerRepository) GetByID(ctx context.Context, id uint, userID uint) (*model.Worker, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	worker, ok := r.store.workers[id]
+	if !ok || worker.DeletedAt.Valid || worker.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	worker.Projects = r.store.workerProjects(id, userID)
+	return &worker, nil
+}
+
+// GetAll retrieves all workers with optional filtering and sorting for a specific user
+func (r *workerRepository) GetAll(ctx context.Context, userID uint, opts repository.ListOptions) ([]model.Worker, repository.PageInfo, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	workers, info := listPage(r.filtered(userID, opts), opts, repository.WorkerRow)
+	for i := range workers {
+		workers[i].Projects = r.store.workerProjects(workers[i].ID, userID)
+	}
+	return workers, info, nil
+}
+
+// Each calls fn with every matching worker, in list order
+func (r *workerRepository) Each(ctx context.Context, userID uint, opts repository.ListOptions, fn func(model.Worker) error) error {
+	r.store.mu.RLock()
+	opts.Page, opts.PageSize, opts.Cursor = 0, 0, nil
+	workers, _ := listPage(r.filtered(userID, opts), opts, repository.WorkerRow)
+	r.store.mu.RUnlock()
+
+	for _, worker := range workers {
+		if err := fn(worker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filtered returns the user's workers matching the search and filters of the options
+func (r *workerRepository) filtered(userID uint, opts repository.ListOptions) []model.Worker {
+	workers := make([]model.Worker, 0)
+	for _, worker := range r.store.workers {
+		if worker.DeletedAt.Valid || worker.UserID != userID {
+			continue
+		}
+
+		// Apply search
+		if opts.Search != "" && !like(worker.Name, opts.Search) && !like(worker.Position, opts.Search) {
+			continue
+		}
+
+		// Apply the whitelisted filters
+		if opts.Query.Matches(repository.WorkerRow(worker)) {
+			workers = append(workers, worker)
+		}
+	}
+	return workers
+}
+
+// Update updates a worker, replacing all of its fields like gorm's Save. A
+// version other than the current one fails with ErrVersionConflict.
+func (r *workerRepository) Update(ctx context.Context, worker *model.Worker, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// First check if this worker belongs to the user
+	existing, ok := r.store.workers[worker.ID]
+	if !ok || existing.DeletedAt.Valid || existing.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+
+	version, err := repository.NextVersion(existing.Version, worker.Version)
+	if err != nil {
+		return err
+	}
+	worker.Version = version
+	worker.CreatedAt = existing.CreatedAt
+	worker.UpdatedAt = time.Now()
+	stored := *worker
+	stored.Projects = nil
+	r.store.workers[worker.ID] = stored
+	return r.store.recordWrite(ctx, model.EntityTypeWorker, worker.ID, userID, existing, stored, repository.WorkerHistoryFields)
+}
+
+// Patch writes the given columns of a worker, with the version check of Update
+func (r *workerRepository) Patch(ctx context.Context, worker *model.Worker, fields []string, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// First check if this worker belongs to the user
+	existing, ok := r.store.workers[worker.ID]
+	if !ok || existing.DeletedAt.Valid || existing.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+
+	version, err := repository.NextVersion(existing.Version, worker.Version)
+	if err != nil {
+		return err
+	}
+	before := existing
+	for _, field := range fields {
+		switch field {
+		case "name":
+			existing.Name = worker.Name
+		case "age":
+			existing.Age = worker.Age
+		case "position":
+			existing.Position = worker.Position
+		case "salary":
+			existing.Salary = worker.Salary
+		default:
+			return fmt.Errorf("unknown worker column %q", field)
+		}
+	}
+	existing.Version = version
+	existing.UpdatedAt = time.Now()
+	worker.Version = version
+	worker.UpdatedAt = existing.UpdatedAt
+	r.store.workers[worker.ID] = existing
+	return r.store.recordWrite(ctx, model.EntityTypeWorker, worker.ID, userID, before, existing, repository.WorkerHistoryFields)
+}
+
+// Delete soft-deletes a worker
+func (r *workerRepository) Delete(ctx context.Context, id uint, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	worker, ok := r.store.workers[id]
+	if !ok || worker.DeletedAt.Valid || worker.UserID != userID {
+		return nil
+	}
+	worker.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.store.workers[id] = worker
+	r.store.record(repository.NewChange(ctx, model.EntityTypeWorker, id, model.LogTypeDelete, userID, nil))
+	return nil
+}
+
+// AddToProject adds a worker to a project (ensuring both belong to the user)
+func (r *workerRepository) AddToProject(ctx context.Context, workerID, projectID, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	assignment := model.NewAssignment(workerID, projectID, userID)
+	return r.store.assign(ctx, &assignment)
+}
+
+// RemoveFromProject removes a worker from a project (ensuring both belong to the user)
+func (r *workerRepository) RemoveFromProject(ctx context.Context, workerID, projectID, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.unassign(ctx, workerID, projectID, userID)
+}
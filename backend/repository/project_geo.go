@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"sort"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/geo"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"gorm.io/gorm"
+)
+
+// nearestStartKm is the radius of the first ring searched for the nearest
+// projects; it grows fourfold until enough projects are found
+const nearestStartKm = 25.0
+
+// GeoQuery selects projects by location
+type GeoQuery struct {
+	// Origin is the point distances are measured from
+	Origin geo.Point
+	// RadiusKm, when positive, keeps the projects within that distance of Origin
+	RadiusKm float64
+	// Box, when set, keeps the projects inside the bounding box
+	Box *geo.BoundingBox
+}
+
+// ProjectDistance is a project with its distance from the origin of a query
+type ProjectDistance struct {
+	model.Project
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// Bounds returns a bounding box holding the main location of every project
+// the query can match, used to prefilter rows on the location index. It
+// returns false when the query matches the whole earth.
+func (q GeoQuery) Bounds() (geo.BoundingBox, bool) {
+	area, ok := q.area()
+	if !ok {
+		return area, false
+	}
+	if area.MinLat > area.MaxLat {
+		return area, true
+	}
+	// A site reaches at most MaxSiteExtentKm past its main location
+	return area.Expand(model.MaxSiteExtentKm), true
+}
+
+// area returns the bounding box of the queried area itself
+func (q GeoQuery) area() (geo.BoundingBox, bool) {
+	if q.RadiusKm <= 0 {
+		if q.Box == nil {
+			return geo.BoundingBox{}, false
+		}
+		return *q.Box, true
+	}
+
+	circle := geo.BoxAround(q.Origin, q.RadiusKm)
+	if q.Box == nil {
+		return circle, true
+	}
+	if circle.CrossesAntimeridian() || q.Box.CrossesAntimeridian() {
+		// The box alone is a correct, if looser, prefilter
+		return *q.Box, true
+	}
+	if box, ok := circle.Intersect(*q.Box); ok {
+		return box, true
+	}
+	// Nothing can match; an empty box keeps the query valid
+	return geo.BoundingBox{MinLng: 0, MinLat: 0, MaxLng: -1, MaxLat: -1}, true
+}
+
+// Matches reports whether any part of a project site (its main location,
+// other locations or boundaries) lies inside the area of the query, and the
+// distance from the origin to the nearest part
+func (q GeoQuery) Matches(project model.Project) (float64, bool) {
+	if q.Box != nil && !project.InBox(*q.Box) {
+		return 0, false
+	}
+	distance := project.DistanceKm(q.Origin)
+	if q.RadiusKm > 0 && distance > q.RadiusKm {
+		return 0, false
+	}
+	return distance, true
+}
+
+// whereInBox restricts a project query to a bounding box
+func whereInBox(db *gorm.DB, box geo.BoundingBox) *gorm.DB {
+	if box.MinLat > box.MaxLat {
+		return db.Where("1 = 0")
+	}
+	db = db.Where("latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat)
+	switch {
+	case box.MinLng <= -180 && box.MaxLng >= 180:
+		return db
+	case box.CrossesAntimeridian():
+		return db.Where("(longitude >= ? OR longitude <= ?)", box.MinLng, box.MaxLng)
+	default:
+		return db.Where("longitude BETWEEN ? AND ?", box.MinLng, box.MaxLng)
+	}
+}
+
+// NearbyPage keeps the candidate projects inside the area of the query, sorts
+// them by distance, ties broken by the sort of the options, and returns the
+// page selected by the options with the total number of matches
+func NearbyPage(candidates []model.Project, q GeoQuery, opts ListOptions) ([]ProjectDistance, PageInfo) {
+	matches := make([]ProjectDistance, 0)
+	for _, project := range candidates {
+		if distance, ok := q.Matches(project); ok {
+			matches = append(matches, ProjectDistance{Project: project, DistanceKm: distance})
+		}
+	}
+	sortByDistance(matches, opts)
+
+	info := PageInfo{Total: int64(len(matches))}
+	if opts.Page <= 0 || opts.PageSize <= 0 {
+		return matches, info
+	}
+	offset := (opts.Page - 1) * opts.PageSize
+	if offset >= len(matches) {
+		return []ProjectDistance{}, info
+	}
+	end := min(offset+opts.PageSize, len(matches))
+	return matches[offset:end], info
+}
+
+// NearestProjects returns the limit candidates nearest to the origin among
+// those within radiusKm of it. It reports false when fewer than limit
+// candidates lie within the radius and a larger radius could find more.
+func NearestProjects(candidates []model.Project, origin geo.Point, radiusKm float64, limit int, opts ListOptions) ([]ProjectDistance, bool) {
+	q := GeoQuery{Origin: origin, RadiusKm: radiusKm}
+	matches := make([]ProjectDistance, 0)
+	for _, project := range candidates {
+		if distance, ok := q.Matches(project); ok {
+			matches = append(matches, ProjectDistance{Project: project, DistanceKm: distance})
+		}
+	}
+	if len(matches) < limit && radiusKm < geo.MaxDistanceKm {
+		return nil, false
+	}
+
+	sortByDistance(matches, opts)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, true
+}
+
+// sortByDistance orders projects nearest first, then by the sort of the options
+func sortByDistance(projects []ProjectDistance, opts ListOptions) {
+	sort.SliceStable(projects, func(i, j int) bool {
+		if projects[i].DistanceKm != projects[j].DistanceKm {
+			return projects[i].DistanceKm < projects[j].DistanceKm
+		}
+		return opts.Query.Less(ProjectRow(projects[i].Project), ProjectRow(projects[j].Project))
+	})
+}
@@ -1,210 +1,544 @@
 package repository
 
 import (
-	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/geo"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
 	"gorm.io/gorm"
 )
 
-type ProjectRepository struct {
+type ProjectRepository interface {
+	Create(ctx context.Context, project *model.Project) error
+	GetByID(ctx context.Context, id uint, userID uint) (*model.Project, error)
+	// GetWithHistory retrieves a project like GetByID, listing its workers
+	// once per assignment, the ended ones included
+	GetWithHistory(ctx context.Context, id uint, userID uint) (*model.Project, error)
+	GetAll(ctx context.Context, userID uint, opts ListOptions) ([]model.Project, PageInfo, error)
+	GetNearby(ctx context.Context, userID uint, area GeoQuery, opts ListOptions) ([]ProjectDistance, PageInfo, error)
+	GetNearest(ctx context.Context, userID uint, origin geo.Point, limit int, opts ListOptions) ([]ProjectDistance, error)
+	// Each calls fn with every project matching the search and filters of
+	// the options, in their sort order, reading them one at a time. With an
+	// area, only the projects inside it are kept, with their distance from
+	// its origin. Paging is ignored and the workers are not loaded.
+	Each(ctx context.Context, userID uint, opts ListOptions, area *GeoQuery, fn func(ProjectDistance) error) error
+	// EachAssignment calls fn with every assignment to a project matching
+	// the options, by project then worker name, reading them one at a time
+	EachAssignment(ctx context.Context, userID uint, opts ListOptions, fn func(Assignment) error) error
+	// GetAvailableWorkers returns a page of the user's workers matching the
+	// options that are not on a project: they have no assignment to it, or
+	// only ended ones
+	GetAvailableWorkers(ctx context.Context, userID uint, available AvailableWorkers, opts ListOptions) ([]model.Worker, PageInfo, error)
+	Update(ctx context.Context, project *model.Project, userID uint) error
+	Patch(ctx context.Context, project *model.Project, fields []string, userID uint) error
+	Delete(ctx context.Context, id uint, userID uint) error
+	// AddWorker assigns a worker to a project, both belonging to the user of
+	// the assignment. Without a start date, the assignment starts after the
+	// earlier assignments of the worker to the project. One sharing a day
+	// with them fails with gorm.ErrDuplicatedKey.
+	AddWorker(ctx context.Context, assignment *model.WorkerProject) error
+	// GetAssignment retrieves the latest assignment of a worker to a project
+	GetAssignment(ctx context.Context, projectID, workerID, userID uint) (*model.WorkerProject, error)
+	// UpdateAssignment replaces the details of the latest assignment of a
+	// worker to a project, placed among the earlier ones like AddWorker does
+	UpdateAssignment(ctx context.Context, assignment *model.WorkerProject) error
+	// GetConflicts returns the conflicts between the assignments of the user's workers
+	GetConflicts(ctx context.Context, userID uint) ([]Conflict, error)
+	// AssignmentConflicts returns the conflicts that making an assignment,
+	// or changing it to the given details, would cause
+	AssignmentConflicts(ctx context.Context, assignment model.WorkerProject) ([]Conflict, error)
+	// RemoveWorker ends the latest assignment of a worker to a project as of
+	// today, keeping it as history
+	RemoveWorker(ctx context.Context, projectID, workerID, userID uint) error
+}
+
+type projectRepository struct {
 	db *gorm.DB
 }
 
-func NewProjectRepository() *ProjectRepository {
-	return &ProjectRepository{
-		db: config.DB,
+func NewProjectRepository(db *gorm.DB) ProjectRepository {
+	return &projectRepository{
+		db: db,
 	}
 }
 
 // Create creates a new project
-func (r *ProjectRepository) Create(project *model.Project) error {
-	return r.db.Create(project).Error
+func (r *projectRepository) Create(ctx context.Context, project *model.Project) error {
+	project.Version = 1
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		return recordProjectChange(ctx, tx, nil, project, model.LogTypeCreate)
+	})
 }
 
 // GetByID retrieves a project by ID and user ID
-func (r *ProjectRepository) GetByID(id uint, userID uint) (*model.Project, error) {
+func (r *projectRepository) GetByID(ctx context.Context, id uint, userID uint) (*model.Project, error) {
 	var project model.Project
-	// Use preload with a custom join query to check both worker's user_id and join table's user_id
-	err := r.db.Preload("Workers", func(db *gorm.DB) *gorm.DB {
-		return db.Joins("JOIN worker_projects ON worker_projects.worker_id = workers.id").
-			Where("workers.user_id = ? AND worker_projects.user_id = ?", userID, userID)
-	}).Where("id = ? AND user_id = ?", id, userID).First(&project).Error
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&project).Error
 	if err != nil {
 		return nil, err
 	}
+	if err := loadWorkers(r.db.WithContext(ctx), userID, false, &project); err != nil {
+		return nil, err
+	}
 	return &project, nil
 }
 
-// GetAll retrieves all projects with optional filtering and sorting for a specific user
-func (r *ProjectRepository) GetAll(userID uint, filters map[string]interface{}, sortBy string, sortOrder string, page int, pageSize int) ([]model.Project, int64, error) {
-	var projects []model.Project
-	var total int64
-	query := r.db.Model(&model.Project{}).Where("user_id = ?", userID)
+// GetWithHistory retrieves a project like GetByID, listing its workers once
+// per assignment, the ended ones included
+func (r *projectRepository) GetWithHistory(ctx context.Context, id uint, userID uint) (*model.Project, error) {
+	var project model.Project
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&project).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := loadWorkers(r.db.WithContext(ctx), userID, true, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
 
-	// Apply filters
-	for key, value := range filters {
-		switch key {
-		case "search":
-			searchTerm := value.(string)
-			query = query.Where(
-				"name LIKE ? OR description LIKE ?",
-				"%"+searchTerm+"%",
-				"%"+searchTerm+"%",
-			)
-		default:
-			query = query.Where(key+" = ?", value)
-		}
+// filtered returns the user's projects matching the search and filters of the options
+func (r *projectRepository) filtered(ctx context.Context, userID uint, opts ListOptions) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.Project{}).Where("user_id = ?", userID)
+
+	// Apply search, ignoring case on every database
+	if opts.Search != "" {
+		query = searchColumns(query, opts.Search, "name", "description")
 	}
 
-	// Count total records (before pagination)
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+	// Apply the whitelisted filters
+	return opts.Query.ApplyFilters(query)
+}
+
+// GetAll retrieves all projects with optional filtering and sorting for a specific user
+func (r *projectRepository) GetAll(ctx context.Context, userID uint, opts ListOptions) ([]model.Project, PageInfo, error) {
+	projects, info, err := findPage(r.filtered(ctx, userID, opts), opts, func(query *gorm.DB) *gorm.DB { return query }, ProjectRow)
+	if err != nil {
+		return nil, info, err
+	}
+	page := make([]*model.Project, len(projects))
+	for i := range projects {
+		page[i] = &projects[i]
+	}
+	if err := loadWorkers(r.db.WithContext(ctx), userID, false, page...); err != nil {
+		return nil, info, err
 	}
+	return projects, info, nil
+}
 
-	// Apply sorting
-	if sortBy != "" {
-		order := sortBy
-		if sortOrder == "desc" {
-			order += " DESC"
-		}
-		query = query.Order(order)
+// GetNearby retrieves the user's projects inside an area, nearest to its
+// origin first. The location index narrows the rows down to the bounding box
+// of the area; exact distances are computed in Go so every database agrees.
+func (r *projectRepository) GetNearby(ctx context.Context, userID uint, area GeoQuery, opts ListOptions) ([]ProjectDistance, PageInfo, error) {
+	query := r.filtered(ctx, userID, opts)
+	if box, ok := area.Bounds(); ok {
+		query = whereInBox(query, box)
 	}
 
-	// Apply pagination
-	if page > 0 && pageSize > 0 {
-		offset := (page - 1) * pageSize
-		query = query.Offset(offset).Limit(pageSize)
+	var candidates []model.Project
+	if err := query.Find(&candidates).Error; err != nil {
+		return nil, PageInfo{}, err
 	}
 
-	// Add user_id condition to the preloaded Workers to ensure we only get workers belonging to the current user
-	// Also ensure the worker_projects join table has the correct user_id
-	err := query.Preload("Workers", func(db *gorm.DB) *gorm.DB {
-		return db.Joins("JOIN worker_projects ON worker_projects.worker_id = workers.id").
-			Where("workers.user_id = ? AND worker_projects.user_id = ?", userID, userID)
-	}).Find(&projects).Error
-	
-	return projects, total, err
+	projects, info := NearbyPage(candidates, area, opts)
+	if err := r.attachWorkers(ctx, userID, projects); err != nil {
+		return nil, PageInfo{}, err
+	}
+	return projects, info, nil
 }
 
-// GetAllWorkers retrieves all workers for a specific user
-func (r *ProjectRepository) GetAllWorkers(userID uint, page int, pageSize int) ([]model.Worker, int64, error) {
-	var workers []model.Worker
-	var total int64
-	query := r.db.Model(&model.Worker{}).Where("user_id = ?", userID)
+// Each calls fn with every matching project, one row at a time
+func (r *projectRepository) Each(ctx context.Context, userID uint, opts ListOptions, area *GeoQuery, fn func(ProjectDistance) error) error {
+	query := r.filtered(ctx, userID, opts)
+	if area == nil {
+		return eachRow(sortAll(query, opts), func(project model.Project) error {
+			return fn(ProjectDistance{Project: project})
+		})
+	}
 
-	// Count total records (before pagination)
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+	if box, ok := area.Bounds(); ok {
+		query = whereInBox(query, box)
 	}
+	return eachRow(sortAll(query, opts), func(project model.Project) error {
+		if distance, ok := area.Matches(project); ok {
+			return fn(ProjectDistance{Project: project, DistanceKm: distance})
+		}
+		return nil
+	})
+}
 
-	// Apply pagination
-	if page > 0 && pageSize > 0 {
-		offset := (page - 1) * pageSize
-		query = query.Offset(offset).Limit(pageSize)
+// GetNearest retrieves the user's limit projects nearest to the origin. It
+// searches rings of growing radius so that only nearby rows are read.
+func (r *projectRepository) GetNearest(ctx context.Context, userID uint, origin geo.Point, limit int, opts ListOptions) ([]ProjectDistance, error) {
+	for radius := nearestStartKm; ; radius *= 4 {
+		radius = min(radius, geo.MaxDistanceKm)
+
+		var candidates []model.Project
+		box, _ := GeoQuery{Origin: origin, RadiusKm: radius}.Bounds()
+		query := whereInBox(r.filtered(ctx, userID, opts), box)
+		if err := query.Find(&candidates).Error; err != nil {
+			return nil, err
+		}
+
+		if projects, ok := NearestProjects(candidates, origin, radius, limit, opts); ok {
+			if err := r.attachWorkers(ctx, userID, projects); err != nil {
+				return nil, err
+			}
+			return projects, nil
+		}
 	}
+}
 
-	err := query.Preload("Projects", "user_id = ?", userID).Find(&workers).Error
-	return workers, total, err
+// attachWorkers loads the workers of a page of projects
+func (r *projectRepository) attachWorkers(ctx context.Context, userID uint, projects []ProjectDistance) error {
+	page := make([]*model.Project, len(projects))
+	for i := range projects {
+		page[i] = &projects[i].Project
+	}
+	return loadWorkers(r.db.WithContext(ctx), userID, false, page...)
 }
 
-// Update updates a project
-func (r *ProjectRepository) Update(project *model.Project, userID uint) error {
-	// First check if this project belongs to the user
-	result := r.db.Where("id = ? AND user_id = ?", project.ID, userID).First(&model.Project{})
-	if result.Error != nil {
-		return result.Error
+// loadWorkers sets the workers of projects, each with its assignment. A
+// worker is listed once, with the first of their assignments that have not
+// ended; withEnded lists a worker once per assignment instead, ended ones
+// included. Workers in the trash are left out.
+func loadWorkers(db *gorm.DB, userID uint, withEnded bool, projects ...*model.Project) error {
+	byID := make(map[uint]*model.Project, len(projects))
+	ids := make([]uint, 0, len(projects))
+	for _, project := range projects {
+		project.Workers = []model.Worker{}
+		byID[project.ID] = project
+		ids = append(ids, project.ID)
+	}
+	if len(ids) == 0 {
+		return nil
 	}
 
-	// Create a transaction to handle the update
-	tx := r.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
+	query := db.Joins("JOIN workers ON workers.id = worker_projects.worker_id AND workers.deleted_at IS NULL").
+		Where("worker_projects.project_id IN ? AND worker_projects.user_id = ? AND workers.user_id = ?", ids, userID, userID)
+	if !withEnded {
+		query = currentAssignments(query)
+	}
+	var assignments []model.WorkerProject
+	if err := query.Order("worker_projects.id").Find(&assignments).Error; err != nil {
+		return err
+	}
+	if len(assignments) == 0 {
+		return nil
 	}
 
-	// Defer a function to handle transaction completion
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	workerIDs := make([]uint, 0, len(assignments))
+	for _, assignment := range assignments {
+		workerIDs = append(workerIDs, assignment.WorkerID)
+	}
+	var workers []model.Worker
+	if err := db.Where("id IN ?", workerIDs).Find(&workers).Error; err != nil {
+		return err
+	}
+	workersByID := make(map[uint]model.Worker, len(workers))
+	for _, worker := range workers {
+		workersByID[worker.ID] = worker
+	}
+
+	for i := range assignments {
+		assignment := &assignments[i]
+		project := byID[assignment.ProjectID]
+		worker := workersByID[assignment.WorkerID]
+		worker.Assignment = assignment
+		if !withEnded {
+			listed := slices.IndexFunc(project.Workers, func(w model.Worker) bool { return w.ID == worker.ID })
+			if listed >= 0 {
+				start, _ := assignment.Period(*project)
+				listedStart, _ := project.Workers[listed].Assignment.Period(*project)
+				if start.Before(listedStart) {
+					project.Workers[listed] = worker
+				}
+				continue
+			}
 		}
-	}()
+		project.Workers = append(project.Workers, worker)
+	}
+	for _, project := range projects {
+		sort.SliceStable(project.Workers, func(i, j int) bool { return project.Workers[i].ID < project.Workers[j].ID })
+	}
+	return nil
+}
 
-	// First, update the project attributes without touching associations
-	if err := tx.Model(project).Omit("Workers").Updates(project).Error; err != nil {
-		tx.Rollback()
-		return err
+// GetAvailableWorkers selects the workers matching the options that have no
+// assignment to the project, ended ones aside, and with ExcludeBusy none to
+// an overlapping active project either
+func (r *projectRepository) GetAvailableWorkers(ctx context.Context, userID uint, available AvailableWorkers, opts ListOptions) ([]model.Worker, PageInfo, error) {
+	var project model.Project
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", available.ProjectID, userID).First(&project).Error; err != nil {
+		return nil, PageInfo{}, err
 	}
 
-	// If there are workers to update, handle that separately
-	// This approach avoids the automatic M2M association handling that would cause the null user_id issue
-	if len(project.Workers) > 0 {
-		// Clear existing associations
-		if err := tx.Where("project_id = ?", project.ID).Delete(&model.WorkerProject{}).Error; err != nil {
-			tx.Rollback()
+	assigned := currentAssignments(r.db.Table("worker_projects").Select("1").
+		Where("worker_projects.worker_id = workers.id AND worker_projects.project_id = ? AND worker_projects.user_id = ?", project.ID, userID))
+	query := filterWorkers(r.db.WithContext(ctx), userID, opts).Where("NOT EXISTS (?)", assigned)
+
+	if available.ExcludeBusy {
+		busy := r.db.Table("worker_projects").Select("1").
+			Joins("JOIN projects ON projects.id = worker_projects.project_id").
+			Where("worker_projects.worker_id = workers.id AND worker_projects.user_id = ?", userID).
+			Where("projects.id <> ? AND projects.status = ? AND projects.deleted_at IS NULL", project.ID, model.ProjectStatusActive).
+			Where("COALESCE(worker_projects.end_date, projects.end_date) IS NULL OR COALESCE(worker_projects.end_date, projects.end_date) >= ?", project.StartDate)
+		if project.EndDate != nil {
+			busy = busy.Where("COALESCE(worker_projects.start_date, projects.start_date) <= ?", *project.EndDate)
+		}
+		query = query.Where("NOT EXISTS (?)", busy)
+	}
+
+	workers, info, err := findPage(query, opts, func(query *gorm.DB) *gorm.DB { return query }, WorkerRow)
+	if err != nil {
+		return nil, info, err
+	}
+	page := make([]*model.Worker, len(workers))
+	for i := range workers {
+		page[i] = &workers[i]
+	}
+	if err := loadProjects(r.db.WithContext(ctx), userID, page...); err != nil {
+		return nil, info, err
+	}
+	return workers, info, nil
+}
+
+// Update updates the non-zero fields of a project. When the project carries
+// a version, the update only applies to that version of the record and fails
+// with ErrVersionConflict otherwise.
+func (r *projectRepository) Update(ctx context.Context, project *model.Project, userID uint) error {
+	// Run the update in a transaction (a savepoint when already inside a unit of work)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// First check if this project belongs to the user
+		var current model.Project
+		if err := tx.Where("id = ? AND user_id = ?", project.ID, userID).First(&current).Error; err != nil {
 			return err
 		}
 
-		// Re-add worker associations with the correct user_id
-		for _, worker := range project.Workers {
-			workerProject := &model.WorkerProject{
-				WorkerID:  worker.ID,
-				ProjectID: project.ID,
-				UserID:    userID,
-			}
-			if err := tx.Create(workerProject).Error; err != nil {
-				tx.Rollback()
+		version, err := NextVersion(current.Version, project.Version)
+		if err != nil {
+			return err
+		}
+		project.Version = version
+
+		// Update the project attributes without touching associations, unless
+		// another write got in first
+		result := tx.Model(project).Where("version = ?", current.Version).Omit("Workers").Updates(project)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		// Only the non-zero fields were written, so read back the result
+		var updated model.Project
+		if err := tx.First(&updated, project.ID).Error; err != nil {
+			return err
+		}
+		if err := recordProjectChange(ctx, tx, &current, &updated, model.LogTypeUpdate); err != nil {
+			return err
+		}
+
+		// If there are workers to update, handle that separately
+		// This approach avoids the automatic M2M association handling that would cause the null user_id issue
+		if len(project.Workers) > 0 {
+			var assigned []uint
+			if err := currentAssignments(tx.Model(&model.WorkerProject{}).Where("project_id = ?", project.ID)).
+				Distinct().Pluck("worker_id", &assigned).Error; err != nil {
 				return err
 			}
+
+			// Keep the assignments of the workers staying on the project, with
+			// their details; only the others are added or removed
+			kept := make(map[uint]bool, len(assigned))
+			for _, workerID := range assigned {
+				kept[workerID] = false
+			}
+			for _, worker := range project.Workers {
+				if _, ok := kept[worker.ID]; ok {
+					kept[worker.ID] = true
+					continue
+				}
+				var added model.Worker
+				if err := tx.Where("id = ? AND user_id = ?", worker.ID, userID).First(&added).Error; err != nil {
+					return err
+				}
+				assignment := model.NewAssignment(worker.ID, project.ID, userID)
+				if err := assign(ctx, tx, added, updated, &assignment); err != nil {
+					return err
+				}
+			}
+			for _, workerID := range assigned {
+				if kept[workerID] {
+					continue
+				}
+				var current []model.WorkerProject
+				if err := currentAssignments(tx.Where("project_id = ? AND worker_id = ?", project.ID, workerID)).
+					Find(&current).Error; err != nil {
+					return err
+				}
+				for i := range current {
+					if err := endAssignment(tx, &current[i], updated); err != nil {
+						return err
+					}
+				}
+				if err := recordAssignment(ctx, tx, workerID, project.ID, model.LogTypeUnassign, userID); err != nil {
+					return err
+				}
+			}
 		}
-	}
-	
-	return tx.Commit().Error
+
+		return nil
+	})
+}
+
+// Patch writes the given columns of a project, zero values included, with
+// the version check of Update. The worker assignments are left untouched.
+func (r *projectRepository) Patch(ctx context.Context, project *model.Project, fields []string, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// First check if this project belongs to the user
+		var current model.Project
+		if err := tx.Where("id = ? AND user_id = ?", project.ID, userID).First(&current).Error; err != nil {
+			return err
+		}
+
+		version, err := NextVersion(current.Version, project.Version)
+		if err != nil {
+			return err
+		}
+		project.Version = version
+
+		columns := append([]string{"version", "updated_at"}, fields...)
+		result := tx.Model(project).Where("version = ?", current.Version).Select(columns).Updates(project)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return recordProjectChange(ctx, tx, &current, project, model.LogTypeUpdate)
+	})
 }
 
 // Delete deletes a project
-func (r *ProjectRepository) Delete(id uint, userID uint) error {
-	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Project{}).Error
+func (r *projectRepository) Delete(ctx context.Context, id uint, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Project{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return recordChanges(tx, NewChange(ctx, model.EntityTypeProject, id, model.LogTypeDelete, userID, nil))
+	})
 }
 
 // AddWorker adds a worker to a project (ensuring both belong to the user)
-func (r *ProjectRepository) AddWorker(projectID, workerID, userID uint) error {
+func (r *projectRepository) AddWorker(ctx context.Context, assignment *model.WorkerProject) error {
 	// Verify project belongs to user
 	project := &model.Project{}
-	if err := r.db.Where("id = ? AND user_id = ?", projectID, userID).First(project).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", assignment.ProjectID, assignment.UserID).First(project).Error; err != nil {
 		return err
 	}
 	
 	// Verify worker belongs to user
 	worker := &model.Worker{}
-	if err := r.db.Where("id = ? AND user_id = ?", workerID, userID).First(worker).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", assignment.WorkerID, assignment.UserID).First(worker).Error; err != nil {
 		return err
 	}
 	
-	// Create the join record with user_id
-	workerProject := &model.WorkerProject{
-		WorkerID:  workerID,
-		ProjectID: projectID,
-		UserID:    userID,
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return assign(ctx, tx, *worker, *project, assignment)
+	})
+}
+
+// GetAssignment retrieves the latest assignment of a worker to a project of the user
+func (r *projectRepository) GetAssignment(ctx context.Context, projectID, workerID, userID uint) (*model.WorkerProject, error) {
+	assignments, err := assignmentsOf(r.db.WithContext(ctx), projectID, workerID, userID)
+	if err != nil {
+		return nil, err
 	}
-	
-	// Use the custom join table to create the relationship
-	return r.db.Create(workerProject).Error
+	if len(assignments) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &assignments[len(assignments)-1], nil
+}
+
+// UpdateAssignment writes every detail of the latest assignment of a worker
+// to a project, clearing the missing ones
+func (r *projectRepository) UpdateAssignment(ctx context.Context, assignment *model.WorkerProject) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		assignments, err := assignmentsOf(tx, assignment.ProjectID, assignment.WorkerID, assignment.UserID)
+		if err != nil {
+			return err
+		}
+		if len(assignments) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		current := assignments[len(assignments)-1]
+		assignment.ID, assignment.CreatedAt = current.ID, current.CreatedAt
+
+		var project model.Project
+		if err := tx.First(&project, assignment.ProjectID).Error; err != nil {
+			return err
+		}
+		if err := FitAssignment(assignment, project, assignments); err != nil {
+			return err
+		}
+		if err := tx.Model(assignment).
+			Select("role", "start_date", "end_date", "allocation_percent", "day_rate", "notes", "updated_at").
+			Updates(assignment).Error; err != nil {
+			return err
+		}
+
+		var worker model.Worker
+		if err := tx.Select("id, name").First(&worker, assignment.WorkerID).Error; err != nil {
+			return err
+		}
+		changes, err := AssignmentUpdateChanges(ctx, worker, project, current.AssignmentDetails, assignment.AssignmentDetails, assignment.UserID)
+		if err != nil {
+			return err
+		}
+		return recordChanges(tx, changes...)
+	})
 }
 
 // RemoveWorker removes a worker from a project (ensuring both belong to the user)
-func (r *ProjectRepository) RemoveWorker(projectID, workerID, userID uint) error {
+func (r *projectRepository) RemoveWorker(ctx context.Context, projectID, workerID, userID uint) error {
 	// Verify project belongs to user
 	project := &model.Project{}
-	if err := r.db.Where("id = ? AND user_id = ?", projectID, userID).First(project).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", projectID, userID).First(project).Error; err != nil {
 		return err
 	}
 	
 	// Verify worker belongs to user
 	worker := &model.Worker{}
-	if err := r.db.Where("id = ? AND user_id = ?", workerID, userID).First(worker).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", workerID, userID).First(worker).Error; err != nil {
 		return err
 	}
 	
-	// Delete the join record that has the appropriate worker_id, project_id AND user_id
-	return r.db.Where("worker_id = ? AND project_id = ? AND user_id = ?", 
-		workerID, projectID, userID).Delete(&model.WorkerProject{}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return unassign(ctx, tx, *worker, *project, userID)
+	})
+}
+
+// recordProjectChange stores the history entry of a project write; updates
+// that change no recorded field leave no entry
+func recordProjectChange(ctx context.Context, tx *gorm.DB, before, after *model.Project, action model.LogType) error {
+	var old interface{}
+	if before != nil {
+		old = before
+	}
+	changes, err := DiffFields(old, after, ProjectHistoryFields)
+	if err != nil {
+		return err
+	}
+	if action == model.LogTypeUpdate && len(changes) == 0 {
+		return nil
+	}
+	return recordChanges(tx, NewChange(ctx, model.EntityTypeProject, after.ID, action, after.UserID, changes))
 } 
\ No newline at end of file
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/search"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SearchHit is a worker or project matching a search query
+type SearchHit struct {
+	Type  model.EntityType `json:"type"`
+	ID    uint             `json:"id"`
+	Title string           `json:"title"`
+	// Subtitle is the position of a worker or the status of a project
+	Subtitle string `json:"subtitle"`
+	// Rank orders the hits of one search; it is not comparable across searches
+	Rank float64 `json:"rank"`
+	// Highlights holds the matching fields with the matches wrapped in
+	// <mark> tags; the rest of the text is HTML-escaped
+	Highlights map[string]string `json:"highlights"`
+}
+
+// SearchRepository runs full-text searches across workers and projects
+type SearchRepository interface {
+	Search(ctx context.Context, userID uint, query string, types []model.EntityType, limit int) ([]SearchHit, error)
+}
+
+type searchRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchRepository creates a new SearchRepository instance
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	return &searchRepository{
+		db: db,
+	}
+}
+
+// workerSearchRow and projectSearchRow are the columns read by a search
+type workerSearchRow struct {
+	ID       uint
+	Name     string
+	Position string
+	Rank     float64
+}
+
+type projectSearchRow struct {
+	ID          uint
+	Name        string
+	Description string
+	Status      string
+	Rank        float64
+}
+
+// Search returns the user's workers and projects matching every term of the
+// query, best match first. On Postgres the search_vector columns and their
+// GIN indexes are used; other databases fall back to LIKE and rank in Go.
+func (r *searchRepository) Search(ctx context.Context, userID uint, query string, types []model.EntityType, limit int) ([]SearchHit, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []SearchHit{}, nil
+	}
+	postgres := r.db.Dialector.Name() == "postgres"
+
+	hits := make([]SearchHit, 0)
+	if includesType(types, model.EntityTypeWorker) {
+		var rows []workerSearchRow
+		db := r.db.WithContext(ctx).Model(&model.Worker{}).Where("user_id = ?", userID)
+		if postgres {
+			db = rankedSearch(db, "id, name, position", terms, limit)
+		} else {
+			db = likeSearch(db.Select("id, name, position"), terms, limit, "name", "position")
+		}
+		if err := db.Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			// Postgres already matched and ranked the row
+			rank := row.Rank
+			if !postgres {
+				var ok bool
+				rank, ok = search.Score(terms, search.Field{Text: row.Name, Weight: search.WeightTitle}, search.Field{Text: row.Position, Weight: search.WeightBody})
+				if !ok {
+					continue
+				}
+			}
+			hits = append(hits, SearchHit{
+				Type:     model.EntityTypeWorker,
+				ID:       row.ID,
+				Title:    row.Name,
+				Subtitle: row.Position,
+				Rank:     rank,
+				Highlights: search.HighlightFields(terms, map[string]string{
+					"name":     row.Name,
+					"position": row.Position,
+				}),
+			})
+		}
+	}
+
+	if includesType(types, model.EntityTypeProject) {
+		var rows []projectSearchRow
+		db := r.db.WithContext(ctx).Model(&model.Project{}).Where("user_id = ?", userID)
+		if postgres {
+			db = rankedSearch(db, "id, name, description, status", terms, limit)
+		} else {
+			db = likeSearch(db.Select("id, name, description, status"), terms, limit, "name", "description")
+		}
+		if err := db.Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			// Postgres already matched and ranked the row
+			rank := row.Rank
+			if !postgres {
+				var ok bool
+				rank, ok = search.Score(terms, search.Field{Text: row.Name, Weight: search.WeightTitle}, search.Field{Text: row.Description, Weight: search.WeightBody})
+				if !ok {
+					continue
+				}
+			}
+			hits = append(hits, SearchHit{
+				Type:     model.EntityTypeProject,
+				ID:       row.ID,
+				Title:    row.Name,
+				Subtitle: row.Status,
+				Rank:     rank,
+				Highlights: search.HighlightFields(terms, map[string]string{
+					"name":        row.Name,
+					"description": row.Description,
+				}),
+			})
+		}
+	}
+
+	return SortHits(hits, limit), nil
+}
+
+// rankedSearch matches the search_vector column against the terms as word
+// prefixes and orders by cover density rank
+func rankedSearch(db *gorm.DB, columns string, terms []string, limit int) *gorm.DB {
+	tsquery := search.TSQuery(terms)
+	return db.
+		Select(columns+", ts_rank_cd(search_vector, to_tsquery('simple', ?)) AS rank", tsquery).
+		Where("search_vector @@ to_tsquery('simple', ?)", tsquery).
+		Order("rank DESC, id").
+		Limit(limit)
+}
+
+// likeCandidatesPerHit is how many rows likeSearch reads per hit requested,
+// leaving room for the rows search.Score turns down
+const likeCandidatesPerHit = 10
+
+// likeSearch requires every term in one of the columns. It matches more
+// than word prefixes; the caller filters the rows with search.Score. Rows
+// with every term in the first column, the title, are read first, and no
+// more than likeCandidatesPerHit rows per hit are read.
+func likeSearch(db *gorm.DB, terms []string, limit int, columns ...string) *gorm.DB {
+	titleMatches := make([]string, len(terms))
+	titleArgs := make([]interface{}, len(terms))
+	for i, term := range terms {
+		db = searchColumns(db, term, columns...)
+		title, args := likeCondition(term, columns[0])
+		titleMatches[i], titleArgs[i] = title, args[0]
+	}
+
+	titleFirst := clause.Expr{SQL: "CASE WHEN " + strings.Join(titleMatches, " AND ") + " THEN 0 ELSE 1 END", Vars: titleArgs}
+	return db.Order(clause.OrderBy{Expression: titleFirst}).Order("id").Limit(limit * likeCandidatesPerHit)
+}
+
+// includesType reports whether an entity type is searched; no types means all
+func includesType(types []model.EntityType, entityType model.EntityType) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == entityType {
+			return true
+		}
+	}
+	return false
+}
+
+// SortHits orders hits by rank, then type and ID, and keeps the first limit
+func SortHits(hits []SearchHit, limit int) []SearchHit {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		if hits[i].Type != hits[j].Type {
+			return hits[i].Type > hits[j].Type
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
@@ -0,0 +1,542 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/query"
+	"gorm.io/gorm"
+)
+
+// ErrNotAssigned is returned when a timesheet entry falls on a day its
+// worker is not assigned to its project
+var ErrNotAssigned = errors.New("worker is not assigned to the project on that date")
+
+// ErrLocked is returned when changing or deleting a timesheet entry that is
+// submitted or approved
+var ErrLocked = errors.New("timesheet entry is locked")
+
+// ErrPeriodLocked is returned when writing a timesheet entry in a week the
+// worker already has an approved entry for on the project
+var ErrPeriodLocked = errors.New("timesheet week is approved")
+
+// Groupings of timesheet summaries
+const (
+	SummaryByWorker  = "worker"
+	SummaryByProject = "project"
+)
+
+// Periods of timesheet summaries
+const (
+	SummaryWeek  = "week"
+	SummaryMonth = "month"
+)
+
+// TimesheetFields is the whitelist of timesheet fields usable in filters and sorts
+var TimesheetFields = query.Schema{
+	"id":             {Column: "id", Type: query.Int, Filterable: true, Sortable: true},
+	"worker_id":      {Column: "worker_id", Type: query.Int, Filterable: true, Sortable: true},
+	"project_id":     {Column: "project_id", Type: query.Int, Filterable: true, Sortable: true},
+	"date":           {Column: "date", Type: query.Time, Filterable: true, Sortable: true},
+	"hours":          {Column: "hours", Type: query.Float, Filterable: true, Sortable: true},
+	"overtime_hours": {Column: "overtime_hours", Type: query.Float, Filterable: true, Sortable: true},
+	"break_minutes":  {Column: "break_minutes", Type: query.Int, Filterable: true, Sortable: true},
+	"status":         {Column: "status", Type: query.String, Filterable: true, Sortable: true},
+	"submitted_at":   {Column: "submitted_at", Type: query.Time, Nullable: true, Filterable: true, Sortable: true},
+	"created_at":     {Column: "created_at", Type: query.Time, Filterable: true, Sortable: true},
+	"updated_at":     {Column: "updated_at", Type: query.Time, Filterable: true, Sortable: true},
+}
+
+// TimesheetRow exposes the columns of a timesheet entry to the query package
+func TimesheetRow(entry model.Timesheet) query.Row {
+	return func(column string) (interface{}, bool) {
+		switch column {
+		case "id":
+			return entry.ID, true
+		case "worker_id":
+			return entry.WorkerID, true
+		case "project_id":
+			return entry.ProjectID, true
+		case "date":
+			return entry.Date, true
+		case "hours":
+			return entry.Hours, true
+		case "overtime_hours":
+			return entry.OvertimeHours, true
+		case "break_minutes":
+			return entry.BreakMinutes, true
+		case "status":
+			return entry.Status, true
+		case "submitted_at":
+			return entry.SubmittedAt, true
+		case "user_id":
+			return entry.UserID, true
+		case "created_at":
+			return entry.CreatedAt, true
+		case "updated_at":
+			return entry.UpdatedAt, true
+		}
+		return nil, false
+	}
+}
+
+// TimesheetSummaryQuery selects the timesheet entries a summary adds up
+type TimesheetSummaryQuery struct {
+	// GroupBy is SummaryByWorker or SummaryByProject
+	GroupBy string
+	// Period is SummaryWeek or SummaryMonth; weeks start on Monday
+	Period string
+	// WorkerID, ProjectID and Status restrict the entries when set
+	WorkerID  uint
+	ProjectID uint
+	Status    string
+	// From and To bound the dates of the entries when set
+	From *time.Time
+	To   *time.Time
+}
+
+// TimesheetHours is the time of a timesheet entry together with the names of
+// its worker and project
+type TimesheetHours struct {
+	WorkerID      uint      `json:"worker_id"`
+	WorkerName    string    `json:"worker_name"`
+	ProjectID     uint      `json:"project_id"`
+	ProjectName   string    `json:"project_name"`
+	Date          time.Time `json:"date"`
+	Hours         float64   `json:"hours"`
+	OvertimeHours float64   `json:"overtime_hours"`
+	BreakMinutes  int       `json:"break_minutes"`
+}
+
+// TimesheetSummary is the time a worker, or all workers of a project, spent
+// over a week or a month
+type TimesheetSummary struct {
+	WorkerID    uint      `json:"worker_id,omitempty"`
+	WorkerName  string    `json:"worker_name,omitempty"`
+	ProjectID   uint      `json:"project_id,omitempty"`
+	ProjectName string    `json:"project_name,omitempty"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	// Days counts the distinct days worked
+	Days          int     `json:"days"`
+	Hours         float64 `json:"hours"`
+	OvertimeHours float64 `json:"overtime_hours"`
+	TotalHours    float64 `json:"total_hours"`
+	BreakMinutes  int     `json:"break_minutes"`
+}
+
+// TimesheetApproval is a submitted timesheet entry awaiting review, with the
+// user who submitted it, their manager and the names of its worker and project
+type TimesheetApproval struct {
+	model.Timesheet
+	Username    string `json:"username"`
+	ManagerID   *uint  `json:"manager_id"`
+	WorkerName  string `json:"worker_name"`
+	ProjectName string `json:"project_name"`
+}
+
+// TimesheetApprovalRow exposes the columns of an approval to the query package
+func TimesheetApprovalRow(approval TimesheetApproval) query.Row {
+	return TimesheetRow(approval.Timesheet)
+}
+
+type TimesheetRepository interface {
+	// Create adds a draft entry, failing with ErrNotAssigned when the worker
+	// is not assigned to the project on its date, with gorm.ErrDuplicatedKey
+	// when the worker already has an entry for the project and date and with
+	// ErrPeriodLocked when the week of the date is approved
+	Create(ctx context.Context, entry *model.Timesheet) error
+	GetByID(ctx context.Context, id uint, userID uint) (*model.Timesheet, error)
+	GetAll(ctx context.Context, userID uint, opts ListOptions) ([]model.Timesheet, PageInfo, error)
+	// Update replaces the time of an entry, keeping its status, with the
+	// checks of Create. Entries that are not editable fail with ErrLocked,
+	// and those moving in or out of an approved week with ErrPeriodLocked.
+	Update(ctx context.Context, entry *model.Timesheet) error
+	// Save creates the entry of the worker for the project and date, or
+	// replaces the time of the existing one, and reports whether it was
+	// created. Only new entries are held back by an approved week.
+	Save(ctx context.Context, entry *model.Timesheet) (bool, error)
+	// Delete deletes an entry, failing with ErrLocked unless it is editable
+	Delete(ctx context.Context, id uint, userID uint) error
+	// Summarize adds up the hours of the matching entries of the user's
+	// workers and projects by worker or project and period
+	Summarize(ctx context.Context, userID uint, summary TimesheetSummaryQuery) ([]TimesheetSummary, error)
+	// ListApprovals lists the submitted entries of the users reporting to a
+	// manager, or of every user when managerID is 0
+	ListApprovals(ctx context.Context, managerID uint, opts ListOptions) ([]TimesheetApproval, PageInfo, error)
+	// GetForReview retrieves an entry of a user reporting to a manager, or of
+	// any user when managerID is 0
+	GetForReview(ctx context.Context, id uint, managerID uint) (*model.Timesheet, error)
+	// Transition writes the status and review fields of an entry, failing
+	// with ErrVersionConflict when its status is no longer from
+	Transition(ctx context.Context, entry *model.Timesheet, from string) error
+}
+
+type timesheetRepository struct {
+	db *gorm.DB
+}
+
+// NewTimesheetRepository creates a new TimesheetRepository
+func NewTimesheetRepository(db *gorm.DB) TimesheetRepository {
+	return &timesheetRepository{
+		db: db,
+	}
+}
+
+// checkAssigned verifies that the worker and project of an entry belong to
+// its user, and that one of the assignments of the worker to the project
+// covers its date
+func checkAssigned(ctx context.Context, tx *gorm.DB, entry *model.Timesheet) error {
+	var project model.Project
+	if err := tx.Where("id = ? AND user_id = ?", entry.ProjectID, entry.UserID).First(&project).Error; err != nil {
+		return err
+	}
+	var worker model.Worker
+	if err := tx.Where("id = ? AND user_id = ?", entry.WorkerID, entry.UserID).First(&worker).Error; err != nil {
+		return err
+	}
+
+	assignments, err := assignmentsOf(tx, entry.ProjectID, entry.WorkerID, entry.UserID)
+	if err != nil {
+		return err
+	}
+	for _, assignment := range assignments {
+		if assignment.Covers(project, entry.Date) {
+			return nil
+		}
+	}
+	return ErrNotAssigned
+}
+
+// checkUnlocked verifies that the week of an entry is not approved, i.e. that
+// the worker has no approved entry for the project that week. Nothing in an
+// approved week is written: no entry is added, changed or deleted.
+func checkUnlocked(tx *gorm.DB, entry *model.Timesheet) error {
+	start, end := periodOf(entry.Date, SummaryWeek)
+	var approved int64
+	err := tx.Model(&model.Timesheet{}).
+		Where("worker_id = ? AND project_id = ? AND user_id = ? AND status = ? AND date >= ? AND date <= ?",
+			entry.WorkerID, entry.ProjectID, entry.UserID, model.TimesheetApproved, start, end).
+		Count(&approved).Error
+	if err != nil {
+		return err
+	}
+	if approved > 0 {
+		return ErrPeriodLocked
+	}
+	return nil
+}
+
+// keepReview carries the status and review fields of the stored entry over
+// to the entry replacing it
+func keepReview(entry *model.Timesheet, current model.Timesheet) {
+	entry.Status = current.Status
+	entry.SubmittedAt = current.SubmittedAt
+	entry.ReviewedBy = current.ReviewedBy
+	entry.ReviewedAt = current.ReviewedAt
+	entry.ReviewComment = current.ReviewComment
+	entry.CreatedAt = current.CreatedAt
+}
+
+// newDraft clears the status and review fields of a new entry
+func newDraft(entry *model.Timesheet) {
+	keepReview(entry, model.Timesheet{Status: model.TimesheetDraft, CreatedAt: entry.CreatedAt})
+}
+
+// findDay looks up the entry of the worker for the project and date of an
+// entry, other than the entry itself
+func findDay(tx *gorm.DB, entry *model.Timesheet) (*model.Timesheet, error) {
+	var existing model.Timesheet
+	err := tx.Where("worker_id = ? AND project_id = ? AND date = ? AND user_id = ? AND id <> ?",
+		entry.WorkerID, entry.ProjectID, entry.Date, entry.UserID, entry.ID).First(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// Create creates a new timesheet entry
+func (r *timesheetRepository) Create(ctx context.Context, entry *model.Timesheet) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkAssigned(ctx, tx, entry); err != nil {
+			return err
+		}
+		if _, err := findDay(tx, entry); err == nil {
+			return gorm.ErrDuplicatedKey
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := checkUnlocked(tx, entry); err != nil {
+			return err
+		}
+		newDraft(entry)
+		return tx.Create(entry).Error
+	})
+}
+
+// GetByID retrieves a timesheet entry by ID and user ID
+func (r *timesheetRepository) GetByID(ctx context.Context, id uint, userID uint) (*model.Timesheet, error) {
+	var entry model.Timesheet
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetAll retrieves the user's timesheet entries with optional filtering and
+// sorting; the search term is matched against the notes
+func (r *timesheetRepository) GetAll(ctx context.Context, userID uint, opts ListOptions) ([]model.Timesheet, PageInfo, error) {
+	db := r.db.WithContext(ctx).Model(&model.Timesheet{}).Where("user_id = ?", userID)
+	if opts.Search != "" {
+		db = searchColumns(db, opts.Search, "notes")
+	}
+	return findPage(opts.Query.ApplyFilters(db), opts, func(query *gorm.DB) *gorm.DB {
+		return query
+	}, TimesheetRow)
+}
+
+// Update replaces the fields of a timesheet entry
+func (r *timesheetRepository) Update(ctx context.Context, entry *model.Timesheet) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current model.Timesheet
+		if err := tx.Where("id = ? AND user_id = ?", entry.ID, entry.UserID).First(&current).Error; err != nil {
+			return err
+		}
+		if !current.Editable() {
+			return ErrLocked
+		}
+		if err := checkAssigned(ctx, tx, entry); err != nil {
+			return err
+		}
+		if _, err := findDay(tx, entry); err == nil {
+			return gorm.ErrDuplicatedKey
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// Neither the week the entry is in nor the one it moves to may be approved
+		if err := checkUnlocked(tx, &current); err != nil {
+			return err
+		}
+		if err := checkUnlocked(tx, entry); err != nil {
+			return err
+		}
+
+		keepReview(entry, current)
+		return tx.Model(entry).Select("*").Omit("created_at").Updates(entry).Error
+	})
+}
+
+// Save creates or replaces the entry of a worker for a project and date
+func (r *timesheetRepository) Save(ctx context.Context, entry *model.Timesheet) (bool, error) {
+	created := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkAssigned(ctx, tx, entry); err != nil {
+			return err
+		}
+
+		entry.ID = 0
+		existing, err := findDay(tx, entry)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if existing != nil && !existing.Editable() {
+			return ErrLocked
+		}
+		if err := checkUnlocked(tx, entry); err != nil {
+			return err
+		}
+		if existing == nil {
+			created = true
+			newDraft(entry)
+			return tx.Create(entry).Error
+		}
+		entry.ID = existing.ID
+		keepReview(entry, *existing)
+		return tx.Model(entry).Select("*").Omit("created_at").Updates(entry).Error
+	})
+	return created, err
+}
+
+// Delete deletes a timesheet entry
+func (r *timesheetRepository) Delete(ctx context.Context, id uint, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var entry model.Timesheet
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&entry).Error; err != nil {
+			return err
+		}
+		if !entry.Editable() {
+			return ErrLocked
+		}
+		if err := checkUnlocked(tx, &entry); err != nil {
+			return err
+		}
+		return tx.Delete(&entry).Error
+	})
+}
+
+// Summarize adds up the hours of the matching entries, leaving out those of
+// trashed workers and projects
+func (r *timesheetRepository) Summarize(ctx context.Context, userID uint, summary TimesheetSummaryQuery) ([]TimesheetSummary, error) {
+	query := r.db.WithContext(ctx).Table("timesheets").
+		Select(`timesheets.worker_id, workers.name AS worker_name, timesheets.project_id, projects.name AS project_name,
+			timesheets.date, timesheets.hours, timesheets.overtime_hours, timesheets.break_minutes`).
+		Joins("JOIN workers ON workers.id = timesheets.worker_id AND workers.deleted_at IS NULL").
+		Joins("JOIN projects ON projects.id = timesheets.project_id AND projects.deleted_at IS NULL").
+		Where("timesheets.user_id = ? AND workers.user_id = ? AND projects.user_id = ?", userID, userID, userID)
+	if summary.WorkerID != 0 {
+		query = query.Where("timesheets.worker_id = ?", summary.WorkerID)
+	}
+	if summary.ProjectID != 0 {
+		query = query.Where("timesheets.project_id = ?", summary.ProjectID)
+	}
+	if summary.Status != "" {
+		query = query.Where("timesheets.status = ?", summary.Status)
+	}
+	if summary.From != nil {
+		query = query.Where("timesheets.date >= ?", *summary.From)
+	}
+	if summary.To != nil {
+		query = query.Where("timesheets.date <= ?", *summary.To)
+	}
+
+	var entries []TimesheetHours
+	if err := query.Scan(&entries).Error; err != nil {
+		return nil, err
+	}
+	return SummarizeTimesheets(entries, summary.GroupBy, summary.Period), nil
+}
+
+// ListApprovals lists the submitted entries awaiting review, leaving out
+// those of trashed workers and projects; the search term is matched against
+// the notes
+func (r *timesheetRepository) ListApprovals(ctx context.Context, managerID uint, opts ListOptions) ([]TimesheetApproval, PageInfo, error) {
+	approvals := r.db.WithContext(ctx).Table("timesheets").
+		Select("timesheets.*, users.username, users.manager_id, workers.name AS worker_name, projects.name AS project_name").
+		Joins("JOIN users ON users.id = timesheets.user_id AND users.deleted_at IS NULL").
+		Joins("JOIN workers ON workers.id = timesheets.worker_id AND workers.deleted_at IS NULL").
+		Joins("JOIN projects ON projects.id = timesheets.project_id AND projects.deleted_at IS NULL").
+		Where("timesheets.status = ?", model.TimesheetSubmitted)
+	if managerID != 0 {
+		approvals = approvals.Where("users.manager_id = ?", managerID)
+	}
+
+	// Filter and sort on the columns of the joined rows, named as in the schema
+	db := r.db.WithContext(ctx).Table("(?) AS approvals", approvals)
+	if opts.Search != "" {
+		db = searchColumns(db, opts.Search, "notes")
+	}
+	return findPage(opts.Query.ApplyFilters(db), opts, func(query *gorm.DB) *gorm.DB {
+		return query
+	}, TimesheetApprovalRow)
+}
+
+// GetForReview retrieves an entry of a user reporting to a manager
+func (r *timesheetRepository) GetForReview(ctx context.Context, id uint, managerID uint) (*model.Timesheet, error) {
+	query := r.db.WithContext(ctx).Where("id = ?", id)
+	if managerID != 0 {
+		query = query.Where("user_id IN (?)", r.db.Model(&model.User{}).Select("id").Where("manager_id = ?", managerID))
+	}
+
+	var entry model.Timesheet
+	if err := query.First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Transition writes the status and review fields of an entry still in status from
+func (r *timesheetRepository) Transition(ctx context.Context, entry *model.Timesheet, from string) error {
+	result := r.db.WithContext(ctx).Model(entry).Where("status = ?", from).
+		Select("status", "submitted_at", "reviewed_by", "reviewed_at", "review_comment", "updated_at").
+		Updates(entry)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// SummarizeTimesheets adds up the hours of timesheet entries by worker or
+// project and by week or month. Summaries are sorted by period, then by name.
+func SummarizeTimesheets(entries []TimesheetHours, groupBy, period string) []TimesheetSummary {
+	type key struct {
+		id    uint
+		start time.Time
+	}
+	summaries := make(map[key]*TimesheetSummary)
+	days := make(map[key]map[time.Time]bool)
+
+	for _, entry := range entries {
+		start, end := periodOf(entry.Date, period)
+		k := key{id: entry.WorkerID, start: start}
+		if groupBy == SummaryByProject {
+			k.id = entry.ProjectID
+		}
+
+		summary, ok := summaries[k]
+		if !ok {
+			summary = &TimesheetSummary{PeriodStart: start, PeriodEnd: end}
+			if groupBy == SummaryByProject {
+				summary.ProjectID, summary.ProjectName = entry.ProjectID, entry.ProjectName
+			} else {
+				summary.WorkerID, summary.WorkerName = entry.WorkerID, entry.WorkerName
+			}
+			summaries[k] = summary
+			days[k] = make(map[time.Time]bool)
+		}
+		summary.Hours += entry.Hours
+		summary.OvertimeHours += entry.OvertimeHours
+		summary.BreakMinutes += entry.BreakMinutes
+		days[k][day(entry.Date)] = true
+	}
+
+	result := make([]TimesheetSummary, 0, len(summaries))
+	for k, summary := range summaries {
+		summary.Days = len(days[k])
+		summary.Hours = roundHours(summary.Hours)
+		summary.OvertimeHours = roundHours(summary.OvertimeHours)
+		summary.TotalHours = roundHours(summary.Hours + summary.OvertimeHours)
+		result = append(result, *summary)
+	}
+	group := func(summary TimesheetSummary) (string, uint) {
+		if groupBy == SummaryByProject {
+			return summary.ProjectName, summary.ProjectID
+		}
+		return summary.WorkerName, summary.WorkerID
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].PeriodStart.Equal(result[j].PeriodStart) {
+			return result[i].PeriodStart.Before(result[j].PeriodStart)
+		}
+		nameI, idI := group(result[i])
+		nameJ, idJ := group(result[j])
+		if nameI != nameJ {
+			return nameI < nameJ
+		}
+		return idI < idJ
+	})
+	return result
+}
+
+// periodOf returns the first and last days of the week, starting on Monday,
+// or of the month of a date
+func periodOf(date time.Time, period string) (time.Time, time.Time) {
+	date = day(date)
+	if period == SummaryMonth {
+		start := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, -1)
+	}
+	start := date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+	return start, start.AddDate(0, 0, 6)
+}
+
+// roundHours rounds a sum of hours to the hundredth, dropping the float noise
+func roundHours(hours float64) float64 {
+	return math.Round(hours*100) / 100
+}
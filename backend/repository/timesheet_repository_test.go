@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+)
+
+func TestApprovedWeekIsLocked(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	const userID = 1
+	worker, project := assignedWorker(t, db, userID, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	timesheets := NewTimesheetRepository(db)
+
+	entryOn := func(date time.Time) *model.Timesheet {
+		return &model.Timesheet{WorkerID: worker.ID, ProjectID: project.ID, Date: date, Hours: 8, UserID: userID}
+	}
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	approved, draft := entryOn(monday), entryOn(monday.AddDate(0, 0, 1))
+	for _, entry := range []*model.Timesheet{approved, draft} {
+		if err := timesheets.Create(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	approved.Status = model.TimesheetApproved
+	if err := timesheets.Transition(ctx, approved, model.TimesheetDraft); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := *draft
+	changed.Hours = 6
+	nextWeek := *draft
+	nextWeek.Date = monday.AddDate(0, 0, 7)
+	writes := map[string]func() error{
+		"create": func() error { return timesheets.Create(ctx, entryOn(monday.AddDate(0, 0, 2))) },
+		"update": func() error { return timesheets.Update(ctx, &changed) },
+		"move":   func() error { return timesheets.Update(ctx, &nextWeek) },
+		"save new day": func() error {
+			_, err := timesheets.Save(ctx, entryOn(monday.AddDate(0, 0, 3)))
+			return err
+		},
+		"save existing day": func() error {
+			_, err := timesheets.Save(ctx, entryOn(draft.Date))
+			return err
+		},
+		"delete": func() error { return timesheets.Delete(ctx, draft.ID, userID) },
+	}
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, ErrPeriodLocked) {
+			t.Errorf("%s: expected ErrPeriodLocked, got %v", name, err)
+		}
+	}
+
+	stored, err := timesheets.GetByID(ctx, draft.ID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Hours != 8 || !stored.Date.Equal(draft.Date) {
+		t.Fatalf("expected the draft to be unchanged, got %v hours on %s", stored.Hours, stored.Date)
+	}
+
+	// The weeks around it are still open
+	if err := timesheets.Create(ctx, entryOn(monday.AddDate(0, 0, 7))); err != nil {
+		t.Fatalf("expected the next week to take entries, got %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"gorm.io/gorm"
+)
+
+// TrashItem is a soft-deleted worker or project. The worker assignments and
+// the history of a trashed record are kept, so restoring it restores them
+// too; they are only removed when the record is purged.
+type TrashItem struct {
+	Type      model.EntityType `json:"type"`
+	ID        uint             `json:"id"`
+	Name      string           `json:"name"`
+	DeletedAt time.Time        `json:"deleted_at"`
+	// Assignments counts the assignments to records that are not trashed,
+	// i.e. those visible again once the item is restored
+	Assignments int64 `json:"assignments"`
+}
+
+// TrashRepository lists, restores and purges soft-deleted workers and projects.
+// Operations on a single item return gorm.ErrRecordNotFound when the item is
+// not in the user's trash.
+type TrashRepository interface {
+	List(ctx context.Context, userID uint, types []model.EntityType, page, pageSize int) ([]TrashItem, int64, error)
+	Restore(ctx context.Context, userID uint, entityType model.EntityType, id uint) (*TrashItem, error)
+	Purge(ctx context.Context, userID uint, entityType model.EntityType, id uint) error
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type trashRepository struct {
+	db *gorm.DB
+}
+
+// NewTrashRepository creates a new TrashRepository instance
+func NewTrashRepository(db *gorm.DB) TrashRepository {
+	return &trashRepository{
+		db: db,
+	}
+}
+
+// trashTable describes how a trashable entity is stored
+type trashTable struct {
+	entityType model.EntityType
+	model      interface{}
+	// joinColumn references the entity in the worker_projects and timesheets tables
+	joinColumn string
+	// other is the table at the other end of an assignment
+	other       string
+	otherColumn string
+}
+
+var trashTables = []trashTable{
+	{entityType: model.EntityTypeWorker, model: &model.Worker{}, joinColumn: "worker_id", other: "projects", otherColumn: "project_id"},
+	{entityType: model.EntityTypeProject, model: &model.Project{}, joinColumn: "project_id", other: "workers", otherColumn: "worker_id"},
+}
+
+// trashTableOf returns the table of an entity type
+func trashTableOf(entityType model.EntityType) (trashTable, bool) {
+	for _, table := range trashTables {
+		if table.entityType == entityType {
+			return table, true
+		}
+	}
+	return trashTable{}, false
+}
+
+// List retrieves the user's trashed items of the given types (all when none
+// are given), most recently deleted first
+func (r *trashRepository) List(ctx context.Context, userID uint, types []model.EntityType, page, pageSize int) ([]TrashItem, int64, error) {
+	items := make([]TrashItem, 0)
+	var total int64
+	for _, table := range trashTables {
+		if !includesType(types, table.entityType) {
+			continue
+		}
+
+		query := r.db.WithContext(ctx).Unscoped().Model(table.model).
+			Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, 0, err
+		}
+		total += count
+
+		// Any item of the page is among the first page*pageSize of its table
+		query = query.Select("id, name, deleted_at").Order("deleted_at DESC, id DESC")
+		if page > 0 && pageSize > 0 {
+			query = query.Limit(page * pageSize)
+		}
+		var rows []TrashItem
+		if err := query.Scan(&rows).Error; err != nil {
+			return nil, 0, err
+		}
+		for i := range rows {
+			rows[i].Type = table.entityType
+		}
+		items = append(items, rows...)
+	}
+
+	// Count the assignments of the items of the page only
+	items = PageTrash(items, page, pageSize)
+	for _, table := range trashTables {
+		var ids []uint
+		for _, item := range items {
+			if item.Type == table.entityType {
+				ids = append(ids, item.ID)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		counts, err := r.countAssignments(r.db.WithContext(ctx), table, ids)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range items {
+			if items[i].Type == table.entityType {
+				items[i].Assignments = counts[items[i].ID]
+			}
+		}
+	}
+	return items, total, nil
+}
+
+// Restore un-deletes an item of the user's trash
+func (r *trashRepository) Restore(ctx context.Context, userID uint, entityType model.EntityType, id uint) (*TrashItem, error) {
+	table, ok := trashTableOf(entityType)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	item := &TrashItem{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(table.model).
+			Select("name, deleted_at").Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+			Limit(1).Scan(item)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		item.Type, item.ID = entityType, id
+
+		// Restoring is a write, so the record gets a new version
+		if err := tx.Unscoped().Model(table.model).Where("id = ?", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error; err != nil {
+			return err
+		}
+		if err := recordChanges(tx, NewChange(ctx, entityType, id, model.LogTypeRestore, userID, nil)); err != nil {
+			return err
+		}
+
+		counts, err := r.countAssignments(tx, table, []uint{id})
+		item.Assignments = counts[id]
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Purge permanently deletes an item of the user's trash with its assignments and timesheets
+func (r *trashRepository) Purge(ctx context.Context, userID uint, entityType model.EntityType, id uint) error {
+	table, ok := trashTableOf(entityType)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(table.model).
+			Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// The rows referencing the item go first, so its foreign keys hold
+		if err := tx.Where(table.joinColumn+" = ?", id).Delete(&model.WorkerProject{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where(table.joinColumn+" = ?", id).Delete(&model.Timesheet{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("entity_type = ? AND entity_id = ?", entityType, id).Delete(&model.EntityChange{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id = ?", id).Delete(table.model).Error
+	})
+}
+
+// PurgeDeletedBefore permanently deletes every worker and project trashed
+// before the cutoff, for all users, and returns how many were deleted
+func (r *trashRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range trashTables {
+			expired := tx.Unscoped().Model(table.model).Select("id").
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+			if err := tx.Where(table.joinColumn+" IN (?)", expired).Delete(&model.WorkerProject{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where(table.joinColumn+" IN (?)", expired).Delete(&model.Timesheet{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("entity_type = ? AND entity_id IN (?)", table.entityType, expired).
+				Delete(&model.EntityChange{}).Error; err != nil {
+				return err
+			}
+
+			result := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(table.model)
+			if result.Error != nil {
+				return result.Error
+			}
+			purged += result.RowsAffected
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// countAssignments counts the assignments of entities to records of the
+// other table that are not trashed, by entity ID, in one query
+func (r *trashRepository) countAssignments(db *gorm.DB, table trashTable, ids []uint) (map[uint]int64, error) {
+	var rows []struct {
+		ID    uint
+		Count int64
+	}
+	err := db.Table("worker_projects").
+		Select("worker_projects."+table.joinColumn+" AS id, COUNT(*) AS count").
+		Joins("JOIN "+table.other+" ON "+table.other+".id = worker_projects."+table.otherColumn).
+		Where("worker_projects."+table.joinColumn+" IN ? AND "+table.other+".deleted_at IS NULL", ids).
+		Group("worker_projects." + table.joinColumn).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ID] = row.Count
+	}
+	return counts, nil
+}
+
+// PageTrash orders trashed items most recently deleted first and returns the
+// requested page
+func PageTrash(items []TrashItem, page, pageSize int) []TrashItem {
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].DeletedAt.Equal(items[j].DeletedAt) {
+			return items[i].DeletedAt.After(items[j].DeletedAt)
+		}
+		if items[i].Type != items[j].Type {
+			return items[i].Type > items[j].Type
+		}
+		return items[i].ID > items[j].ID
+	})
+
+	if page <= 0 || pageSize <= 0 {
+		return items
+	}
+	offset := (page - 1) * pageSize
+	if offset >= len(items) {
+		return []TrashItem{}
+	}
+	return items[offset:min(offset+pageSize, len(items))]
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
+	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
+	"gorm.io/gorm"
+)
+
+// openTestDB opens a migrated SQLite database in a temporary directory, the
+// way the server opens its own
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := config.Default().Database
+	cfg.Driver = config.DriverSQLite
+	cfg.Path = filepath.Join(t.TempDir(), "test.db")
+	db := config.InitDB(cfg)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// trashedWorkerWithHistory creates a worker of the user assigned to a project
+// with a timesheet entry, then moves the worker to the trash
+func trashedWorkerWithHistory(t *testing.T, db *gorm.DB, userID uint) uint {
+	t.Helper()
+	ctx := context.Background()
+	worker := &model.Worker{Name: "Ana Pop", Age: 30, Position: "Mason", Salary: 3000, UserID: userID}
+	if err := NewWorkerRepository(db).Create(ctx, worker); err != nil {
+		t.Fatal(err)
+	}
+	project := &model.Project{Name: "North", Description: "A test building site", Status: "active",
+		StartDate: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Latitude: 46.77, Longitude: 23.59, UserID: userID}
+	if err := NewProjectRepository(db).Create(ctx, project); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewWorkerRepository(db).AddToProject(ctx, worker.ID, project.ID, userID); err != nil {
+		t.Fatal(err)
+	}
+	entry := &model.Timesheet{WorkerID: worker.ID, ProjectID: project.ID,
+		Date: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), Hours: 8, UserID: userID}
+	if err := NewTimesheetRepository(db).Create(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewWorkerRepository(db).Delete(ctx, worker.ID, userID); err != nil {
+		t.Fatal(err)
+	}
+	return worker.ID
+}
+
+// expectRows fails the test unless the query counts the rows
+func expectRows(t *testing.T, query *gorm.DB, want int64) {
+	t.Helper()
+	var got int64
+	if err := query.Count(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("expected %d rows, got %d", want, got)
+	}
+}
+
+func TestPurgeDeletesAssignedWorker(t *testing.T) {
+	db := openTestDB(t)
+	const userID = 1
+	workerID := trashedWorkerWithHistory(t, db, userID)
+
+	if err := NewTrashRepository(db).Purge(context.Background(), userID, model.EntityTypeWorker, workerID); err != nil {
+		t.Fatalf("purging the worker: %v", err)
+	}
+
+	expectRows(t, db.Unscoped().Model(&model.Worker{}).Where("id = ?", workerID), 0)
+	expectRows(t, db.Model(&model.WorkerProject{}).Where("worker_id = ?", workerID), 0)
+	expectRows(t, db.Model(&model.Timesheet{}).Where("worker_id = ?", workerID), 0)
+	expectRows(t, db.Model(&model.EntityChange{}).
+		Where("entity_type = ? AND entity_id = ?", model.EntityTypeWorker, workerID), 0)
+	expectRows(t, db.Model(&model.Project{}), 1)
+}
+
+func TestPurgeLeavesOtherUsersAlone(t *testing.T) {
+	db := openTestDB(t)
+	workerID := trashedWorkerWithHistory(t, db, 1)
+
+	err := NewTrashRepository(db).Purge(context.Background(), 2, model.EntityTypeWorker, workerID)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+	expectRows(t, db.Unscoped().Model(&model.Worker{}).Where("id = ?", workerID), 1)
+	expectRows(t, db.Model(&model.WorkerProject{}).Where("worker_id = ?", workerID), 1)
+}
+
+func TestPurgeDeletedBeforeDeletesAssignedWorker(t *testing.T) {
+	db := openTestDB(t)
+	workerID := trashedWorkerWithHistory(t, db, 1)
+
+	purged, err := NewTrashRepository(db).PurgeDeletedBefore(context.Background(), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("purging the trash: %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected 1 item purged, got %d", purged)
+	}
+	expectRows(t, db.Unscoped().Model(&model.Worker{}).Where("id = ?", workerID), 0)
+	expectRows(t, db.Model(&model.WorkerProject{}).Where("worker_id = ?", workerID), 0)
+}
+
+func TestPurgeDeletedBeforeKeepsRecentItems(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	expired := trashedWorkerWithHistory(t, db, 1)
+	recent := trashedWorkerWithHistory(t, db, 2)
+	if err := db.Unscoped().Model(&model.Worker{}).Where("id = ?", expired).
+		Update("deleted_at", time.Now().AddDate(0, 0, -40)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := NewTrashRepository(db).PurgeDeletedBefore(ctx, time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected 1 item purged, got %d", purged)
+	}
+	expectRows(t, db.Unscoped().Model(&model.Worker{}).Where("id = ?", expired), 0)
+	expectRows(t, db.Unscoped().Model(&model.Worker{}).Where("id = ?", recent), 1)
+	expectRows(t, db.Model(&model.WorkerProject{}).Where("worker_id = ?", recent), 1)
+}
+
+func TestRestoreBringsBackTheAssignments(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	const userID = 1
+	workerID := trashedWorkerWithHistory(t, db, userID)
+	trash := NewTrashRepository(db)
+
+	items, total, err := trash.List(ctx, userID, nil, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || items[0].ID != workerID || items[0].Assignments != 1 {
+		t.Fatalf("expected the worker in the trash with 1 assignment, got %+v", items)
+	}
+	if _, total, err := trash.List(ctx, 2, nil, 1, 10); err != nil || total != 0 {
+		t.Fatalf("expected the trash of another user to be empty, got %d items (%v)", total, err)
+	}
+
+	item, err := trash.Restore(ctx, userID, model.EntityTypeWorker, workerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Assignments != 1 {
+		t.Fatalf("expected the restored worker to get 1 assignment back, got %d", item.Assignments)
+	}
+	worker, err := NewWorkerRepository(db).GetByID(ctx, workerID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worker.Projects) != 1 {
+		t.Fatalf("expected the restored worker on 1 project, got %d", len(worker.Projects))
+	}
+	if _, err := trash.Restore(ctx, userID, model.EntityTypeWorker, workerID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected a restored worker to be out of the trash, got %v", err)
+	}
+}
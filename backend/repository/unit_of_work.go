@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Repositories groups the repositories that share one database handle, so that
+// they can take part in the same transaction
+type Repositories struct {
+	Workers    WorkerRepository
+	Projects   ProjectRepository
+	Timesheets TimesheetRepository
+	Users      UserRepository
+	Logs       LogRepository
+	// Nested runs operations in a unit of work of their own within the one
+	// the repositories take part in, a savepoint of its transaction, so
+	// that they can fail without failing the enclosing one
+	Nested UnitOfWork
+}
+
+// NewRepositories creates every repository on top of the given database handle
+func NewRepositories(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Workers:    NewWorkerRepository(db),
+		Projects:   NewProjectRepository(db),
+		Timesheets: NewTimesheetRepository(db),
+		Users:      NewUserRepository(db),
+		Logs:       NewLogRepository(db),
+		Nested:     NewUnitOfWork(db),
+	}
+}
+
+// UnitOfWork runs operations spanning several repositories atomically
+type UnitOfWork interface {
+	// Do runs fn with repositories bound to a single transaction. The
+	// transaction is committed if fn returns nil and rolled back if it
+	// returns an error or panics.
+	Do(ctx context.Context, fn func(repos *Repositories) error) error
+}
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+// NewUnitOfWork creates a new UnitOfWork instance
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &unitOfWork{
+		db: db,
+	}
+}
+
+// Do runs fn inside a database transaction, or a savepoint of the
+// transaction the database handle is bound to
+func (u *unitOfWork) Do(ctx context.Context, fn func(repos *Repositories) error) error {
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepositories(tx))
+	})
+}
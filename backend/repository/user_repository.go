@@ -1,39 +1,43 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"time"
 
-	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	CreateUser(user *model.User, plainPassword string) error
-	GetUserByID(id uint) (*model.User, error)
-	GetUserByUsername(username string) (*model.User, error)
-	GetUserByEmail(email string) (*model.User, error)
-	ValidateCredentials(username, password string) (*model.User, error)
-	UpdateLastLogin(userID uint) error
-	ChangePassword(userID uint, newPassword string) error
-	GetAllUsers(page, pageSize int, search string) ([]model.User, int64, error)
-	UpdateUserStatus(userID uint, active bool) error
-	UpdateUserRole(userID uint, role string) error
+	CreateUser(ctx context.Context, user *model.User, plainPassword string) error
+	GetUserByID(ctx context.Context, id uint) (*model.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	ValidateCredentials(ctx context.Context, username, password string) (*model.User, error)
+	UpdateLastLogin(ctx context.Context, userID uint) error
+	ChangePassword(ctx context.Context, userID uint, newPassword string) error
+	GetAllUsers(ctx context.Context, page, pageSize int, search string) ([]model.User, int64, error)
+	UpdateUserStatus(ctx context.Context, userID uint, active bool) error
+	UpdateUserRole(ctx context.Context, userID uint, role string) error
+	// UpdateUserManager sets the manager approving a user's timesheets; nil
+	// leaves the user without one
+	UpdateUserManager(ctx context.Context, userID uint, managerID *uint) error
 }
 
 type userRepository struct {
 	db *gorm.DB
 }
 
-func NewUserRepository() UserRepository {
+func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{
-		db: config.DB,
+		db: db,
 	}
 }
 
 // CreateUser creates a new user with hashed password
-func (r *userRepository) CreateUser(user *model.User, plainPassword string) error {
+func (r *userRepository) CreateUser(ctx context.Context, user *model.User, plainPassword string) error {
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(plainPassword), bcrypt.DefaultCost)
 	if err != nil {
@@ -42,39 +46,39 @@ func (r *userRepository) CreateUser(user *model.User, plainPassword string) erro
 	
 	user.PasswordHash = string(hashedPassword)
 	
-	return r.db.Create(user).Error
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
 // GetUserByID retrieves a user by ID
-func (r *userRepository) GetUserByID(id uint) (*model.User, error) {
+func (r *userRepository) GetUserByID(ctx context.Context, id uint) (*model.User, error) {
 	var user model.User
-	if err := r.db.First(&user, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
 // GetUserByUsername retrieves a user by username
-func (r *userRepository) GetUserByUsername(username string) (*model.User, error) {
+func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user model.User
-	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
 // GetUserByEmail retrieves a user by email
-func (r *userRepository) GetUserByEmail(email string) (*model.User, error) {
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
 // ValidateCredentials validates user credentials and returns the user if valid
-func (r *userRepository) ValidateCredentials(username, password string) (*model.User, error) {
-	user, err := r.GetUserByUsername(username)
+func (r *userRepository) ValidateCredentials(ctx context.Context, username, password string) (*model.User, error) {
+	user, err := r.GetUserByUsername(ctx, username)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("invalid credentials")
@@ -97,40 +101,40 @@ func (r *userRepository) ValidateCredentials(username, password string) (*model.
 }
 
 // UpdateLastLogin updates the last login timestamp for a user
-func (r *userRepository) UpdateLastLogin(userID uint) error {
-	return r.db.Exec("UPDATE users SET last_login = NOW() WHERE id = ?", userID).Error
+func (r *userRepository) UpdateLastLogin(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Update("last_login", time.Now()).Error
 }
 
 // ChangePassword changes a user's password
-func (r *userRepository) ChangePassword(userID uint, newPassword string) error {
+func (r *userRepository) ChangePassword(ctx context.Context, userID uint, newPassword string) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 	
-	return r.db.Model(&model.User{}).Where("id = ?", userID).
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).
 		Update("password_hash", string(hashedPassword)).Error
 }
 
 // GetAllUsers retrieves all users with pagination and search
-func (r *userRepository) GetAllUsers(page, pageSize int, search string) ([]model.User, int64, error) {
+func (r *userRepository) GetAllUsers(ctx context.Context, page, pageSize int, search string) ([]model.User, int64, error) {
 	var users []model.User
 	var total int64
 
 	// Base query
-	query := r.db.Model(&model.User{})
+	query := r.db.WithContext(ctx).Model(&model.User{})
 	
-	// Apply search filter if provided
+	// Apply search filter if provided, ignoring case
 	if search != "" {
-		query = query.Where("username LIKE ? OR email LIKE ?", "%"+search+"%", "%"+search+"%")
+		query = searchColumns(query, search, "username", "email")
 	}
 	
 	// Get total count
 	query.Count(&total)
 	
-	// Apply pagination
+	// Apply pagination, in ID order so that pages neither repeat nor skip users
 	offset := (page - 1) * pageSize
-	if err := query.Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
+	if err := query.Order("id").Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
 		return nil, 0, err
 	}
 	
@@ -143,16 +147,28 @@ func (r *userRepository) GetAllUsers(page, pageSize int, search string) ([]model
 }
 
 // UpdateUserStatus activates or deactivates a user
-func (r *userRepository) UpdateUserStatus(userID uint, active bool) error {
-	return r.db.Model(&model.User{}).Where("id = ?", userID).Update("active", active).Error
+func (r *userRepository) UpdateUserStatus(ctx context.Context, userID uint, active bool) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Update("active", active).Error
 }
 
 // UpdateUserRole changes a user's role
-func (r *userRepository) UpdateUserRole(userID uint, role string) error {
+func (r *userRepository) UpdateUserRole(ctx context.Context, userID uint, role string) error {
 	// Validate role
-	if role != "user" && role != "admin" {
+	if !model.ValidRole(role) {
 		return errors.New("invalid role")
 	}
 	
-	return r.db.Model(&model.User{}).Where("id = ?", userID).Update("role", role).Error
-} 
\ No newline at end of file
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Update("role", role).Error
+}
+
+// UpdateUserManager sets or clears a user's manager
+func (r *userRepository) UpdateUserManager(ctx context.Context, userID uint, managerID *uint) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Update("manager_id", managerID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import "errors"
+
+// ErrVersionConflict is returned by updates made against a version of a
+// record that is no longer the current one
+var ErrVersionConflict = errors.New("version conflict")
+
+// NextVersion returns the version a record gets when it is written. The
+// expected version is the one the write was based on; zero skips the check.
+func NextVersion(current, expected uint) (uint, error) {
+	if expected != 0 && expected != current {
+		return 0, ErrVersionConflict
+	}
+	return current + 1, nil
+}
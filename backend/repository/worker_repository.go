@@ -1,152 +1,271 @@
 package repository
 
 import (
-	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/config"
+	"context"
+
 	"github.com/Forquosh/Worksite-Management-Studio-Online/backend/model"
 	"gorm.io/gorm"
 )
 
-type WorkerRepository struct {
+type WorkerRepository interface {
+	Create(ctx context.Context, worker *model.Worker) error
+	GetByID(ctx context.Context, id uint, userID uint) (*model.Worker, error)
+	GetAll(ctx context.Context, userID uint, opts ListOptions) ([]model.Worker, PageInfo, error)
+	// Each calls fn with every worker matching the search and filters of
+	// the options, in their sort order, reading them one at a time. Paging
+	// is ignored and the projects of the workers are not loaded.
+	Each(ctx context.Context, userID uint, opts ListOptions, fn func(model.Worker) error) error
+	Update(ctx context.Context, worker *model.Worker, userID uint) error
+	Patch(ctx context.Context, worker *model.Worker, fields []string, userID uint) error
+	Delete(ctx context.Context, id uint, userID uint) error
+	AddToProject(ctx context.Context, workerID, projectID, userID uint) error
+	RemoveFromProject(ctx context.Context, workerID, projectID, userID uint) error
+}
+
+type workerRepository struct {
 	db *gorm.DB
 }
 
-func NewWorkerRepository() *WorkerRepository {
-	return &WorkerRepository{
-		db: config.DB,
+func NewWorkerRepository(db *gorm.DB) WorkerRepository {
+	return &workerRepository{
+		db: db,
 	}
 }
 
 // Create creates a new worker
-func (r *WorkerRepository) Create(worker *model.Worker) error {
-	return r.db.Create(worker).Error
+func (r *workerRepository) Create(ctx context.Context, worker *model.Worker) error {
+	worker.Version = 1
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(worker).Error; err != nil {
+			return err
+		}
+		return recordWorkerChange(ctx, tx, nil, worker, model.LogTypeCreate)
+	})
 }
 
 // GetByID retrieves a worker by ID and user ID
-func (r *WorkerRepository) GetByID(id uint, userID uint) (*model.Worker, error) {
+func (r *workerRepository) GetByID(ctx context.Context, id uint, userID uint) (*model.Worker, error) {
 	var worker model.Worker
-	// Use preload with a custom join query to check both project's user_id and join table's user_id
-	err := r.db.Preload("Projects", func(db *gorm.DB) *gorm.DB {
-		return db.Joins("JOIN worker_projects ON worker_projects.project_id = projects.id").
-			Where("projects.user_id = ? AND worker_projects.user_id = ?", userID, userID)
-	}).Where("id = ? AND user_id = ?", id, userID).First(&worker).Error
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&worker).Error
 	if err != nil {
 		return nil, err
 	}
+	if err := loadProjects(r.db.WithContext(ctx), userID, &worker); err != nil {
+		return nil, err
+	}
 	return &worker, nil
 }
 
+// filtered returns the user's workers matching the search and filters of the options
+func (r *workerRepository) filtered(ctx context.Context, userID uint, opts ListOptions) *gorm.DB {
+	return filterWorkers(r.db.WithContext(ctx), userID, opts)
+}
+
+// filterWorkers selects the user's workers matching the search and filters of the options
+func filterWorkers(db *gorm.DB, userID uint, opts ListOptions) *gorm.DB {
+	query := db.Model(&model.Worker{}).Where("user_id = ?", userID)
+
+	// Apply search, ignoring case on every database
+	if opts.Search != "" {
+		query = searchColumns(query, opts.Search, "name", "position")
+	}
+
+	// Apply the whitelisted filters
+	return opts.Query.ApplyFilters(query)
+}
+
 // GetAll retrieves all workers with optional filtering and sorting for a specific user
-func (r *WorkerRepository) GetAll(userID uint, filters map[string]interface{}, sortBy string, sortOrder string, page int, pageSize int) ([]model.Worker, int64, error) {
-	var workers []model.Worker
-	var total int64
-	query := r.db.Model(&model.Worker{}).Where("user_id = ?", userID)
-
-	// Apply filters
-	for key, value := range filters {
-		switch key {
-		case "search":
-			searchTerm := value.(string)
-			query = query.Where("name LIKE ? OR position LIKE ?", "%"+searchTerm+"%", "%"+searchTerm+"%")
-		case "position":
-			query = query.Where("position = ?", value)
-		case "min_age":
-			query = query.Where("age >= ?", value)
-		case "max_age":
-			query = query.Where("age <= ?", value)
-		case "min_salary":
-			query = query.Where("salary >= ?", value)
-		case "max_salary":
-			query = query.Where("salary <= ?", value)
-		default:
-			query = query.Where(key+" = ?", value)
-		}
+func (r *workerRepository) GetAll(ctx context.Context, userID uint, opts ListOptions) ([]model.Worker, PageInfo, error) {
+	workers, info, err := findPage(r.filtered(ctx, userID, opts), opts, func(query *gorm.DB) *gorm.DB { return query }, WorkerRow)
+	if err != nil {
+		return nil, info, err
+	}
+	page := make([]*model.Worker, len(workers))
+	for i := range workers {
+		page[i] = &workers[i]
 	}
+	if err := loadProjects(r.db.WithContext(ctx), userID, page...); err != nil {
+		return nil, info, err
+	}
+	return workers, info, nil
+}
 
-	// Count total records (before pagination)
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+// loadProjects sets the projects each worker has an assignment to that has
+// not ended, leaving out the projects in the trash
+func loadProjects(db *gorm.DB, userID uint, workers ...*model.Worker) error {
+	ids := make([]uint, len(workers))
+	for i, worker := range workers {
+		worker.Projects = []model.Project{}
+		ids[i] = worker.ID
+	}
+	if len(ids) == 0 {
+		return nil
 	}
 
-	// Apply sorting
-	if sortBy != "" {
-		order := sortBy
-		if sortOrder == "desc" {
-			order += " DESC"
-		}
-		query = query.Order(order)
+	var assigned []struct {
+		WorkerID  uint
+		ProjectID uint
+	}
+	err := currentAssignments(db.Table("worker_projects").Distinct("worker_projects.worker_id", "worker_projects.project_id").
+		Joins("JOIN projects ON projects.id = worker_projects.project_id AND projects.deleted_at IS NULL").
+		Where("worker_projects.worker_id IN ? AND worker_projects.user_id = ? AND projects.user_id = ?", ids, userID, userID)).
+		Order("worker_projects.project_id").
+		Scan(&assigned).Error
+	if err != nil || len(assigned) == 0 {
+		return err
 	}
 
-	// Apply pagination
-	if page > 0 && pageSize > 0 {
-		offset := (page - 1) * pageSize
-		query = query.Offset(offset).Limit(pageSize)
+	projectIDs := make([]uint, len(assigned))
+	for i, row := range assigned {
+		projectIDs[i] = row.ProjectID
+	}
+	var projects []model.Project
+	if err := db.Where("id IN ?", projectIDs).Find(&projects).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]model.Project, len(projects))
+	for _, project := range projects {
+		byID[project.ID] = project
 	}
 
-	// Add user_id condition to the preloaded Projects to ensure we only get projects belonging to the current user
-	// Also ensure the worker_projects join table has the correct user_id
-	err := query.Preload("Projects", func(db *gorm.DB) *gorm.DB {
-		return db.Joins("JOIN worker_projects ON worker_projects.project_id = projects.id").
-			Where("projects.user_id = ? AND worker_projects.user_id = ?", userID, userID)
-	}).Find(&workers).Error
-	return workers, total, err
+	byWorker := make(map[uint]*model.Worker, len(workers))
+	for _, worker := range workers {
+		byWorker[worker.ID] = worker
+	}
+	for _, row := range assigned {
+		worker := byWorker[row.WorkerID]
+		worker.Projects = append(worker.Projects, byID[row.ProjectID])
+	}
+	return nil
 }
 
-// Update updates a worker
-func (r *WorkerRepository) Update(worker *model.Worker, userID uint) error {
-	// First check if this worker belongs to the user
-	result := r.db.Where("id = ? AND user_id = ?", worker.ID, userID).First(&model.Worker{})
-	if result.Error != nil {
-		return result.Error
-	}
-	
-	return r.db.Save(worker).Error
+// Each calls fn with every matching worker, one row at a time
+func (r *workerRepository) Each(ctx context.Context, userID uint, opts ListOptions, fn func(model.Worker) error) error {
+	return eachRow(sortAll(r.filtered(ctx, userID, opts), opts), fn)
+}
+
+// Update replaces the fields of a worker. When the worker carries a version,
+// the update only applies to that version of the record and fails with
+// ErrVersionConflict otherwise.
+func (r *workerRepository) Update(ctx context.Context, worker *model.Worker, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// First check if this worker belongs to the user
+		var current model.Worker
+		if err := tx.Where("id = ? AND user_id = ?", worker.ID, userID).First(&current).Error; err != nil {
+			return err
+		}
+
+		version, err := NextVersion(current.Version, worker.Version)
+		if err != nil {
+			return err
+		}
+		worker.Version = version
+		worker.CreatedAt = current.CreatedAt
+
+		// Write every field like Save, unless another write got in first
+		result := tx.Model(worker).Where("version = ?", current.Version).
+			Select("*").Omit("created_at", "deleted_at", "Projects").Updates(worker)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return recordWorkerChange(ctx, tx, &current, worker, model.LogTypeUpdate)
+	})
+}
+
+// Patch writes the given columns of a worker, with the version check of Update
+func (r *workerRepository) Patch(ctx context.Context, worker *model.Worker, fields []string, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// First check if this worker belongs to the user
+		var current model.Worker
+		if err := tx.Where("id = ? AND user_id = ?", worker.ID, userID).First(&current).Error; err != nil {
+			return err
+		}
+
+		version, err := NextVersion(current.Version, worker.Version)
+		if err != nil {
+			return err
+		}
+		worker.Version = version
+
+		columns := append([]string{"version", "updated_at"}, fields...)
+		result := tx.Model(worker).Where("version = ?", current.Version).Select(columns).Updates(worker)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return recordWorkerChange(ctx, tx, &current, worker, model.LogTypeUpdate)
+	})
 }
 
 // Delete deletes a worker
-func (r *WorkerRepository) Delete(id uint, userID uint) error {
-	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Worker{}).Error
+func (r *workerRepository) Delete(ctx context.Context, id uint, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Worker{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return recordChanges(tx, NewChange(ctx, model.EntityTypeWorker, id, model.LogTypeDelete, userID, nil))
+	})
 }
 
 // AddToProject adds a worker to a project (ensuring both belong to the user)
-func (r *WorkerRepository) AddToProject(workerID, projectID, userID uint) error {
+func (r *workerRepository) AddToProject(ctx context.Context, workerID, projectID, userID uint) error {
 	// Verify worker belongs to user
 	worker := &model.Worker{}
-	if err := r.db.Where("id = ? AND user_id = ?", workerID, userID).First(worker).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", workerID, userID).First(worker).Error; err != nil {
 		return err
 	}
 	
 	// Verify project belongs to user
 	project := &model.Project{}
-	if err := r.db.Where("id = ? AND user_id = ?", projectID, userID).First(project).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", projectID, userID).First(project).Error; err != nil {
 		return err
 	}
 	
 	// Create the join record with user_id
-	workerProject := &model.WorkerProject{
-		WorkerID:  workerID,
-		ProjectID: projectID,
-		UserID:    userID,
-	}
-	
-	// Use the custom join table to create the relationship
-	return r.db.Create(workerProject).Error
+	workerProject := model.NewAssignment(workerID, projectID, userID)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return assign(ctx, tx, *worker, *project, &workerProject)
+	})
 }
 
 // RemoveFromProject removes a worker from a project (ensuring both belong to the user)
-func (r *WorkerRepository) RemoveFromProject(workerID, projectID, userID uint) error {
+func (r *workerRepository) RemoveFromProject(ctx context.Context, workerID, projectID, userID uint) error {
 	// Verify worker belongs to user
 	worker := &model.Worker{}
-	if err := r.db.Where("id = ? AND user_id = ?", workerID, userID).First(worker).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", workerID, userID).First(worker).Error; err != nil {
 		return err
 	}
 	
 	// Verify project belongs to user
 	project := &model.Project{}
-	if err := r.db.Where("id = ? AND user_id = ?", projectID, userID).First(project).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", projectID, userID).First(project).Error; err != nil {
 		return err
 	}
 	
-	// Delete the join record that has the appropriate worker_id, project_id AND user_id
-	return r.db.Where("worker_id = ? AND project_id = ? AND user_id = ?", 
-		workerID, projectID, userID).Delete(&model.WorkerProject{}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return unassign(ctx, tx, *worker, *project, userID)
+	})
+}
+
+// recordWorkerChange stores the history entry of a worker write; updates
+// that change no recorded field leave no entry
+func recordWorkerChange(ctx context.Context, tx *gorm.DB, before, after *model.Worker, action model.LogType) error {
+	var old interface{}
+	if before != nil {
+		old = before
+	}
+	changes, err := DiffFields(old, after, WorkerHistoryFields)
+	if err != nil {
+		return err
+	}
+	if action == model.LogTypeUpdate && len(changes) == 0 {
+		return nil
+	}
+	return recordChanges(tx, NewChange(ctx, model.EntityTypeWorker, after.ID, action, after.UserID, changes))
 } 
\ No newline at end of file
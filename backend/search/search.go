@@ -0,0 +1,164 @@
+// Package search holds the dialect-independent parts of full-text search:
+// splitting a query into terms, matching and scoring text against them the
+// way the Postgres "simple" configuration does, and highlighting matches.
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MaxTerms bounds the number of terms taken from a query
+	MaxTerms = 8
+	// ExcerptLength is the length, in characters, of highlighted excerpts
+	ExcerptLength = 160
+)
+
+// Field is a piece of text searched with a weight, like a tsvector weight class
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Weights of the searched fields: titles (names) rank above body text
+const (
+	WeightTitle = 1.0
+	WeightBody  = 0.4
+)
+
+// Terms splits a query into distinct lowercase words. Everything but letters
+// and digits separates words, so the terms are safe to embed in a tsquery.
+func Terms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range words(query) {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+		if len(terms) == MaxTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// TSQuery builds a Postgres tsquery requiring every term as a word prefix
+func TSQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
+
+// words splits text into lowercase words
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Score reports whether every term prefixes a word of the fields, and if so
+// a rank: the weighted number of matching words, exact words counting double
+func Score(terms []string, fields ...Field) (float64, bool) {
+	if len(terms) == 0 {
+		return 0, false
+	}
+
+	matched := make(map[string]bool)
+	rank := 0.0
+	for _, field := range fields {
+		for _, word := range words(field.Text) {
+			for _, term := range terms {
+				if !strings.HasPrefix(word, term) {
+					continue
+				}
+				matched[term] = true
+				if word == term {
+					rank += 2 * field.Weight
+				} else {
+					rank += field.Weight
+				}
+			}
+		}
+	}
+	return rank, len(matched) == len(terms)
+}
+
+// Highlight HTML-escapes text and wraps the words prefixed by a term in
+// <mark> tags. It returns "" when nothing matches, so callers can omit the
+// field. Long text is cut down to a window around the first match.
+func Highlight(text string, terms []string, maxRunes int) string {
+	runes := []rune(text)
+
+	// Find the spans of the matching words
+	type span struct{ start, end int }
+	var spans []span
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		word := strings.ToLower(string(runes[i:j]))
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				spans = append(spans, span{i, j})
+				break
+			}
+		}
+		i = j
+	}
+	if len(spans) == 0 {
+		return ""
+	}
+
+	// Cut long text to a window starting shortly before the first match
+	from, to := 0, len(runes)
+	if maxRunes > 0 && len(runes) > maxRunes {
+		from = max(spans[0].start-maxRunes/4, 0)
+		to = min(from+maxRunes, len(runes))
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, s := range spans {
+		if s.start < from || s.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:s.start])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[s.start:s.end])))
+		b.WriteString("</mark>")
+		pos = s.end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:to])))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// HighlightFields highlights the named fields that match, leaving out the others
+func HighlightFields(terms []string, fields map[string]string) map[string]string {
+	result := make(map[string]string)
+	for name, text := range fields {
+		if highlighted := Highlight(text, terms, ExcerptLength); highlighted != "" {
+			result[name] = highlighted
+		}
+	}
+	return result
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
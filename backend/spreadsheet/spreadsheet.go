@@ -0,0 +1,102 @@
+// Package spreadsheet reads tables of text cells from CSV files and from the
+// first worksheet of XLSX (Office Open XML) workbooks, and writes tables in
+// both formats as a stream.
+package spreadsheet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// Format is a spreadsheet file format
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+// ErrUnknownFormat is returned for files that are neither CSV nor XLSX
+var ErrUnknownFormat = errors.New("spreadsheet: unknown format")
+
+// Row is a row of a spreadsheet with its number, counted from 1 as the user
+// sees it: the line a CSV record starts on, or the row of a worksheet
+type Row struct {
+	Number int
+	Cells  []string
+}
+
+// Reader reads the rows of a spreadsheet one at a time. Empty rows may be
+// skipped.
+type Reader interface {
+	// Read returns the next row, or io.EOF after the last one
+	Read() (Row, error)
+}
+
+// FormatOf returns the format of a file from its name
+func FormatOf(filename string) (Format, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return CSV, nil
+	case ".xlsx":
+		return XLSX, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// NewReader returns a reader of a spreadsheet file in the given format
+func NewReader(file io.ReaderAt, size int64, format Format) (Reader, error) {
+	switch format {
+	case CSV:
+		return newCSVReader(io.NewSectionReader(file, 0, size))
+	case XLSX:
+		return newXLSXReader(file, size)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// csvReader reads a CSV file, comma or semicolon separated
+type csvReader struct {
+	reader *csv.Reader
+}
+
+var utf8BOM = []byte("\ufeff")
+
+// newCSVReader reads CSV, using semicolons as separator when the header line
+// has more of them than commas, as spreadsheet programs write in locales with
+// a decimal comma
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	buffered := bufio.NewReader(r)
+	head, err := buffered.Peek(4096)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	// Drop the byte order mark spreadsheet programs start UTF-8 files with
+	if bytes.HasPrefix(head, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+		head = head[len(utf8BOM):]
+	}
+	header, _, _ := bytes.Cut(head, []byte("\n"))
+
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+	return &csvReader{reader: reader}, nil
+}
+
+// Read returns the next record
+func (r *csvReader) Read() (Row, error) {
+	cells, err := r.reader.Read()
+	if err != nil {
+		return Row{}, err
+	}
+	line, _ := r.reader.FieldPos(0)
+	return Row{Number: line, Cells: cells}, nil
+}
@@ -0,0 +1,64 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// readAll reads every row of a spreadsheet
+func readAll(t *testing.T, data []byte, format Format) []Row {
+	t.Helper()
+	reader, err := NewReader(bytes.NewReader(data), int64(len(data)), format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []Row
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for name, want := range map[string]Format{"workers.csv": CSV, "Workers.XLSX": XLSX} {
+		if format, err := FormatOf(name); err != nil || format != want {
+			t.Errorf("expected %s to be %s, got %q (%v)", name, want, format, err)
+		}
+	}
+	if _, err := FormatOf("workers.xls"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "commas", data: "name,age\nAna Pop,30\n\"Ion\nRus\",40\n"},
+		{name: "semicolons", data: "name;age\nAna Pop;30\n\"Ion\nRus\";40\n"},
+		{name: "byte order mark", data: "\ufeffname,age\nAna Pop,30\n\"Ion\nRus\",40\n"},
+	}
+	want := []Row{
+		{Number: 1, Cells: []string{"name", "age"}},
+		{Number: 2, Cells: []string{"Ana Pop", "30"}},
+		// Rows are numbered by the line they start on
+		{Number: 3, Cells: []string{"Ion\nRus", "40"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rows := readAll(t, []byte(tt.data), CSV); !reflect.DeepEqual(rows, want) {
+				t.Fatalf("expected %q, got %q", want, rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType returns the media type of a format
+func ContentType(format Format) string {
+	if format == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Writer writes the rows of a spreadsheet one at a time. Cells are strings,
+// numbers, times, nil for blank cells, or pointers to those; numbers are
+// written as numbers and times in RFC 3339.
+type Writer interface {
+	Write(cells ...interface{}) error
+	// Close writes the end of the spreadsheet; it does not close the
+	// underlying writer
+	Close() error
+}
+
+// NewWriter returns a writer of a spreadsheet in the given format. XLSX
+// workbooks hold one worksheet named sheet.
+func NewWriter(w io.Writer, format Format, sheet string) (Writer, error) {
+	switch format {
+	case CSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case XLSX:
+		return newXLSXWriter(w, sheet)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// cellValue returns a cell as a number, when it is one, or as text
+func cellValue(cell interface{}) (text string, number bool) {
+	switch v := cell.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, false
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case time.Time:
+		return v.Format(time.RFC3339), false
+	case *time.Time:
+		if v == nil {
+			return "", false
+		}
+		return v.Format(time.RFC3339), false
+	case fmt.Stringer:
+		return v.String(), false
+	}
+	return fmt.Sprint(cell), false
+}
+
+// csvWriter writes CSV
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+// Write writes a record. Text starting like a formula is prefixed with a
+// quote, so spreadsheet programs opening the file do not evaluate it.
+func (w *csvWriter) Write(cells ...interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		text, number := cellValue(cell)
+		if !number && text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+			text = "'" + text
+		}
+		record[i] = text
+	}
+	return w.writer.Write(record)
+}
+
+// Close flushes the buffered records
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// The parts of a workbook other than its worksheet
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="` + worksheetRelType + `" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxWriter writes a workbook whose worksheet is the last part of the
+// archive, so its rows are compressed and written as they come. Text is
+// written as inline strings, which need no shared strings table.
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	row     int
+}
+
+// newXLSXWriter writes the fixed parts of a workbook and starts its worksheet
+func newXLSXWriter(w io.Writer, sheet string) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheetName(sheet)))
+	parts := []struct{ path, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, name.String())},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := archive.Create(part.path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheetWriter := bufio.NewWriter(f)
+	if _, err := sheetWriter.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{archive: archive, sheet: sheetWriter}, nil
+}
+
+// Write writes a row
+func (w *xlsxWriter) Write(cells ...interface{}) error {
+	w.row++
+	fmt.Fprintf(w.sheet, `<row r="%d">`, w.row)
+	for i, cell := range cells {
+		text, number := cellValue(cell)
+		if text == "" {
+			continue
+		}
+		ref := columnName(i) + strconv.Itoa(w.row)
+		if number {
+			fmt.Fprintf(w.sheet, `<c r="%s"><v>%s</v></c>`, ref, text)
+			continue
+		}
+		fmt.Fprintf(w.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		if err := xml.EscapeText(w.sheet, []byte(text)); err != nil {
+			return err
+		}
+		w.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := w.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Close ends the worksheet and the archive
+func (w *xlsxWriter) Close() error {
+	if _, err := w.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.archive.Close()
+}
+
+// columnName returns the letters of a 0-based column, e.g. "AB" for 27
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// sheetName makes a valid worksheet name: at most 31 characters, none of
+// which is one of : \ / ? * [ ]
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" {
+		return "Sheet1"
+	}
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	return name
+}
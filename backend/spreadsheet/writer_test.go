@@ -0,0 +1,83 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// written writes the rows of cells as a spreadsheet and returns the file
+func written(t *testing.T, format Format, rows ...[]interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, "Workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cells := range rows {
+		if err := w.Write(cells...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriteCSV(t *testing.T) {
+	created := time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC)
+	data := written(t, CSV,
+		[]interface{}{"name", "salary", "created_at", "notes"},
+		[]interface{}{"Ana, Pop", -3000, created, nil},
+		// Text that spreadsheet programs would evaluate is quoted
+		[]interface{}{"=HYPERLINK(\"x\")", 2.5, (*time.Time)(nil), "-1"},
+	)
+	want := "name,salary,created_at,notes\n" +
+		"\"Ana, Pop\",-3000,2026-03-01T08:30:00Z,\n" +
+		"\"'=HYPERLINK(\"\"x\"\")\",2.5,,'-1\n"
+	if string(data) != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, data)
+	}
+}
+
+func TestWriteXLSXReadsBack(t *testing.T) {
+	data := written(t, XLSX,
+		[]interface{}{"name", "age", "notes"},
+		[]interface{}{"Ana <Pop> & co", 30, nil},
+		[]interface{}{"  Ion Rus ", uint(40), "=1+1"},
+	)
+	want := []Row{
+		{Number: 1, Cells: []string{"name", "age", "notes"}},
+		{Number: 2, Cells: []string{"Ana <Pop> & co", "30"}},
+		{Number: 3, Cells: []string{"  Ion Rus ", "40", "=1+1"}},
+	}
+	if rows := readAll(t, data, XLSX); !reflect.DeepEqual(rows, want) {
+		t.Fatalf("expected %q, got %q", want, rows)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if name := columnName(index); name != want {
+			t.Errorf("expected column %d to be %s, got %s", index, want, name)
+		}
+		if back, ok := columnIndex(want + "1"); !ok || back != index {
+			t.Errorf("expected %s to be column %d, got %d", want, index, back)
+		}
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	tests := map[string]string{
+		"":                                   "Sheet1",
+		"workers/2026:[March]":               "workers_2026__March_",
+		"a very long worksheet name indeed!": "a very long worksheet name inde",
+	}
+	for name, want := range tests {
+		if got := sheetName(name); got != want {
+			t.Errorf("expected %q to become %q, got %q", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidXLSX is returned for XLSX files that cannot be read
+var ErrInvalidXLSX = errors.New("spreadsheet: invalid XLSX file")
+
+// ErrTooLarge is returned for XLSX files with a part larger than maxPartBytes
+// once uncompressed
+var ErrTooLarge = errors.New("spreadsheet: XLSX file too large once uncompressed")
+
+// Relationship type of the worksheets of a workbook
+const worksheetRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+
+// maxColumns is the number of columns of a worksheet
+const maxColumns = 16384
+
+// maxPartBytes bounds the uncompressed size of each part read from a
+// workbook, so that a small archive cannot expand without limit
+const maxPartBytes = 50 << 20
+
+// xlsxReader reads the rows of the first worksheet of a workbook as they are
+// decoded, keeping only the shared strings table in memory
+type xlsxReader struct {
+	decoder *xml.Decoder
+	shared  []string
+	// last is the number of the last row read
+	last int
+}
+
+// newXLSXReader opens the first worksheet of a workbook
+func newXLSXReader(file io.ReaderAt, size int64) (*xlsxReader, error) {
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidXLSX, sheetPath)
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = readSharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := openPart(sheet)
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxReader{decoder: xml.NewDecoder(content), shared: shared}, nil
+}
+
+// openPart opens a part of the archive, refusing parts declared larger than
+// maxPartBytes and reading no more than that from the others
+func openPart(f *zip.File) (io.ReadCloser, error) {
+	if f.UncompressedSize64 > maxPartBytes {
+		return nil, fmt.Errorf("%w: %s", ErrTooLarge, f.Name)
+	}
+	content, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(content, maxPartBytes), content}, nil
+}
+
+// firstSheetPath finds the archive path of the first worksheet listed by the
+// workbook
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeXML(files, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("%w: the workbook has no worksheet", ErrInvalidXLSX)
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Type   string `xml:"Type,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RelID || rel.Type != worksheetRelType {
+			continue
+		}
+		// Targets are relative to the workbook, or absolute within the archive
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", fmt.Errorf("%w: the first worksheet is missing", ErrInvalidXLSX)
+}
+
+// decodeXML decodes an XML part of the archive
+func decodeXML(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%w: missing %s", ErrInvalidXLSX, name)
+	}
+	content, err := openPart(f)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	if err := xml.NewDecoder(content).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidXLSX, name, err)
+	}
+	return nil
+}
+
+// readSharedStrings reads the strings cells refer to by index. A string may
+// be split in runs of rich text; phonetic guides are left out.
+func readSharedStrings(f *zip.File) ([]string, error) {
+	content, err := openPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	var shared []string
+	var text strings.Builder
+	inText, phonetic := false, 0
+	decoder := xml.NewDecoder(content)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return shared, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: shared strings: %v", ErrInvalidXLSX, err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "si":
+				text.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				phonetic++
+			}
+		case xml.EndElement:
+			switch token.Name.Local {
+			case "si":
+				shared = append(shared, text.String())
+			case "t":
+				inText = false
+			case "rPh":
+				phonetic--
+			}
+		case xml.CharData:
+			if inText && phonetic == 0 {
+				text.Write(token)
+			}
+		}
+	}
+}
+
+// Read returns the next row of the worksheet
+func (r *xlsxReader) Read() (Row, error) {
+	for {
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			return Row{}, io.EOF
+		}
+		if err != nil {
+			return Row{}, fmt.Errorf("%w: worksheet: %v", ErrInvalidXLSX, err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if token.Name.Local == "row" {
+				return r.readRow(token)
+			}
+		case xml.EndElement:
+			if token.Name.Local == "sheetData" {
+				return Row{}, io.EOF
+			}
+		}
+	}
+}
+
+// readRow reads the cells of a row element. Cells may be left out of a row,
+// so they are placed by their reference.
+func (r *xlsxReader) readRow(start xml.StartElement) (Row, error) {
+	row := Row{Number: r.last + 1}
+	if number, err := strconv.Atoi(attr(start, "r")); err == nil {
+		row.Number = number
+	}
+	r.last = row.Number
+
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return Row{}, fmt.Errorf("%w: worksheet: %v", ErrInvalidXLSX, err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if token.Name.Local != "c" {
+				continue
+			}
+			column := len(row.Cells)
+			if index, ok := columnIndex(attr(token, "r")); ok {
+				column = index
+			}
+			if column >= maxColumns {
+				return Row{}, fmt.Errorf("%w: cell %q out of range", ErrInvalidXLSX, attr(token, "r"))
+			}
+			value, err := r.readCell(token)
+			if err != nil {
+				return Row{}, err
+			}
+			for len(row.Cells) <= column {
+				row.Cells = append(row.Cells, "")
+			}
+			row.Cells[column] = value
+		case xml.EndElement:
+			if token.Name.Local == "row" {
+				return row, nil
+			}
+		}
+	}
+}
+
+// readCell reads the text of a cell element from its value or inline string
+func (r *xlsxReader) readCell(start xml.StartElement) (string, error) {
+	var cell struct {
+		Value  string `xml:"v"`
+		Inline struct {
+			Text []string `xml:"t"`
+			Runs []string `xml:"r>t"`
+		} `xml:"is"`
+	}
+	if err := r.decoder.DecodeElement(&cell, &start); err != nil {
+		return "", fmt.Errorf("%w: worksheet: %v", ErrInvalidXLSX, err)
+	}
+
+	switch attr(start, "t") {
+	case "s":
+		index, err := strconv.Atoi(strings.TrimSpace(cell.Value))
+		if err != nil || index < 0 || index >= len(r.shared) {
+			return "", fmt.Errorf("%w: shared string %q out of range", ErrInvalidXLSX, cell.Value)
+		}
+		return r.shared[index], nil
+	case "inlineStr":
+		return strings.Join(cell.Inline.Text, "") + strings.Join(cell.Inline.Runs, ""), nil
+	case "b":
+		if cell.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	}
+	// Numbers, formula results and errors are stored as text already
+	return cell.Value, nil
+}
+
+// columnIndex returns the 0-based column of a cell reference such as "AB12"
+func columnIndex(ref string) (int, bool) {
+	index := 0
+	letters := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		index = index*26 + int(ch-'A'+1)
+		letters++
+		if index > maxColumns {
+			return maxColumns, true
+		}
+	}
+	if letters == 0 {
+		return 0, false
+	}
+	return index - 1, true
+}
+
+// attr returns the value of an attribute of an element
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
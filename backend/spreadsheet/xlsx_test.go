@@ -0,0 +1,141 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+const (
+	testWorkbook = `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+	<sheets><sheet name="Workers" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	testRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+	<Relationship Id="rId1" Type="` + worksheetRelType + `" Target="worksheets/sheet1.xml"/></Relationships>`
+)
+
+// workbook zips the parts of a workbook, with the workbook and its
+// relationships added when missing
+func workbook(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	if _, ok := parts["xl/workbook.xml"]; !ok {
+		parts["xl/workbook.xml"] = testWorkbook
+	}
+	if _, ok := parts["xl/_rels/workbook.xml.rels"]; !ok {
+		parts["xl/_rels/workbook.xml.rels"] = testRels
+	}
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// sheet wraps rows in a worksheet
+func sheet(rows string) string {
+	return `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+		rows + `</sheetData></worksheet>`
+}
+
+func TestReadXLSX(t *testing.T) {
+	data := workbook(t, map[string]string{
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>name</t></si>
+			<si><t>salary</t></si>
+			<si><r><t>Ana </t></r><r><t>Pop</t></r><rPh><t>アナ</t></rPh></si></sst>`,
+		"xl/worksheets/sheet1.xml": sheet(`
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+			<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2" t="b"><v>1</v></c></row>
+			<row r="5"><c r="B5"><v>4200.5</v></c><c t="inlineStr"><is><t>Ion Rus</t></is></c></row>
+			<row><c r="A6"><v>7</v></c></row>`),
+	})
+
+	want := []Row{
+		{Number: 1, Cells: []string{"name", "salary"}},
+		// Cells are placed by their reference, leaving gaps blank
+		{Number: 2, Cells: []string{"Ana Pop", "", "TRUE"}},
+		{Number: 5, Cells: []string{"", "4200.5", "Ion Rus"}},
+		// Rows without a number follow the previous one
+		{Number: 6, Cells: []string{"7"}},
+	}
+	if rows := readAll(t, data, XLSX); !reflect.DeepEqual(rows, want) {
+		t.Fatalf("expected %q, got %q", want, rows)
+	}
+}
+
+func TestReadXLSXErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not an archive", data: []byte("name,age\n")},
+		{name: "no workbook", data: workbook(t, map[string]string{"xl/workbook.xml": `<workbook/>`})},
+		{name: "missing worksheet", data: workbook(t, map[string]string{})},
+		{name: "shared string out of range", data: workbook(t, map[string]string{
+			"xl/worksheets/sheet1.xml": sheet(`<row r="1"><c r="A1" t="s"><v>3</v></c></row>`),
+		})},
+		{name: "column out of range", data: workbook(t, map[string]string{
+			"xl/worksheets/sheet1.xml": sheet(`<row r="1"><c r="ZZZZ1"><v>1</v></c></row>`),
+		})},
+		{name: "malformed worksheet", data: workbook(t, map[string]string{
+			"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c r="A1">`,
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewReader(bytes.NewReader(tt.data), int64(len(tt.data)), XLSX)
+			for err == nil {
+				_, err = reader.Read()
+			}
+			if !errors.Is(err, ErrInvalidXLSX) {
+				t.Fatalf("expected ErrInvalidXLSX, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReadXLSXRefusesLargeParts(t *testing.T) {
+	// The worksheet claims to expand beyond the limit; its content is never read
+	content := []byte(sheet(""))
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, part := range map[string]string{"xl/workbook.xml": testWorkbook, "xl/_rels/workbook.xml.rels": testRels} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(part))
+	}
+	w, err := archive.CreateRaw(&zip.FileHeader{
+		Name:               "xl/worksheets/sheet1.xml",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(content),
+		CompressedSize64:   uint64(len(content)),
+		UncompressedSize64: maxPartBytes + 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(content)
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if _, err := NewReader(bytes.NewReader(data), int64(len(data)), XLSX); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+}
@@ -9,7 +9,8 @@ export type RegisterRequest = {
   username: string
   email: string
   password: string
-  role?: string
+  // Only 'user' is accepted; other roles are granted by an admin
+  role?: 'user'
 }
 
 export type AuthResponse = {
@@ -1,5 +1,24 @@
 import { Worker } from './worker'
 
+export type GeoPoint = {
+  lat: number
+  lng: number
+}
+
+export type SiteBoundary = {
+  name: string
+  // Closed ring: the first point is repeated at the end
+  points: GeoPoint[]
+  area_km2?: number
+}
+
+export type SiteLocation = {
+  name: string
+  kind: 'gate' | 'depot' | 'office' | 'parking' | 'other'
+  latitude: number
+  longitude: number
+}
+
 export type Project = {
   id: number
   name: string
@@ -9,7 +28,10 @@ export type Project = {
   end_date?: string
   latitude?: number
   longitude?: number
+  boundaries?: SiteBoundary[] | null
+  locations?: SiteLocation[] | null
   user_id: number
+  version?: number
   created_at?: string
   updated_at?: string
   deleted_at?: string | null
@@ -3,6 +3,7 @@ export type User = {
   username: string
   email: string
   role: string
+  manager_id?: number | null
   active: boolean
   last_login?: string
   created_at?: string
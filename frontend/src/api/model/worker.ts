@@ -7,6 +7,7 @@ export type Worker = {
   position: string
   salary: number
   user_id: number
+  version?: number
   created_at?: string
   updated_at?: string
   deleted_at?: string | null
@@ -11,7 +11,7 @@ export const adminService = {
   async getUsers(page = 1, pageSize = 20, search = ''): Promise<PaginatedUsersResponse> {
     const queryParams = new URLSearchParams({
       page: page.toString(),
-      pageSize: pageSize.toString()
+      page_size: pageSize.toString()
     })
 
     if (search) {
@@ -72,7 +72,7 @@ export const adminService = {
   async getUserActivity(userId: number, page = 1, pageSize = 10): Promise<UserActivityResponse> {
     const queryParams = new URLSearchParams({
       page: page.toString(),
-      pageSize: pageSize.toString()
+      page_size: pageSize.toString()
     })
 
     const response = await fetch(`${API_URL}/users/${userId}/activity?${queryParams.toString()}`, {
@@ -61,7 +61,7 @@ export const projectsService = {
       if (!response.ok) {
         const errorData = await response.json().catch(() => ({}))
         throw new Error(
-          errorData.error || `Failed to fetch projects: ${response.status} ${response.statusText}`
+          errorData.message || `Failed to fetch projects: ${response.status} ${response.statusText}`
         )
       }
 
@@ -100,7 +100,7 @@ export const projectsService = {
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
       const errorMessage =
-        errorData.error || `Failed to fetch project: ${response.status} ${response.statusText}`
+        errorData.message || `Failed to fetch project: ${response.status} ${response.statusText}`
       throw new Error(errorMessage)
     }
 
@@ -120,7 +120,7 @@ export const projectsService = {
 
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
-      const errorMessage = errorData.error || 'Failed to add project'
+      const errorMessage = errorData.message || 'Failed to add project'
       throw new Error(errorMessage)
     }
 
@@ -140,7 +140,7 @@ export const projectsService = {
 
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
-      const errorMessage = errorData.error || 'Failed to update project'
+      const errorMessage = errorData.message || 'Failed to update project'
       throw new Error(errorMessage)
     }
 
@@ -158,7 +158,7 @@ export const projectsService = {
 
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
-      const errorMessage = errorData.error || 'Failed to delete project'
+      const errorMessage = errorData.message || 'Failed to delete project'
       throw new Error(errorMessage)
     }
   },
@@ -176,7 +176,7 @@ export const projectsService = {
 
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
-      const errorMessage = errorData.error || 'Failed to assign worker to project'
+      const errorMessage = errorData.message || 'Failed to assign worker to project'
       throw new Error(errorMessage)
     }
 
@@ -194,7 +194,7 @@ export const projectsService = {
 
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
-      const errorMessage = errorData.error || 'Failed to unassign worker from project'
+      const errorMessage = errorData.message || 'Failed to unassign worker from project'
       throw new Error(errorMessage)
     }
 
@@ -226,7 +226,7 @@ export const projectsService = {
 
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
-      const errorMessage = errorData.error || 'Failed to get available workers'
+      const errorMessage = errorData.message || 'Failed to get available workers'
       throw new Error(errorMessage)
     }
 
@@ -73,7 +73,7 @@ export const workersService = {
       if (!response.ok) {
         const errorData = await response.json().catch(() => ({}))
         throw new Error(
-          errorData.error || `Failed to fetch workers: ${response.status} ${response.statusText}`
+          errorData.message || `Failed to fetch workers: ${response.status} ${response.statusText}`
         )
       }
 
@@ -98,7 +98,7 @@ export const workersService = {
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
       const errorMessage =
-        errorData.error || `Failed to fetch worker: ${response.status} ${response.statusText}`
+        errorData.message || `Failed to fetch worker: ${response.status} ${response.statusText}`
       throw new Error(errorMessage)
     }
 
@@ -118,7 +118,7 @@ export const workersService = {
 
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
-      const errorMessage = errorData.error || 'Failed to add worker'
+      const errorMessage = errorData.message || 'Failed to add worker'
       throw new Error(errorMessage)
     }
 
@@ -138,7 +138,7 @@ export const workersService = {
 
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
-      const errorMessage = errorData.error || 'Failed to update worker'
+      const errorMessage = errorData.message || 'Failed to update worker'
       throw new Error(errorMessage)
     }
 
@@ -156,7 +156,7 @@ export const workersService = {
 
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
-      const errorMessage = errorData.error || 'Failed to delete worker'
+      const errorMessage = errorData.message || 'Failed to delete worker'
       throw new Error(errorMessage)
     }
   },
@@ -174,7 +174,7 @@ export const workersService = {
 
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}))
-      const errorMessage = errorData.error || 'Failed to delete workers'
+      const errorMessage = errorData.message || 'Failed to delete workers'
       throw new Error(errorMessage)
     }
   }
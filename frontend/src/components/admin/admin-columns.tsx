@@ -108,6 +108,8 @@ export function useAdminColumns({
               </SelectTrigger>
               <SelectContent>
                 <SelectItem value='user'>User</SelectItem>
+                <SelectItem value='foreman'>Foreman</SelectItem>
+                <SelectItem value='manager'>Manager</SelectItem>
                 <SelectItem value='admin'>Admin</SelectItem>
               </SelectContent>
             </Select>
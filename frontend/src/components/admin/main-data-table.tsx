@@ -41,6 +41,20 @@ const getLogTypeBadge = (logType: string) => {
       return 'secondary'
     case 'DELETE':
       return 'destructive'
+    case 'RESTORE':
+      return 'secondary'
+    case 'PURGE':
+      return 'destructive'
+    case 'BULK':
+      return 'secondary'
+    case 'TRANSFER':
+      return 'secondary'
+    case 'SUBMIT':
+      return 'secondary'
+    case 'APPROVE':
+      return 'default'
+    case 'REJECT':
+      return 'destructive'
     case 'LOGIN':
       return 'outline'
     case 'LOGOUT':
@@ -33,7 +33,7 @@ export default function EditWorkerForm({
   })
 
   const onSubmit: SubmitHandler<WorkerFormInputs> = async data => {
-    await onEditWorker({ ...data, id: worker.id, user_id: worker.user_id })
+    await onEditWorker({ ...data, id: worker.id, user_id: worker.user_id, version: worker.version })
     reset()
   }
 